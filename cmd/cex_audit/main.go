@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"github.com/xavierzho/go-cexs/platforms/audit"
+	"os"
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "cex audit",
+		Usage: "Inspect the audit trail connectors record.",
+		Commands: cli.Commands{
+			{
+				Name:      "verify",
+				Usage:     "Check the hash chain of an audit directory.",
+				ArgsUsage: "<dir>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "head",
+						Usage: "hash the last entry is expected to have, detects entries removed from the end",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					dir := ctx.Args().First()
+					if dir == "" {
+						return fmt.Errorf("audit directory is required")
+					}
+					report, err := audit.Verify(dir)
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					if report.Files == 0 {
+						return cli.Exit(fmt.Sprintf("no audit files in %s", dir), 1)
+					}
+					if head := ctx.String("head"); head != "" && head != report.Head {
+						return cli.Exit(fmt.Sprintf("chain ends at %s, expected %s", report.Head, head), 1)
+					}
+					fmt.Printf("ok: %d entries in %d files, sequence %d to %d, head %s\n",
+						report.Entries, report.Files, report.First, report.Last, report.Head)
+					if report.First > 1 {
+						fmt.Printf("note: chain starts at sequence %d, earlier files are not in %s\n", report.First, dir)
+					}
+					return nil
+				},
+			},
+		},
+	}
+	if err := app.Run(os.Args); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,314 @@
+// Package audit tamper-evident record of the requests connectors send.
+//
+// Every Call becomes an Entry in rotating JSON lines files. Each entry carries the sha256 hash of
+// the one before, so editing, inserting or removing an entry breaks the chain from there on;
+// Verify, also behind cmd/cex_audit, checks it. Keys, passphrases and signatures are redacted.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xavierzho/go-cexs/platforms"
+)
+
+// Outcome of a recorded call.
+type Outcome string
+
+const (
+	Ok            Outcome = "OK"
+	ExchangeError Outcome = "EXCHANGE_ERROR"
+	NetworkError  Outcome = "NETWORK_ERROR"
+	Failed        Outcome = "ERROR"
+)
+
+// Entry one recorded call.
+type Entry struct {
+	Sequence uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Platform string    `json:"platform"`
+	Method   string    `json:"method"`
+	Route    string    `json:"route"`
+	// URL, Header as sent, signatures and credentials redacted
+	URL        string      `json:"url,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	StatusCode int         `json:"status,omitempty"`
+	Response   string      `json:"response,omitempty"`
+	LatencyMs  float64     `json:"latency_ms"`
+	Outcome    Outcome     `json:"outcome"`
+	ErrorCode  string      `json:"error_code,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	// Prev hash of the previous entry, empty for the first
+	Prev string `json:"prev"`
+	// Hash sha256 of this entry with an empty Hash
+	Hash string `json:"hash"`
+}
+
+// hash of entry, chained through its Prev.
+func (e Entry) hash() (string, error) {
+	e.Hash = ""
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Mutating Filter keeping the calls that may change orders or funds, i.e. everything but GET.
+func Mutating(invocation *platforms.Invocation) bool {
+	return invocation.Method != http.MethodGet
+}
+
+const (
+	defaultMaxBytes = 64 << 20
+	defaultMaxAge   = 24 * time.Hour
+	filePattern     = "audit-%06d.jsonl"
+)
+
+// Recorder appends entries to audit-NNNNNN.jsonl files in a directory, synced after every entry.
+type Recorder struct {
+	// MaxBytes size a file may reach before the next one is started, default 64 MiB
+	MaxBytes int64
+	// MaxAge time a file is written to before the next one is started, default 24h
+	MaxAge time.Duration
+	// Filter calls to record, nil records every call
+	Filter func(invocation *platforms.Invocation) bool
+	// Logger receives entries that could not be written, silent by default
+	Logger *slog.Logger
+
+	dir      string
+	mux      sync.Mutex
+	file     *os.File
+	index    int
+	size     int64
+	opened   time.Time
+	sequence uint64
+	last     string
+	err      error
+}
+
+// Open record into dir, continuing the chain of the files already there.
+func Open(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	recorder := &Recorder{
+		MaxBytes: defaultMaxBytes,
+		MaxAge:   defaultMaxAge,
+		Logger:   platforms.Discard(),
+		dir:      dir,
+	}
+	files, err := Files(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return recorder, recorder.rotate()
+	}
+	return recorder, recorder.resume(files[len(files)-1])
+}
+
+// Files the audit files of dir in chain order.
+func Files(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, match := range matches {
+		var index int
+		if _, err = fmt.Sscanf(filepath.Base(match), filePattern, &index); err == nil {
+			files = append(files, match)
+		}
+	}
+	// zero padded, lexical order is numeric order
+	sort.Strings(files)
+	return files, nil
+}
+
+// resume append to the last file after its last complete entry, a line torn by a crash is dropped.
+func (r *Recorder) resume(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		var entry Entry
+		if err = json.Unmarshal(line, &entry); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("%s: entry after sequence %d: %w", path, r.sequence, err)
+		}
+		if offset == 0 {
+			r.opened = entry.Time
+		}
+		offset += int64(len(line))
+		r.sequence, r.last = entry.Sequence, entry.Hash
+	}
+	if err = file.Truncate(offset); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if offset == 0 {
+		r.opened = time.Now()
+	}
+	_, _ = fmt.Sscanf(filepath.Base(path), filePattern, &r.index)
+	r.file, r.size = file, offset
+	return nil
+}
+
+// rotate start the next file.
+func (r *Recorder) rotate() error {
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+	}
+	r.index++
+	file, err := os.OpenFile(filepath.Join(r.dir, fmt.Sprintf(filePattern, r.index)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		r.file = nil
+		return err
+	}
+	r.file, r.size, r.opened = file, 0, time.Now()
+	return nil
+}
+
+// Record chain entry to the previous one and append it, Sequence, Prev and Hash are set here.
+func (r *Recorder) Record(entry Entry) (Entry, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.file == nil || r.size > 0 && (r.MaxBytes > 0 && r.size >= r.MaxBytes || r.MaxAge > 0 && time.Since(r.opened) >= r.MaxAge) {
+		if err := r.rotate(); err != nil {
+			return entry, r.fail(err)
+		}
+	}
+	entry.Sequence, entry.Prev = r.sequence+1, r.last
+	hash, err := entry.hash()
+	if err != nil {
+		return entry, r.fail(err)
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return entry, r.fail(err)
+	}
+	line = append(line, '\n')
+	if _, err = r.file.Write(line); err != nil {
+		return entry, r.fail(err)
+	}
+	if err = r.file.Sync(); err != nil {
+		return entry, r.fail(err)
+	}
+	r.sequence, r.last, r.size = entry.Sequence, entry.Hash, r.size+int64(len(line))
+	return entry, nil
+}
+
+// fail keep the first write failure for Err.
+func (r *Recorder) fail(err error) error {
+	if r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+// Err the first entry that could not be written, nil while the record is complete.
+func (r *Recorder) Err() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.err
+}
+
+// Instrument record the calls of connector, false if it does not run a middleware chain.
+func (r *Recorder) Instrument(connector any) bool {
+	interceptable, ok := connector.(platforms.Interceptable)
+	if ok {
+		interceptable.Use(r.Middleware())
+	}
+	return ok
+}
+
+// Middleware record calls after they returned. The request has been sent by then,
+// so a recording failure is logged and kept for Err instead of failing the call.
+func (r *Recorder) Middleware() platforms.Middleware {
+	return func(next platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			if r.Filter != nil && !r.Filter(invocation) {
+				return next(invocation)
+			}
+			start := time.Now()
+			err := next(invocation)
+			entry := Entry{
+				Time:       start.UTC(),
+				Platform:   string(invocation.Platform),
+				Method:     invocation.Method,
+				Route:      invocation.Route,
+				Body:       string(invocation.RequestBody),
+				StatusCode: invocation.StatusCode,
+				Response:   string(invocation.Response),
+				LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+				Outcome:    Ok,
+			}
+			if invocation.Request != nil {
+				entry.URL = platforms.RedactURL(invocation.Request.URL.String())
+				entry.Header = platforms.RedactHeader(invocation.Request.Header)
+			}
+			var exchangeErr *platforms.ExchangeError
+			switch {
+			case err == nil:
+			case errors.As(err, &exchangeErr):
+				entry.Outcome, entry.ErrorCode = ExchangeError, exchangeErr.Code
+			case errors.Is(err, platforms.ErrNetwork):
+				entry.Outcome = NetworkError
+			default:
+				entry.Outcome = Failed
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			}
+			if _, recordErr := r.Record(entry); recordErr != nil {
+				r.Logger.Error("audit entry not written", slog.String("platform", entry.Platform),
+					slog.String("method", entry.Method), slog.String("route", entry.Route), slog.String("error", recordErr.Error()))
+			}
+			return err
+		}
+	}
+}
+
+func (r *Recorder) Close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+)
+
+type exchange struct {
+	platforms.Middlewares
+}
+
+func (e *exchange) order(signature string) error {
+	return e.Invoke(&platforms.Invocation{
+		Platform: constants.Binance, Method: http.MethodPost, Route: "/api/v3/order",
+		Params: &platforms.ObjectBody{"symbol": "BTCUSDT"},
+	}, func(invocation *platforms.Invocation) error {
+		request, _ := http.NewRequest(invocation.Method, "https://api.binance.com/api/v3/order?symbol=BTCUSDT&signature="+signature, nil)
+		request.Header.Set("X-MBX-APIKEY", "my-api-key")
+		invocation.Request, invocation.RequestBody = request, []byte(`{"symbol":"BTCUSDT"}`)
+		invocation.StatusCode, invocation.Response = http.StatusBadRequest, []byte(`{"code":-2010}`)
+		return &platforms.ExchangeError{Exchange: string(constants.Binance), Code: "-2010", Message: "Account has insufficient balance"}
+	})
+}
+
+func TestRecordAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.MaxBytes = 1 // every entry in a file of its own
+	connector := &exchange{}
+	recorder.Instrument(connector)
+	for i := 0; i < 3; i++ {
+		if err = connector.order("deadbeef"); err == nil {
+			t.Fatal("call error not returned")
+		}
+	}
+	_ = recorder.Close()
+	if err = recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := Files(dir)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %v", files)
+	}
+	content, _ := os.ReadFile(files[0])
+	for _, secret := range []string{"my-api-key", "deadbeef"} {
+		if bytes.Contains(content, []byte(secret)) {
+			t.Errorf("%s not redacted: %s", secret, content)
+		}
+	}
+	if !bytes.Contains(content, []byte(`"outcome":"EXCHANGE_ERROR"`)) || !bytes.Contains(content, []byte(`"error_code":"-2010"`)) {
+		t.Errorf("outcome not recorded: %s", content)
+	}
+
+	report, err := Verify(dir)
+	if err != nil || report.Entries != 3 || report.First != 1 || report.Last != 3 {
+		t.Fatalf("unexpected report %+v, %v", report, err)
+	}
+
+	// the chain continues after reopening
+	recorder, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = recorder.Record(Entry{Platform: string(constants.Okx), Method: http.MethodGet, Route: "/api/v5/account/balance", Outcome: Ok}); err != nil {
+		t.Fatal(err)
+	}
+	_ = recorder.Close()
+	if report, err = Verify(dir); err != nil || report.Last != 4 {
+		t.Fatalf("unexpected report %+v, %v", report, err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range []string{"/a", "/b", "/c"} {
+		if _, err = recorder.Record(Entry{Method: http.MethodPost, Route: route, Outcome: Ok}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = recorder.Close()
+	files, _ := Files(dir)
+	content, _ := os.ReadFile(files[0])
+	if err = os.WriteFile(files[0], bytes.Replace(content, []byte(`"/b"`), []byte(`"/x"`), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Verify(dir)
+	var chainErr *ChainError
+	if !errors.As(err, &chainErr) || chainErr.Line != 2 || chainErr.Sequence != 2 {
+		t.Fatalf("tampered entry not located: %v", err)
+	}
+
+	// removing an entry breaks the chain as well
+	lines := strings.SplitAfter(string(content), "\n")
+	if err = os.WriteFile(files[0], []byte(lines[0]+lines[2]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Verify(dir); !errors.As(err, &chainErr) || chainErr.Sequence != 3 {
+		t.Fatalf("removed entry not detected: %v", err)
+	}
+}
+
+func TestTornEntryDropped(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = recorder.Record(Entry{Method: http.MethodPost, Route: "/a", Outcome: Ok}); err != nil {
+		t.Fatal(err)
+	}
+	_ = recorder.Close()
+	files, _ := Files(dir)
+	file, _ := os.OpenFile(files[0], os.O_APPEND|os.O_WRONLY, 0o600)
+	_, _ = file.WriteString(`{"seq":2,"rou`)
+	_ = file.Close()
+
+	if recorder, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = recorder.Record(Entry{Method: http.MethodPost, Route: "/b", Outcome: Ok}); err != nil {
+		t.Fatal(err)
+	}
+	_ = recorder.Close()
+	if report, err := Verify(dir); err != nil || report.Last != 2 {
+		t.Fatalf("unexpected report %+v, %v", report, err)
+	}
+}
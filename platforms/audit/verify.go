@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ChainError where the chain of an audit directory breaks.
+type ChainError struct {
+	File     string
+	Line     int
+	Sequence uint64
+	Reason   string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("%s:%d: sequence %d: %s", e.File, e.Line, e.Sequence, e.Reason)
+}
+
+// Report of a chain verified.
+type Report struct {
+	Files   int
+	Entries uint64
+	// First sequence of the chain, above 1 when older files were archived or removed
+	First uint64
+	Last  uint64
+	// Head hash of the last entry, keep it elsewhere to detect entries removed from the end
+	Head string
+}
+
+// Verify the chain of the audit files in dir: every entry hashes to its Hash,
+// carries the Hash of the entry before as Prev, and sequences follow each other.
+// A *ChainError locates the first break.
+func Verify(dir string) (Report, error) {
+	var report Report
+	files, err := Files(dir)
+	if err != nil {
+		return report, err
+	}
+	for _, path := range files {
+		if err = verifyFile(path, &report); err != nil {
+			return report, err
+		}
+		report.Files++
+	}
+	return report, nil
+}
+
+func verifyFile(path string, report *Report) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	// responses such as full order books make long lines
+	scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var entry Entry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return &ChainError{File: path, Line: line, Sequence: report.Last + 1, Reason: err.Error()}
+		}
+		broken := func(reason string) error {
+			return &ChainError{File: path, Line: line, Sequence: entry.Sequence, Reason: reason}
+		}
+		if report.Entries == 0 {
+			report.First = entry.Sequence
+			if entry.Sequence == 1 && entry.Prev != "" {
+				return broken("first entry has a previous hash")
+			}
+		} else {
+			if entry.Sequence != report.Last+1 {
+				return broken(fmt.Sprintf("expected sequence %d", report.Last+1))
+			}
+			if entry.Prev != report.Head {
+				return broken("previous hash does not match")
+			}
+		}
+		hash, err := entry.hash()
+		if err != nil {
+			return broken(err.Error())
+		}
+		if hash != entry.Hash {
+			return broken("hash does not match the entry")
+		}
+		report.Entries++
+		report.Last, report.Head = entry.Sequence, entry.Hash
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,35 @@
+package platforms
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// NewClientId a random client order id every connector accepts: 24 hex characters
+// fit the tightest limit, gate's 28 byte text with its "t-" prefix.
+func NewClientId() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")[:24]
+}
+
+// ChunkOrders split the indexes of orders into batches of at most size, keeping input order.
+// bySymbol is for exchanges whose batch endpoint only accepts a single symbol per request.
+func ChunkOrders(orders []types.OrderEntry, size int, bySymbol bool) [][]int {
+	var chunks [][]int
+	var open = make(map[string]int) // symbol -> index of the chunk still being filled
+	for i, order := range orders {
+		key := ""
+		if bySymbol {
+			key = order.Symbol
+		}
+		at, ok := open[key]
+		if !ok || len(chunks[at]) >= size {
+			chunks = append(chunks, nil)
+			at = len(chunks) - 1
+			open[key] = at
+		}
+		chunks[at] = append(chunks[at], i)
+	}
+	return chunks
+}
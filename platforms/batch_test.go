@@ -0,0 +1,24 @@
+package platforms
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xavierzho/go-cexs/types"
+)
+
+func TestChunkOrders(t *testing.T) {
+	orders := []types.OrderEntry{
+		{Symbol: "BTCUSDT"},
+		{Symbol: "ETHUSDT"},
+		{Symbol: "BTCUSDT"},
+		{Symbol: "BTCUSDT"},
+		{Symbol: "ETHUSDT"},
+	}
+	if chunks := ChunkOrders(orders, 2, false); !reflect.DeepEqual(chunks, [][]int{{0, 1}, {2, 3}, {4}}) {
+		t.Errorf("unexpected chunks %v", chunks)
+	}
+	if chunks := ChunkOrders(orders, 2, true); !reflect.DeepEqual(chunks, [][]int{{0, 2}, {1, 4}, {3}}) {
+		t.Errorf("unexpected chunks by symbol %v", chunks)
+	}
+}
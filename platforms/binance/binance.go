@@ -1,8 +1,10 @@
 package binance
 
 import (
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/platforms"
 	"net/http"
+	"time"
 
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/types"
@@ -11,6 +13,8 @@ import (
 type Connector struct {
 	*platforms.Credentials
 	Client *http.Client
+	platforms.Middlewares
+	platforms.Logging
 }
 
 func NewConnector(base *platforms.Credentials, client *http.Client) *Connector {
@@ -91,6 +95,58 @@ func (c *Connector) Balance(symbols []string) (map[string]types.BalanceEntry, er
 	return result, nil
 }
 
+type CommissionRates struct {
+	Maker  string `json:"maker"`
+	Taker  string `json:"taker"`
+	Buyer  string `json:"buyer"`
+	Seller string `json:"seller"`
+}
+
+type CommissionResp struct {
+	Symbol             string          `json:"symbol"`
+	StandardCommission CommissionRates `json:"standardCommission"`
+	TaxCommission      CommissionRates `json:"taxCommission"`
+	Discount           struct {
+		EnabledForAccount bool   `json:"enabledForAccount"`
+		EnabledForSymbol  bool   `json:"enabledForSymbol"`
+		DiscountAsset     string `json:"discountAsset"`
+		Discount          string `json:"discount"`
+	} `json:"discount"`
+}
+
+func (c *Connector) GetFeeRates(symbols []string) (map[string]types.FeeRateEntry, error) {
+	var result = make(map[string]types.FeeRateEntry, len(symbols))
+	for _, symbol := range symbols {
+		var resp CommissionResp
+		err := c.Call(http.MethodGet, CommissionEndpoint, &platforms.ObjectBody{
+			SymbolFiled: symbol,
+			TimeFiled:   time.Now().UnixMilli(),
+		}, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		standardMaker, _ := decimal.NewFromString(resp.StandardCommission.Maker)
+		standardTaker, _ := decimal.NewFromString(resp.StandardCommission.Taker)
+		taxMaker, _ := decimal.NewFromString(resp.TaxCommission.Maker)
+		taxTaker, _ := decimal.NewFromString(resp.TaxCommission.Taker)
+		entry := types.FeeRateEntry{
+			Symbol: resp.Symbol,
+			Maker:  standardMaker.Add(taxMaker),
+			Taker:  standardTaker.Add(taxTaker),
+		}
+		// the discount only applies to the standard commission
+		if resp.Discount.EnabledForAccount && resp.Discount.EnabledForSymbol {
+			discount, _ := decimal.NewFromString(resp.Discount.Discount)
+			rate := decimal.NewFromInt(1).Sub(discount)
+			entry.DiscountAsset = resp.Discount.DiscountAsset
+			entry.DiscountMaker = standardMaker.Mul(rate).Add(taxMaker)
+			entry.DiscountTaker = standardTaker.Mul(rate).Add(taxTaker)
+		}
+		result[resp.Symbol] = entry
+	}
+	return result, nil
+}
+
 func (c *Connector) Name() constants.Platform {
 	return constants.Binance
 }
@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"testing"
 	"time"
 
@@ -255,3 +256,87 @@ func TestFloat(t *testing.T) {
 
 	fmt.Println(time.Now().UnixMilli())
 }
+
+// myTradesServer answers myTrades from trades like binance: by fromId, or by time range.
+func myTradesServer(trades []MyTrade) platforms.Middleware {
+	return func(platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			param := func(key string) int64 {
+				value, _ := strconv.ParseInt(invocation.Param(key), 10, 64)
+				return value
+			}
+			var page []MyTrade
+			for _, trade := range trades {
+				if fromId := param("fromId"); fromId != 0 && trade.ID < fromId ||
+					fromId == 0 && (trade.Time < param("startTime") || trade.Time > param("endTime")) {
+					continue
+				}
+				if len(page) < myTradesLimit {
+					page = append(page, trade)
+				}
+			}
+			*invocation.Result.(*[]MyTrade) = page
+			return nil
+		}
+	}
+}
+
+func TestGetMyTradesPaging(t *testing.T) {
+	// more trades in one millisecond than a page holds, then one after until
+	var trades []MyTrade
+	for id := int64(1); id <= 2500; id++ {
+		trades = append(trades, MyTrade{ID: id, Time: 5000, Price: "1", Qty: "1"})
+	}
+	trades = append(trades, MyTrade{ID: 2501, Time: 9000})
+	cex := NewConnector(&platforms.Credentials{}, nil)
+	cex.Use(myTradesServer(trades))
+	fills, err := cex.GetMyTrades(context.Background(), "BTCUSDT", 0, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 2500 || fills[0].TradeId != "1" || fills[2499].TradeId != "2500" {
+		t.Errorf("want trades 1 to 2500, got %d", len(fills))
+	}
+}
+
+// allOrdersServer answers allOrders from orders like binance: by orderId, or by time range.
+func allOrdersServer(orders []QueryOrder) platforms.Middleware {
+	return func(platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			param := func(key string) int64 {
+				value, _ := strconv.ParseInt(invocation.Param(key), 10, 64)
+				return value
+			}
+			var page []QueryOrder
+			for _, order := range orders {
+				if orderId := param("orderId"); orderId != 0 && int64(order.OrderID) < orderId ||
+					orderId == 0 && (order.Time < param("startTime") || order.Time > param("endTime")) {
+					continue
+				}
+				if len(page) < allOrdersLimit {
+					page = append(page, order)
+				}
+			}
+			*invocation.Result.(*[]QueryOrder) = page
+			return nil
+		}
+	}
+}
+
+func TestOrderHistoryPaging(t *testing.T) {
+	// more orders in one millisecond than a page holds, then one after until
+	var orders []QueryOrder
+	for id := 1; id <= 2500; id++ {
+		orders = append(orders, QueryOrder{OrderID: id, Time: 5000, Status: "FILLED"})
+	}
+	orders = append(orders, QueryOrder{OrderID: 2501, Time: 9000, Status: "FILLED"})
+	cex := NewConnector(&platforms.Credentials{}, nil)
+	cex.Use(allOrdersServer(orders))
+	history, err := cex.OrderHistory(context.Background(), "BTCUSDT", 0, 8000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2500 || history[0].OrderId != "1" || history[2499].OrderId != "2500" {
+		t.Errorf("want orders 1 to 2500, got %d", len(history))
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 )
 
 func (c *Connector) Sign(params []byte) string {
@@ -19,7 +20,20 @@ func (c *Connector) Sign(params []byte) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
+// errorKinds binance error codes with a unified meaning
+var errorKinds = map[int64]error{
+	-2011: platforms.ErrOrderNotFound, // Unknown order sent, also for orders no longer open
+	-2013: platforms.ErrOrderNotFound, // Order does not exist
+}
+
 func (c *Connector) Call(method string, route string, params platforms.Serializer, authType constants.AuthType, returnType interface{}) error {
+	return c.Invoke(&platforms.Invocation{Platform: c.Name(), Method: method, Route: route, AuthType: authType,
+		Params: params, Result: returnType}, c.Logged(c.call))
+}
+
+// call the request of one invocation, the middlewares of the connector run around it
+func (c *Connector) call(invocation *platforms.Invocation) error {
+	method, route, params, authType, returnType := invocation.Method, invocation.Route, invocation.Params, invocation.AuthType, invocation.Result
 	headers := http.Header{}
 	var reqBody io.Reader = nil
 
@@ -52,20 +66,31 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 
 	req.Header = headers
 
-	resp, err := http.DefaultClient.Do(req)
+	invocation.Request = req
+	resp, err := c.Client.Do(req)
 	if err != nil {
-		return err
+		return platforms.NetworkError(err)
 	}
 
-	decoder := utils.Json.NewDecoder(resp.Body)
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	invocation.StatusCode, invocation.Header, invocation.Response = resp.StatusCode, resp.Header, respBody
 	if resp.StatusCode != http.StatusOK {
 		var errResp ErrorResponse
-		err := decoder.Decode(&errResp)
+		err := utils.Json.Unmarshal(respBody, &errResp)
 		if err != nil {
 			return err
 		}
-		return fmt.Errorf("binance request error(%d)[%s]", errResp.Code, errResp.Msg)
+		return &platforms.ExchangeError{
+			Exchange: "Binance",
+			Code:     strconv.FormatInt(errResp.Code, 10),
+			Message:  errResp.Msg,
+			Err:      errorKinds[errResp.Code],
+		}
 	}
 
-	return decoder.Decode(&returnType)
+	return utils.Json.Unmarshal(respBody, returnType)
 }
@@ -85,14 +85,20 @@ func (ot OrderType) Convert() constants.OrderType {
 }
 
 const (
-	OrderEndpoint       = "/api/v3/order"
-	OpenOrdersEndpoint  = "/api/v3/openOrders"
-	DepthEndpoint       = "/api/v3/depth"
-	AccountEndpoint     = "/api/v3/account"
-	ServerTimeEndpoint  = "/api/v3/time"
-	KlineEndpoint       = "/api/v3/klines"
-	PriceTickerEndpoint = "/api/v3/ticker/price"
-	ListenKeyEndpoint   = "/api/v3/userDataStream"
+	OrderEndpoint          = "/api/v3/order"
+	OpenOrdersEndpoint     = "/api/v3/openOrders"
+	DepthEndpoint          = "/api/v3/depth"
+	AccountEndpoint        = "/api/v3/account"
+	ServerTimeEndpoint     = "/api/v3/time"
+	KlineEndpoint          = "/api/v3/klines"
+	PriceTickerEndpoint    = "/api/v3/ticker/price"
+	ListenKeyEndpoint      = "/api/v3/userDataStream"
+	MyTradesEndpoint       = "/api/v3/myTrades"
+	AllOrdersEndpoint      = "/api/v3/allOrders"
+	CommissionEndpoint     = "/api/v3/account/commission"
+	OrderListOcoEndpoint   = "/api/v3/orderList/oco"
+	OrderListOtoEndpoint   = "/api/v3/orderList/oto"
+	OrderListOtocoEndpoint = "/api/v3/orderList/otoco"
 )
 
 type NewOrderRespType string
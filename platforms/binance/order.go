@@ -1,8 +1,12 @@
 package binance
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/xavierzho/go-cexs/platforms"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -67,7 +71,7 @@ func (c *Connector) PlaceOrder(params types.OrderEntry) (string, error) {
 	resp := new(NewOrderFULL)
 	//fmt.Println("request", params)
 	orderType := c.MatchOrderType(params.Type)
-	err := c.Call(http.MethodPost, OrderEndpoint, &platforms.ObjectBody{
+	body := &platforms.ObjectBody{
 		SymbolFiled:        params.Symbol,
 		"side":             strings.ToUpper(params.Side),
 		"type":             orderType,
@@ -77,21 +81,28 @@ func (c *Connector) PlaceOrder(params types.OrderEntry) (string, error) {
 		"newClientOrderId": params.TradeNo,
 		"newOrderRespType": NewOrderRespTypeFULL,
 		TimeFiled:          time.Now().UnixMilli(),
-	}, constants.Signed, resp)
+	}
+	if !params.StopPrice.IsZero() {
+		body.Set("stopPrice", params.StopPrice.String())
+	}
+	err := c.Call(http.MethodPost, OrderEndpoint, body, constants.Signed, resp)
 
 	return strconv.FormatInt(resp.OrderId, 10), err
 }
 
-func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]string, error) {
-	var list []string
-	for _, order := range orders {
+// BatchOrder spot api has no batch endpoint, orders are placed one by one.
+func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	var results = make([]types.BatchOrderResult, len(orders))
+	for i, order := range orders {
+		results[i].TradeNo = order.TradeNo
 		orderId, err := c.PlaceOrder(order)
 		if err != nil {
+			results[i].Message = err.Error()
 			continue
 		}
-		list = append(list, orderId)
+		results[i].OrderId = orderId
 	}
-	return list, nil
+	return results, nil
 }
 
 type QueryOrder struct {
@@ -159,17 +170,41 @@ func (c *Connector) CancelAll(symbol string) error {
 	}, constants.Signed, nil)
 }
 
-func (c *Connector) CancelByIds(symbol string, orderIds []string) (map[string]bool, error) {
-	var result = make(map[string]bool)
-	for _, id := range orderIds {
+func (c *Connector) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	var result = make([]types.CancelResult, len(orderIds))
+	for i, id := range orderIds {
+		result[i].OrderId = id
 		success, err := c.Cancel(symbol, id)
-		if err != nil {
-			continue
+		if err == nil && !success {
+			err = fmt.Errorf("[Binance] cancel order %s not confirmed", id)
 		}
-		result[id] = success
+		result[i].Err = err
 	}
 	return result, nil
 }
+
+// CancelAllSymbols open orders of every symbol are listed first, then canceled symbol by symbol.
+func (c *Connector) CancelAllSymbols() error {
+	var openOrders []OpenOrder
+	err := c.Call(http.MethodGet, OpenOrdersEndpoint, &platforms.ObjectBody{
+		TimeFiled: time.Now().UnixMilli(),
+	}, constants.Signed, &openOrders)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	var symbols = make(map[string]struct{})
+	for _, order := range openOrders {
+		if _, ok := symbols[order.Symbol]; ok {
+			continue
+		}
+		symbols[order.Symbol] = struct{}{}
+		if err = c.CancelAll(order.Symbol); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
 func (c *Connector) queryOrder(symbol, orderId string) (*QueryOrder, error) {
 	var resp = new(QueryOrder)
 	err := c.Call(http.MethodGet, OrderEndpoint, &platforms.ObjectBody{
@@ -194,6 +229,10 @@ func (c *Connector) QueryOrder(symbol string, orderId string) (types.QueryOrder,
 	if err != nil {
 		return types.QueryOrder{}, err
 	}
+	return resp.convert(), nil
+}
+
+func (resp *QueryOrder) convert() types.QueryOrder {
 	price, _ := decimal.NewFromString(resp.Price)
 	amount, _ := decimal.NewFromString(resp.OrigQty)
 	filled, _ := decimal.NewFromString(resp.ExecutedQty)
@@ -209,7 +248,56 @@ func (c *Connector) QueryOrder(symbol string, orderId string) (types.QueryOrder,
 		CreateTime: resp.Time,
 		UpdateTime: resp.UpdateTime,
 		Filled:     filled,
-	}, nil
+	}
+}
+
+const allOrdersLimit = 1000
+
+func (c *Connector) OrderHistory(ctx context.Context, symbol string, since, until int64, statuses []constants.OrderStatus) ([]types.QueryOrder, error) {
+	var result []types.QueryOrder
+	var orderId int // set once a window held a full page
+	start := since
+	for orderId != 0 || start <= until {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+myTradesWindow, until)
+		var params = &platforms.ObjectBody{
+			SymbolFiled: symbol,
+			"limit":     allOrdersLimit,
+			TimeFiled:   time.Now().UnixMilli(),
+		}
+		if orderId != 0 {
+			params.Set("orderId", orderId)
+		} else {
+			params.Set("startTime", start)
+			params.Set("endTime", end)
+		}
+		var orders []QueryOrder
+		err := c.Call(http.MethodGet, AllOrdersEndpoint, params, constants.Signed, &orders)
+		if err != nil {
+			return nil, err
+		}
+		for i := range orders {
+			if orders[i].Time > until {
+				return result, nil
+			}
+			order := orders[i].convert()
+			if len(statuses) > 0 && !slices.Contains(statuses, order.Status) {
+				continue
+			}
+			result = append(result, order)
+		}
+		if len(orders) == allOrdersLimit {
+			orderId = orders[len(orders)-1].OrderID + 1
+			continue
+		}
+		if orderId != 0 {
+			break
+		}
+		start = end + 1
+	}
+	return result, nil
 }
 
 type OpenOrder struct {
@@ -261,3 +349,92 @@ func (c *Connector) PendingOrders(symbol string) ([]types.OpenOrderEntry, error)
 	}
 	return result, nil
 }
+
+type MyTrade struct {
+	Symbol          string `json:"symbol"`
+	ID              int64  `json:"id"`
+	OrderID         int64  `json:"orderId"`
+	OrderListID     int64  `json:"orderListId"`
+	Price           string `json:"price"`
+	Qty             string `json:"qty"`
+	QuoteQty        string `json:"quoteQty"`
+	Commission      string `json:"commission"`
+	CommissionAsset string `json:"commissionAsset"`
+	Time            int64  `json:"time"`
+	IsBuyer         bool   `json:"isBuyer"`
+	IsMaker         bool   `json:"isMaker"`
+	IsBestMatch     bool   `json:"isBestMatch"`
+}
+
+// myTradesWindow the widest startTime/endTime span accepted by myTrades and allOrders.
+const myTradesWindow = int64(24*time.Hour/time.Millisecond) - 1
+
+const myTradesLimit = 1000
+
+// GetMyTrades walk startTime/endTime windows until one holds a full page, then page by
+// fromId = last id + 1 up to until: myTrades does not combine fromId with a time range, and
+// paging by time would drop the trades sharing the millisecond of a page boundary.
+func (c *Connector) GetMyTrades(ctx context.Context, symbol string, since, until int64) ([]types.FillEntry, error) {
+	var result []types.FillEntry
+	var fromId int64 // set once a window held a full page
+	start := since
+	for fromId != 0 || start <= until {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+myTradesWindow, until)
+		var params = &platforms.ObjectBody{
+			SymbolFiled: symbol,
+			"limit":     myTradesLimit,
+			TimeFiled:   time.Now().UnixMilli(),
+		}
+		if fromId != 0 {
+			params.Set("fromId", fromId)
+		} else {
+			params.Set("startTime", start)
+			params.Set("endTime", end)
+		}
+		var trades []MyTrade
+		err := c.Call(http.MethodGet, MyTradesEndpoint, params, constants.Signed, &trades)
+		if err != nil {
+			return nil, err
+		}
+		for _, trade := range trades {
+			if trade.Time > until {
+				return result, nil
+			}
+			result = append(result, trade.fill())
+		}
+		if len(trades) == myTradesLimit {
+			fromId = trades[len(trades)-1].ID + 1
+			continue
+		}
+		if fromId != 0 {
+			break
+		}
+		start = end + 1
+	}
+	return result, nil
+}
+
+func (trade MyTrade) fill() types.FillEntry {
+	price, _ := decimal.NewFromString(trade.Price)
+	qty, _ := decimal.NewFromString(trade.Qty)
+	fee, _ := decimal.NewFromString(trade.Commission)
+	side := "SELL"
+	if trade.IsBuyer {
+		side = "BUY"
+	}
+	return types.FillEntry{
+		Symbol:      trade.Symbol,
+		TradeId:     strconv.FormatInt(trade.ID, 10),
+		OrderId:     strconv.FormatInt(trade.OrderID, 10),
+		Side:        side,
+		Price:       price,
+		Quantity:    qty,
+		Fee:         fee,
+		FeeCurrency: trade.CommissionAsset,
+		IsMaker:     trade.IsMaker,
+		Timestamp:   trade.Time,
+	}
+}
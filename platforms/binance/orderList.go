@@ -0,0 +1,127 @@
+package binance
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OrderListResponse struct {
+	OrderListId int64 `json:"orderListId"`
+	Orders      []struct {
+		Symbol        string `json:"symbol"`
+		OrderId       int64  `json:"orderId"`
+		ClientOrderId string `json:"clientOrderId"`
+	} `json:"orders"`
+}
+
+// setLeg order fields of one leg, the keys are prefixed by the leg role, e.g. "pendingAbove".
+func setLeg(params platforms.ObjectBody, prefix string, orderType OrderType, order *types.OrderEntry, clientId string) {
+	params[prefix+"Type"] = orderType.String()
+	params[prefix+"ClientOrderId"] = clientId
+	if orderType != OrderTypeStopLoss {
+		params[prefix+"Price"] = order.Price.String()
+	}
+	if orderType == OrderTypeStopLoss || orderType == OrderTypeStopLossLimit {
+		params[prefix+"StopPrice"] = order.StopPrice.String()
+	}
+	if orderType == OrderTypeLimit || orderType == OrderTypeStopLossLimit {
+		params[prefix+"TimeInForce"] = GTC.String()
+	}
+}
+
+func stopLossType(order *types.OrderEntry) OrderType {
+	if order.Price.IsZero() {
+		return OrderTypeStopLoss
+	}
+	return OrderTypeStopLossLimit
+}
+
+func clientId(order *types.OrderEntry) string {
+	if order.TradeNo != "" {
+		return order.TradeNo
+	}
+	return uuid.New().String()
+}
+
+// setExits binance names the legs of an OCO by their price relative to the market:
+// a sell takes profit above and stops below, a buy the other way round.
+func setExits(params platforms.ObjectBody, prefix string, list types.OrderListEntry, takeProfitId, stopLossId string) {
+	above, below := "Above", "Below"
+	if strings.ToUpper(list.TakeProfit.Side) == "BUY" {
+		above, below = below, above
+	}
+	setLeg(params, prefix+above, OrderTypeLimitMaker, list.TakeProfit, takeProfitId)
+	setLeg(params, prefix+below, stopLossType(list.StopLoss), list.StopLoss, stopLossId)
+}
+
+// PlaceOrderList https://developers.binance.com/docs/binance-spot-api-docs/rest-api/trading-endpoints#new-order-list---oco-trade
+func (c *Connector) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	if err := platforms.ValidateOrderList(list); err != nil {
+		return types.OrderListResult{}, err
+	}
+	var ids = make(map[string]*string) // client order id -> result field
+	var result types.OrderListResult
+	var params = platforms.ObjectBody{
+		SymbolFiled:         list.Symbol,
+		"listClientOrderId": uuid.New().String(),
+		TimeFiled:           time.Now().UnixMilli(),
+	}
+	var endpoint string
+	var exit *types.OrderEntry // the pending leg(s) quantity and side
+	switch list.Type {
+	case types.OCO:
+		endpoint = OrderListOcoEndpoint
+		takeProfitId, stopLossId := clientId(list.TakeProfit), clientId(list.StopLoss)
+		ids[takeProfitId], ids[stopLossId] = &result.TakeProfitOrderId, &result.StopLossOrderId
+		params["side"] = strings.ToUpper(list.TakeProfit.Side)
+		params["quantity"] = list.TakeProfit.Quantity.String()
+		setExits(params, "", list, takeProfitId, stopLossId)
+	case types.OTO, types.OTOCO:
+		entryId := clientId(list.Entry)
+		ids[entryId] = &result.EntryOrderId
+		params["workingSide"] = strings.ToUpper(list.Entry.Side)
+		params["workingQuantity"] = list.Entry.Quantity.String()
+		setLeg(params, "working", c.MatchOrderType(list.Entry.Type).(OrderType), list.Entry, entryId)
+		if list.Type == types.OTOCO {
+			endpoint = OrderListOtocoEndpoint
+			exit = list.TakeProfit
+			takeProfitId, stopLossId := clientId(list.TakeProfit), clientId(list.StopLoss)
+			ids[takeProfitId], ids[stopLossId] = &result.TakeProfitOrderId, &result.StopLossOrderId
+			setExits(params, "pending", list, takeProfitId, stopLossId)
+		} else if exit = list.TakeProfit; exit != nil {
+			endpoint = OrderListOtoEndpoint
+			id := clientId(exit)
+			ids[id] = &result.TakeProfitOrderId
+			setLeg(params, "pending", OrderTypeLimitMaker, exit, id)
+		} else {
+			endpoint = OrderListOtoEndpoint
+			exit = list.StopLoss
+			id := clientId(exit)
+			ids[id] = &result.StopLossOrderId
+			setLeg(params, "pending", stopLossType(exit), exit, id)
+		}
+		params["pendingSide"] = strings.ToUpper(exit.Side)
+		params["pendingQuantity"] = exit.Quantity.String()
+	default:
+		return result, fmt.Errorf("unknown order list type %s", list.Type)
+	}
+	var resp OrderListResponse
+	err := c.Call(http.MethodPost, endpoint, &params, constants.Signed, &resp)
+	if err != nil {
+		return result, err
+	}
+	result.ListId = strconv.FormatInt(resp.OrderListId, 10)
+	for _, order := range resp.Orders {
+		if field, ok := ids[order.ClientOrderId]; ok {
+			*field = strconv.FormatInt(order.OrderId, 10)
+		}
+	}
+	return result, nil
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
-	"github.com/xavierzho/go-cexs/utils"
 	"strings"
 )
 
@@ -83,7 +82,7 @@ func (stream *MarketStream) CandleStream(ctx context.Context, symbol, interval s
 					continue
 				}
 				var event StreamResponse[CandleEvent]
-				_ = utils.Json.Unmarshal(msg, &event)
+				_ = stream.Decode(msg, &event)
 				k := event.Data.Kline
 				var line = []any{k.StartTime, k.Open, k.High, k.Low, k.Close, k.Volume, k.QuoteVolume}
 				var result = make(types.CandleEntry, len(line))
@@ -126,7 +125,7 @@ func (stream *MarketStream) DepthStream(ctx context.Context, symbol string, chan
 					continue
 				}
 				var event StreamResponse[DepthEvent]
-				_ = utils.Json.Unmarshal(msg, &event)
+				_ = stream.Decode(msg, &event)
 				channel <- types.DepthEntry{
 					Bids: event.Data.Bids,
 					Asks: event.Data.Asks,
@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
 	"github.com/xavierzho/go-cexs/utils"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 )
@@ -17,7 +19,8 @@ import (
 var listenKeyEndpoint = RestAPI + ListenKeyEndpoint
 
 type UserDataStream struct {
-	base *platforms.StreamBase
+	base       *platforms.StreamBase
+	dispatcher *platforms.Dispatcher
 	*platforms.Credentials
 	listenKey string
 }
@@ -76,6 +79,44 @@ type OrderUpdate struct {
 	STP                     int64  `json:"v,omitempty"` // Prevented Match Id; This is only visible if the order expired due to STP
 }
 
+func (o OrderUpdate) convert() types.OrderUpdateEntry {
+	price, _ := decimal.NewFromString(o.Price)
+	quantity, _ := decimal.NewFromString(o.Quantity)
+	filled, _ := decimal.NewFromString(o.CumulativeQuantity)
+	filledQuote, _ := decimal.NewFromString(o.CumulativeQty)
+	lastPrice, _ := decimal.NewFromString(o.LastExecutedPrice)
+	lastQuantity, _ := decimal.NewFromString(o.LastQuantity)
+	fee, _ := decimal.NewFromString(o.CommissionAmount)
+	var entry = types.OrderUpdateEntry{
+		OrderId:          strconv.Itoa(o.OrderId),
+		ClientOrderId:    o.ClientOrderId,
+		Status:           OrderStatus(o.OrderStatus).Convert(),
+		Symbol:           o.Symbol,
+		Side:             o.Side,
+		Type:             OrderType(o.OrderType).Convert(),
+		Price:            price,
+		Quantity:         quantity,
+		FilledQuantity:   filled,
+		FilledQuote:      filledQuote,
+		LastFillPrice:    lastPrice,
+		LastFillQuantity: lastQuantity,
+		Fee:              fee,
+		FeeAsset:         o.CommissionAsset,
+		IsMaker:          o.TradeMakerSide,
+		EventTime:        o.Time,
+		TransactionTime:  o.TransactionTime,
+	}
+	// trade id is -1 when the execution is not a trade
+	if o.TradeId > 0 {
+		entry.TradeId = strconv.Itoa(o.TradeId)
+	}
+	// reject reason is NONE unless the order was rejected
+	if o.RejectReason != "NONE" {
+		entry.RejectReason = o.RejectReason
+	}
+	return entry
+}
+
 type BalanceUpdate struct {
 	StreamEvent
 	Asset     string `json:"a"`
@@ -108,10 +149,27 @@ type StreamResponse[T Event] struct {
 }
 
 func NewUserStream(creds *platforms.Credentials) *UserDataStream {
-	return &UserDataStream{
+	stream := &UserDataStream{
 		Credentials: creds,
 		base:        platforms.NewStream(),
 	}
+	stream.dispatcher = platforms.NewDispatcher(stream.base, stream.route)
+	stream.dispatcher.Recover = stream.Reconnect
+	return stream
+}
+
+// SetLogger log through logger, nil restores the silent default.
+func (stream *UserDataStream) SetLogger(logger *slog.Logger) {
+	stream.base.SetLogger(logger)
+}
+
+func (stream *UserDataStream) Logger() *slog.Logger {
+	return stream.base.Logger()
+}
+
+// Observe report the traffic of the stream to observer.
+func (stream *UserDataStream) Observe(observer platforms.StreamObserver) {
+	stream.base.Observe(observer)
 }
 
 // https://developers.binance.com/docs/binance-spot-api-docs/user-data-stream#create-a-listenkey-user_stream
@@ -183,11 +241,11 @@ func (stream *UserDataStream) Reconnect() error {
 	var attempt int
 	for attempt = 0; attempt < 3; attempt++ {
 		_ = stream.closeListenKey(stream.listenKey)
-		log.Printf("Reconnection attempt %d of %d...\n", attempt+1, 3)
+		stream.base.Logger().Info("reconnecting", slog.String("platform", string(constants.Binance)), slog.Int("attempt", attempt+1), slog.Int("attempts", 3))
 
 		// 获取新的 listenKey 并尝试连接
 		if err := stream.getListenKey(); err != nil {
-			log.Printf("Failed to get listenKey: %v\n", err)
+			stream.base.Logger().Warn("listen key failed", slog.String("platform", string(constants.Binance)), slog.String("error", err.Error()))
 			//time.Sleep(stream.reconnectInterval)
 			continue
 		}
@@ -195,12 +253,12 @@ func (stream *UserDataStream) Reconnect() error {
 		// 尝试建立 WebSocket 连接
 		err := stream.base.Connect(StreamAPI + "?streams=" + stream.listenKey)
 		if err != nil {
-			log.Printf("Failed to reconnect WebSocket: %v\n", err)
+			stream.base.Logger().Warn("reconnect failed", slog.String("platform", string(constants.Binance)), slog.String("error", err.Error()))
 			//time.Sleep(stream.reconnectInterval)
 			continue
 		}
 
-		log.Println("Reconnected successfully")
+		stream.base.Logger().Info("reconnected", slog.String("platform", string(constants.Binance)))
 
 		// 重新开始接收消息
 		return nil
@@ -221,117 +279,77 @@ func (stream *UserDataStream) Login() error {
 	return nil
 }
 
-func (stream *UserDataStream) OrderStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
-	err := stream.base.Connect(StreamAPI)
-	if err != nil {
-		return err
+// route event type of a push. An expired listen key is renewed right here on the reader,
+// the streams go on reading from the new connection.
+func (stream *UserDataStream) route(msg []byte) string {
+	eventType := EventType(utils.Json.Get(msg, "data", "e").ToString())
+	if eventType == ExpiredEventType {
+		stream.base.Logger().Info("listen key expired, reconnecting", slog.String("platform", string(constants.Binance)))
+		if err := stream.Reconnect(); err != nil {
+			stream.base.Logger().Error("reconnect after listen key expiry failed", slog.String("platform", string(constants.Binance)), slog.String("error", err.Error()))
+		}
+		return ""
 	}
-	go func(ctx context.Context) {
-		for {
-			select {
-			case <-ctx.Done():
+	return string(eventType)
+}
 
-				return
-			default:
-				msg, err := stream.base.ReadMessage()
-				if err != nil {
-					continue
-				}
-				switch EventType(utils.Json.Get(msg, "data", "e").ToString()) {
-				case OrderEventType:
-					var event StreamResponse[OrderUpdate]
-
-					_ = utils.Json.Unmarshal(msg, &event)
-					channel <- types.OrderUpdateEntry{
-						OrderId:       strconv.Itoa(event.Data.OrderId),
-						ClientOrderId: event.Data.ClientOrderId,
-						Status:        OrderStatus(event.Data.OrderStatus).Convert(),
-					}
-				case ExpiredEventType:
-					log.Println("ListenKey expired, reconnecting...")
-					// 当 listenKey 过期时，重新连接
-					if err := stream.Reconnect(); err != nil {
-						log.Printf("Failed to reconnect after listenKey expired: %v\n", err)
-						return
-					}
-				}
-			}
+// subscribe the listen key connection pushes every event type, nothing is sent to subscribe.
+func (stream *UserDataStream) subscribe(ctx context.Context, eventType EventType, handle func(msg []byte)) error {
+	return stream.dispatcher.Subscribe(ctx, string(eventType), func() error { return nil }, handle)
+}
+
+// OrderStream reads executionReport events from the listen key connection opened by Login.
+func (stream *UserDataStream) OrderStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
+	return stream.subscribe(ctx, OrderEventType, func(msg []byte) {
+		var event StreamResponse[OrderUpdate]
+		if err := stream.base.Decode(msg, &event); err != nil {
+			return
 		}
-	}(ctx)
-	return nil
+		if !platforms.Deliver(ctx, channel, event.Data.convert()) {
+			return
+		}
+	})
 }
 
+// BalanceStream reads balanceUpdate events from the listen key connection opened by Login.
 func (stream *UserDataStream) BalanceStream(ctx context.Context, channel chan<- types.BalanceUpdateEntry) error {
-	err := stream.base.Connect(StreamAPI)
-	if err != nil {
-		return err
-	}
-	go func(ctx context.Context) {
-		for {
-			select {
-			case <-ctx.Done():
-
-				return
-			default:
-				msg, err := stream.base.ReadMessage()
-				if err != nil {
-					continue
-				}
-				switch EventType(utils.Json.Get(msg, "data", "e").ToString()) {
-				case BalanceEventType:
-					var event StreamResponse[BalanceUpdate]
-
-					_ = utils.Json.Unmarshal(msg, &event)
-					channel <- types.BalanceUpdateEntry{}
-				case ExpiredEventType:
-					log.Println("ListenKey expired, reconnecting...")
-					// 当 listenKey 过期时，重新连接
-					if err := stream.Reconnect(); err != nil {
-						log.Printf("Failed to reconnect after listenKey expired: %v\n", err)
-						return
-					}
-				}
-
-			}
+	return stream.subscribe(ctx, BalanceEventType, func(msg []byte) {
+		var event StreamResponse[BalanceUpdate]
+		if err := stream.base.Decode(msg, &event); err != nil {
+			return
 		}
-	}(ctx)
-
-	return nil
+		delta, _ := decimal.NewFromString(event.Data.Delta)
+		if !platforms.Deliver(ctx, channel, types.BalanceUpdateEntry{
+			Asset:     event.Data.Asset,
+			Delta:     delta,
+			Reason:    event.Data.Type,
+			Timestamp: event.Data.ClearTime,
+		}) {
+			return
+		}
+	})
 }
 
+// AccountStream reads outboundAccountPosition events from the listen key connection opened by Login.
 func (stream *UserDataStream) AccountStream(ctx context.Context, channel chan<- types.AccountUpdateEntry) error {
-	err := stream.base.Connect(StreamAPI)
-	if err != nil {
-		return err
-	}
-	go func(ctx context.Context) {
-		for {
-			select {
-			case <-ctx.Done():
+	return stream.subscribe(ctx, AccountEventType, func(msg []byte) {
+		var event StreamResponse[AccountUpdate]
+		if err := stream.base.Decode(msg, &event); err != nil {
+			return
+		}
+		// outboundAccountPosition only carries the assets that changed
+		for _, asset := range event.Data.Balances {
+			free, _ := decimal.NewFromString(asset.Free)
+			locked, _ := decimal.NewFromString(asset.Lock)
+			if !platforms.Deliver(ctx, channel, types.AccountUpdateEntry{
+				Asset:     asset.Asset,
+				Free:      free,
+				Locked:    locked,
+				Reason:    event.Data.Type,
+				Timestamp: event.Data.UpdateTimestamp,
+			}) {
 				return
-			default:
-				msg, err := stream.base.ReadMessage()
-				if err != nil {
-					continue
-				}
-				switch EventType(utils.Json.Get(msg, "data", "e").ToString()) {
-				case AccountEventType:
-					var event StreamResponse[AccountUpdate]
-
-					_ = utils.Json.Unmarshal(msg, &event)
-					channel <- types.AccountUpdateEntry{}
-				case ExpiredEventType:
-					log.Println("ListenKey expired, reconnecting...")
-					// 当 listenKey 过期时，重新连接
-					if err := stream.Reconnect(); err != nil {
-						log.Printf("Failed to reconnect after listenKey expired: %v\n", err)
-						return
-					}
-				}
-
 			}
 		}
-	}(ctx)
-
-	return nil
+	})
 }
@@ -0,0 +1,100 @@
+package binance
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
+)
+
+// WsAPI https://developers.binance.com/docs/binance-spot-api-docs/web-socket-api/general-api-information
+const WsAPI = "wss://ws-api.binance.com:443/ws-api/v3"
+
+// WsOrderProtocol binance websocket api needs no session, every request is signed like REST.
+type WsOrderProtocol struct {
+	*Connector
+}
+
+func NewWsTrader(cred *platforms.Credentials, client *http.Client) *platforms.WsTrader {
+	connector := NewConnector(cred, client)
+	return platforms.NewWsTrader(connector, &WsOrderProtocol{connector})
+}
+
+func (p *WsOrderProtocol) URL() string {
+	return WsAPI
+}
+
+func (p *WsOrderProtocol) Login(*platforms.StreamBase) error {
+	return nil
+}
+
+func (p *WsOrderProtocol) request(id, method string, params platforms.ObjectBody) map[string]any {
+	params["apiKey"] = p.APIKey
+	params[TimeFiled] = time.Now().UnixMilli()
+	encoded, _ := params.EncodeQuery()
+	params[SignatureFiled] = p.Sign([]byte(encoded))
+	return map[string]any{
+		"id":     id,
+		"method": method,
+		"params": params,
+	}
+}
+
+func (p *WsOrderProtocol) PlaceOrder(id string, order types.OrderEntry) map[string]any {
+	params := platforms.ObjectBody{
+		SymbolFiled:   p.SymbolPattern(order.Symbol),
+		"side":        strings.ToUpper(order.Side),
+		"type":        p.MatchOrderType(order.Type).String(),
+		"price":       order.Price.StringFixed(8),
+		"quantity":    order.Quantity.StringFixed(2),
+		"timeInForce": GTC.String(),
+	}
+	if order.TradeNo != "" {
+		params["newClientOrderId"] = order.TradeNo
+	}
+	return p.request(id, "order.place", params)
+}
+
+func (p *WsOrderProtocol) Cancel(id, symbol, orderId string) map[string]any {
+	return p.request(id, "order.cancel", platforms.ObjectBody{
+		SymbolFiled: p.SymbolPattern(symbol),
+		"orderId":   orderId,
+	})
+}
+
+type WsReply struct {
+	Id     string `json:"id"`
+	Status int    `json:"status"`
+	Result struct {
+		OrderId int64 `json:"orderId"`
+	} `json:"result"`
+	Error ErrorResponse `json:"error"`
+}
+
+func (p *WsOrderProtocol) ReplyId(msg []byte) (string, bool) {
+	var reply WsReply
+	if err := utils.Json.Unmarshal(msg, &reply); err != nil || reply.Id == "" {
+		return "", false
+	}
+	return reply.Id, true
+}
+
+func (p *WsOrderProtocol) OrderId(msg []byte) (string, error) {
+	var reply WsReply
+	if err := utils.Json.Unmarshal(msg, &reply); err != nil {
+		return "", err
+	}
+	if reply.Status != http.StatusOK {
+		return "", &platforms.ExchangeError{
+			Exchange: "Binance",
+			Code:     strconv.FormatInt(reply.Error.Code, 10),
+			Message:  reply.Error.Msg,
+			Err:      errorKinds[reply.Error.Code],
+		}
+	}
+	return strconv.FormatInt(reply.Result.OrderId, 10), nil
+}
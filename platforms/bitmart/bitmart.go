@@ -1,14 +1,18 @@
 package bitmart
 
 import (
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
 	"net/http"
 )
 
 type Connector struct {
 	*platforms.Credentials
 	Client *http.Client
+	platforms.Middlewares
+	platforms.Logging
 }
 
 func (c *Connector) Name() constants.Platform {
@@ -25,3 +29,35 @@ func NewConnector(base *platforms.Credentials, client *http.Client) platforms.Sp
 func (c *Connector) SymbolPattern(symbol string) string {
 	return constants.SymbolWithUnderline(symbol)
 }
+
+type TradeFeeResponse struct {
+	Symbol           string `json:"symbol"`
+	BuyTakerFeeRate  string `json:"buy_taker_fee_rate"`
+	SellTakerFeeRate string `json:"sell_taker_fee_rate"`
+	BuyMakerFeeRate  string `json:"buy_maker_fee_rate"`
+	SellMakerFeeRate string `json:"sell_maker_fee_rate"`
+}
+
+func (c *Connector) GetFeeRates(symbols []string) (map[string]types.FeeRateEntry, error) {
+	var result = make(map[string]types.FeeRateEntry, len(symbols))
+	for _, symbol := range symbols {
+		var response TradeFeeResponse
+		err := c.Call(http.MethodGet, TradeFeeEndpoint, &platforms.ObjectBody{
+			SymbolFiled: symbol,
+		}, constants.Keyed, &response)
+		if err != nil {
+			return nil, err
+		}
+		// bitmart quotes buy and sell separately, keep the higher one so quoting stays conservative
+		buyMaker, _ := decimal.NewFromString(response.BuyMakerFeeRate)
+		sellMaker, _ := decimal.NewFromString(response.SellMakerFeeRate)
+		buyTaker, _ := decimal.NewFromString(response.BuyTakerFeeRate)
+		sellTaker, _ := decimal.NewFromString(response.SellTakerFeeRate)
+		result[symbol] = types.FeeRateEntry{
+			Symbol: symbol,
+			Maker:  decimal.Max(buyMaker, sellMaker),
+			Taker:  decimal.Max(buyTaker, sellTaker),
+		}
+	}
+	return result, nil
+}
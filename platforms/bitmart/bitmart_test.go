@@ -116,3 +116,73 @@ func TestGetCandles(t *testing.T) {
 	}
 	fmt.Println(candles)
 }
+
+// tradesServer answers query/trades from trades, oldest first, like bitmart: newest first, back from fromId.
+func tradesServer(trades []Trade) platforms.Middleware {
+	return func(platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			fromId := invocation.Param("fromId")
+			var page []Trade
+			for i := len(trades) - 1; i >= 0 && len(page) < tradesLimit; i-- {
+				if fromId != "" && len(page) == 0 && trades[i].TradeID != fromId {
+					continue
+				}
+				page = append(page, trades[i])
+			}
+			*invocation.Result.(*[]Trade) = page
+			return nil
+		}
+	}
+}
+
+func TestGetMyTradesPaging(t *testing.T) {
+	// more trades in one millisecond than a page holds
+	var trades []Trade
+	for id := 1; id <= 500; id++ {
+		trades = append(trades, Trade{TradeID: fmt.Sprint(id), Symbol: "BTC_USDT", CreateTime: 5000, Price: "1", Size: "1"})
+	}
+	cex := NewConnector(&platforms.Credentials{}, nil)
+	cex.(platforms.Interceptable).Use(tradesServer(trades))
+	fills, err := cex.GetMyTrades(context.Background(), "BTCUSDT", 0, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 500 || fills[0].TradeId != "500" || fills[499].TradeId != "1" || fills[0].Symbol != "BTCUSDT" {
+		t.Errorf("want trades 500 down to 1, got %d", len(fills))
+	}
+}
+
+// historyServer answers query/history-orders from orders, oldest first, like bitmart: newest first, back from fromId.
+func historyServer(orders []QueryOrder) platforms.Middleware {
+	return func(platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			fromId := invocation.Param("fromId")
+			var page []QueryOrder
+			for i := len(orders) - 1; i >= 0 && len(page) < historyLimit; i-- {
+				if fromId != "" && len(page) == 0 && orders[i].OrderID != fromId {
+					continue
+				}
+				page = append(page, orders[i])
+			}
+			*invocation.Result.(*[]QueryOrder) = page
+			return nil
+		}
+	}
+}
+
+func TestOrderHistoryPaging(t *testing.T) {
+	// more orders in one millisecond than a page holds
+	var orders []QueryOrder
+	for id := 1; id <= 250; id++ {
+		orders = append(orders, QueryOrder{OrderID: fmt.Sprint(id), Symbol: "BTC_USDT", CreateTime: 5000})
+	}
+	cex := NewConnector(&platforms.Credentials{}, nil)
+	cex.(platforms.Interceptable).Use(historyServer(orders))
+	history, err := cex.OrderHistory(context.Background(), "BTCUSDT", 0, 8000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 250 || history[0].OrderId != "250" || history[249].OrderId != "1" {
+		t.Errorf("want orders 250 down to 1, got %d", len(history))
+	}
+}
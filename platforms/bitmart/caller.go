@@ -37,8 +37,21 @@ type Response struct {
 	Data    json.RawMessage `json:"data"`
 }
 
+// errorKinds error codes with a unified meaning
+var errorKinds = map[int]error{
+	50030: platforms.ErrOrderClosed,   // Order is already canceled
+	50031: platforms.ErrOrderNotFound, // Order does not exist
+}
+
 func (c *Connector) Call(method string, route string, params platforms.Serializer, authType constants.AuthType,
 	returnType any) error {
+	return c.Invoke(&platforms.Invocation{Platform: c.Name(), Method: method, Route: route, AuthType: authType,
+		Params: params, Result: returnType}, c.Logged(c.call))
+}
+
+// call the request of one invocation, the middlewares of the connector run around it
+func (c *Connector) call(invocation *platforms.Invocation) error {
+	method, route, params, authType, returnType := invocation.Method, invocation.Route, invocation.Params, invocation.AuthType, invocation.Result
 	var err error
 	timestamp := time.Now()
 	header := http.Header{}
@@ -84,19 +97,28 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 		url = fmt.Sprintf("%s?%s", url, query)
 		req, err = http.NewRequest(http.MethodGet, url, nil)
 	} else if method == http.MethodPost {
-		req.Body = io.NopCloser(body)
+		req.Body = io.NopCloser(bytes.NewReader(bodyData.Bytes()))
+		req.ContentLength = int64(bodyData.Len())
+		invocation.RequestBody = bodyData.Bytes()
 	}
 	var response Response
+	invocation.Request = req
 	resp, err := c.Client.Do(req)
+	if err != nil {
+		return platforms.NetworkError(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	invocation.StatusCode, invocation.Header, invocation.Response = resp.StatusCode, resp.Header, respBody
+	err = json.Unmarshal(respBody, &response)
 	if err != nil {
 		return err
 	}
 	if response.Code != 1000 || resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("[Bitmart](error code=%d) %s", response.Code, response.Message)
+		return &platforms.ExchangeError{Exchange: "Bitmart", Code: strconv.Itoa(response.Code), Message: response.Message, Err: errorKinds[response.Code]}
 	}
 	return json.Unmarshal(response.Data, returnType)
 }
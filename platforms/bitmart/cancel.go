@@ -3,20 +3,28 @@ package bitmart
 import (
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
 	"net/http"
+	"slices"
 )
 
 func (c *Connector) CancelAll(symbol string) error {
 	var response map[string]interface{}
 	err := c.Call(http.MethodPost, CancelAllEndpoint, &platforms.ObjectBody{
 		SymbolFiled: symbol,
-	}, constants.Signed, response)
+	}, constants.Signed, &response)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// CancelAllSymbols cancel_all without symbol cancels the orders of every symbol.
+func (c *Connector) CancelAllSymbols() error {
+	var response map[string]interface{}
+	return c.Call(http.MethodPost, CancelAllEndpoint, &platforms.ObjectBody{}, constants.Signed, &response)
+}
+
 func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
 	var response struct {
 		Result bool `json:"result"`
@@ -39,21 +47,29 @@ type CancelIdsResponse struct {
 	FailedCount  int64    `json:"failedCount"`
 }
 
-func (c *Connector) CancelByIds(symbol string, orderIds []string) (map[string]bool, error) {
-	var response CancelIdsResponse
-	var result = make(map[string]bool)
-	err := c.Call(http.MethodPost, CancelsEndpoint, &platforms.ObjectBody{
-		"symbol":   symbol,
-		"orderIds": orderIds,
-	}, constants.Signed, &response)
-	if err != nil {
-		return nil, err
-	}
-	for _, id := range response.SuccessIds {
-		result[id] = true
-	}
-	for _, id := range response.FailIds {
-		result[id] = false
+// cancelsLimit maximum order ids per cancel_orders request
+const cancelsLimit = 10
+
+// CancelByIds bitmart only reports which ids failed, not why.
+func (c *Connector) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	var result = make([]types.CancelResult, len(orderIds))
+	for start := 0; start < len(orderIds); start += cancelsLimit {
+		end := min(start+cancelsLimit, len(orderIds))
+		var response CancelIdsResponse
+		err := c.Call(http.MethodPost, CancelsEndpoint, &platforms.ObjectBody{
+			SymbolFiled: symbol,
+			"orderIds":  orderIds[start:end],
+		}, constants.Signed, &response)
+		for j, id := range orderIds[start:end] {
+			index := start + j
+			result[index].OrderId = id
+			switch {
+			case err != nil:
+				result[index].Err = err
+			case slices.Contains(response.FailIds, id):
+				result[index].Err = &platforms.ExchangeError{Exchange: "Bitmart", Message: "cancel failed"}
+			}
+		}
 	}
 	return result, nil
 }
@@ -77,8 +77,22 @@ const (
 	TickerEndpoint     = "/spot/quotation/v3/ticker"
 	ServerTimeEndpoint = "/system/time"
 	KlineEndpoint      = "/spot/quotation/v3/lite-klines"
+	TradesEndpoint     = "/spot/v4/query/trades"
+	HistoryEndpoint    = "/spot/v4/query/history-orders"
+	TradeFeeEndpoint   = "/spot/v1/trade_fee"
 )
 
 const (
 	SymbolFiled = "symbol"
 )
+
+const (
+	TradeRoleMaker = "maker"
+	TradeRoleTaker = "taker"
+)
+
+// order stream exec_type
+const (
+	ExecTypeMaker = "M"
+	ExecTypeTaker = "T"
+)
@@ -1,10 +1,14 @@
 package bitmart
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/xavierzho/go-cexs/platforms"
-	"log"
-	"math"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -53,59 +57,82 @@ type BatchResponse struct {
 	Code int    `json:"code"`
 	Msg  string `json:"msg"`
 	Data struct {
-		OrderIds []string `json:"order_ids"`
+		OrderIds []string `json:"orderIds"`
 	} `json:"data"`
 }
 
-func (c *Connector) BatchOrder(params []types.OrderEntry) ([]string, error) {
+// batchOrderLimit maximum orders per batch request, all of the same symbol
+const batchOrderLimit = 10
+
+// PlaceOrderList bitmart spot has no order lists, see platforms.OrderListEmulator.
+func (c *Connector) PlaceOrderList(types.OrderListEntry) (types.OrderListResult, error) {
+	return types.OrderListResult{}, platforms.ErrNotSupported
+}
+
+// BatchOrder https://developer-pro.bitmart.com/en/spot/#new-batch-order-v4-signed
+func (c *Connector) BatchOrder(params []types.OrderEntry) ([]types.BatchOrderResult, error) {
 	// Prepare orders
 	orders := make([]map[string]interface{}, len(params))
+	results := make([]types.BatchOrderResult, len(params))
 	for i, arg := range params {
+		results[i].TradeNo = arg.TradeNo
 		orders[i] = map[string]interface{}{
 			"size":          arg.Quantity.StringFixed(1),
 			"price":         arg.Price.StringFixed(11),
 			"side":          strings.ToLower(arg.Side),
-			SymbolFiled:     arg.Symbol,
 			"type":          c.MatchOrderType(arg.Type).String(),
 			"clientOrderId": arg.TradeNo,
 		}
 	}
 
-	maxSize := 10
-	numBatches := int(math.Ceil(float64(len(orders)) / float64(maxSize)))
-	results := make([]string, len(orders))
 	var mu sync.Mutex
 	var wg sync.WaitGroup
+	var errs []error
 
-	for i := 0; i < numBatches; i++ {
+	for i, chunk := range platforms.ChunkOrders(params, batchOrderLimit, true) {
 		wg.Add(1)
-		batchStart := i * maxSize
-		batchEnd := int(math.Min(float64(batchStart+maxSize), float64(len(orders))))
-		batchOrders := orders[batchStart:batchEnd]
-
-		go func(i int, batchOrders []map[string]interface{}) {
+		go func(i int, chunk []int) {
 			defer wg.Done()
-			params := &platforms.ObjectBody{
-				SymbolFiled:   batchOrders[0][SymbolFiled],
+			var batchOrders = make([]map[string]interface{}, len(chunk))
+			for j, index := range chunk {
+				batchOrders[j] = orders[index]
+			}
+			body := &platforms.ObjectBody{
+				SymbolFiled:   params[chunk[0]].Symbol,
 				"orderParams": batchOrders,
 			}
 			var response BatchResponse
-			err := c.Call(http.MethodPost, BatchOrderEndpoint, params, constants.Signed, &response)
+			err := c.Call(http.MethodPost, BatchOrderEndpoint, body, constants.Signed, &response)
+			// the whole batch is rejected when one order fails
+			if err == nil && response.Code != 0 {
+				err = fmt.Errorf("[Bitmart](error code=%d) %s", response.Code, response.Msg)
+			}
 			mu.Lock()
 			defer mu.Unlock()
 			if err != nil {
-				log.Printf("Error in batch %d: %v\n", i, err)
-			} else {
-				// 将批次的订单 ID 填充到结果中，保证原始顺序
-				for j, id := range response.Data.OrderIds {
-					results[batchStart+j] = id
+				c.Logger().Warn("batch order failed", slog.String("platform", string(c.Name())), slog.Int("batch", i), slog.Int("orders", len(chunk)), slog.String("error", err.Error()))
+				errs = append(errs, err)
+				for _, index := range chunk {
+					if response.Code != 0 {
+						results[index].Code = strconv.Itoa(response.Code)
+						results[index].Message = response.Msg
+						continue
+					}
+					results[index].Message = err.Error()
+				}
+				return
+			}
+			// order ids are in request order
+			for j, id := range response.Data.OrderIds {
+				if j < len(chunk) {
+					results[chunk[j]].OrderId = id
 				}
 			}
-		}(i, batchOrders)
+		}(i, chunk)
 	}
 
 	wg.Wait()
-	return results, nil
+	return results, errors.Join(errs...)
 }
 
 type QueryOrder struct {
@@ -141,6 +168,10 @@ func (c *Connector) QueryOrder(_ string, orderId string) (types.QueryOrder, erro
 	if err != nil {
 		return types.QueryOrder{}, err
 	}
+	return resp.convert(), nil
+}
+
+func (resp QueryOrder) convert() types.QueryOrder {
 	price, _ := decimal.NewFromString(resp.Price)
 	amount, _ := decimal.NewFromString(resp.Size)
 	symbol, _ := constants.StandardizeSymbol(resp.Symbol)
@@ -157,7 +188,50 @@ func (c *Connector) QueryOrder(_ string, orderId string) (types.QueryOrder, erro
 		CreateTime: resp.CreateTime,
 		UpdateTime: resp.UpdateTime,
 		Filled:     filled,
-	}, nil
+	}
+}
+
+const historyLimit = 100
+
+func (c *Connector) OrderHistory(ctx context.Context, symbol string, since, until int64, statuses []constants.OrderStatus) ([]types.QueryOrder, error) {
+	var result []types.QueryOrder
+	// orders are returned newest first, a page continues back from the order fromId, which comes first again
+	var fromId string
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var params = &platforms.ObjectBody{
+			SymbolFiled: symbol,
+			"orderMode": "spot",
+			"startTime": since,
+			"endTime":   until,
+			"limit":     historyLimit,
+		}
+		if fromId != "" {
+			params.Set("fromId", fromId)
+		}
+		var response []QueryOrder
+		err := c.Call(http.MethodPost, HistoryEndpoint, params, constants.Signed, &response)
+		if err != nil {
+			return nil, err
+		}
+		for _, resp := range response {
+			if resp.OrderID == fromId {
+				continue
+			}
+			order := resp.convert()
+			if len(statuses) > 0 && !slices.Contains(statuses, order.Status) {
+				continue
+			}
+			result = append(result, order)
+		}
+		if len(response) < historyLimit || response[len(response)-1].OrderID == fromId {
+			break
+		}
+		fromId = response[len(response)-1].OrderID
+	}
+	return result, nil
 }
 
 func (c *Connector) GetOrderStatus(_ string, orderId string) (constants.OrderStatus, error) {
@@ -199,3 +273,76 @@ func (c *Connector) PendingOrders(symbol string) ([]types.OpenOrderEntry, error)
 	}
 	return result, nil
 }
+
+type Trade struct {
+	TradeID       string `json:"tradeId"`
+	OrderID       string `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId"`
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`
+	OrderMode     string `json:"orderMode"`
+	Type          string `json:"type"`
+	Price         string `json:"price"`
+	Size          string `json:"size"`
+	Notional      string `json:"notional"`
+	Fee           string `json:"fee"`
+	FeeCoinName   string `json:"feeCoinName"`
+	TradeRole     string `json:"tradeRole"`
+	CreateTime    int64  `json:"createTime"`
+	UpdateTime    int64  `json:"updateTime"`
+}
+
+const tradesLimit = 200
+
+func (c *Connector) GetMyTrades(ctx context.Context, symbol string, since, until int64) ([]types.FillEntry, error) {
+	var result []types.FillEntry
+	// trades are returned newest first, a page continues back from the trade fromId, which comes first again
+	var fromId string
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var params = &platforms.ObjectBody{
+			SymbolFiled: symbol,
+			"orderMode": "spot",
+			"startTime": since,
+			"endTime":   until,
+			"limit":     tradesLimit,
+		}
+		if fromId != "" {
+			params.Set("fromId", fromId)
+		}
+		var response []Trade
+		err := c.Call(http.MethodPost, TradesEndpoint, params, constants.Signed, &response)
+		if err != nil {
+			return nil, err
+		}
+		for _, trade := range response {
+			if trade.TradeID == fromId {
+				continue
+			}
+			price, _ := decimal.NewFromString(trade.Price)
+			size, _ := decimal.NewFromString(trade.Size)
+			fee, _ := decimal.NewFromString(trade.Fee)
+			symbol, _ := constants.StandardizeSymbol(trade.Symbol)
+			result = append(result, types.FillEntry{
+				Symbol:      symbol,
+				TradeId:     trade.TradeID,
+				OrderId:     trade.OrderID,
+				TradeNo:     trade.ClientOrderID,
+				Side:        strings.ToUpper(trade.Side),
+				Price:       price,
+				Quantity:    size,
+				Fee:         fee,
+				FeeCurrency: trade.FeeCoinName,
+				IsMaker:     trade.TradeRole == TradeRoleMaker,
+				Timestamp:   trade.CreateTime,
+			})
+		}
+		if len(response) < tradesLimit || response[len(response)-1].TradeID == fromId {
+			break
+		}
+		fromId = response[len(response)-1].TradeID
+	}
+	return result, nil
+}
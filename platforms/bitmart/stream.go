@@ -6,7 +6,6 @@ import (
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
-	"github.com/xavierzho/go-cexs/utils"
 )
 
 type MarketStream struct {
@@ -65,7 +64,7 @@ func (stream *MarketStream) CandleStream(ctx context.Context, symbol, interval s
 				}
 				var event StreamResp[CandleUpdate]
 
-				_ = utils.Json.Unmarshal(msg, &event)
+				_ = stream.Decode(msg, &event)
 				for _, d := range event.Data {
 					//var candle = new(types.CandleEntry)
 					//candle.FromList(datum.Candle, keys)
@@ -127,10 +126,11 @@ func (stream *MarketStream) DepthStream(ctx context.Context, symbol string, chan
 				}
 				var event StreamResp[Depth]
 
-				_ = utils.Json.Unmarshal(msg, &event)
+				_ = stream.Decode(msg, &event)
 				channel <- types.DepthEntry{
-					Asks: event.Data[0].Asks,
-					Bids: event.Data[0].Bids,
+					Asks:     event.Data[0].Asks,
+					Bids:     event.Data[0].Bids,
+					Snapshot: event.Data[0].Type == "snapshot",
 				}
 			}
 		}
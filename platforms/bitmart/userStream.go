@@ -6,9 +6,14 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
 	"github.com/xavierzho/go-cexs/utils"
@@ -18,14 +23,20 @@ type UserDataStream struct {
 	credentials *platforms.Credentials
 
 	*platforms.StreamBase
+	dispatcher    *platforms.Dispatcher
+	mux           sync.Mutex
+	subscriptions []string // topics, replayed after re-login
 }
 
 func NewUserStream(credentials *platforms.Credentials) *UserDataStream {
-	stream := platforms.NewStream()
-	return &UserDataStream{
+	stream := &UserDataStream{
 		credentials: credentials,
-		StreamBase:  stream,
+		StreamBase:  platforms.NewStream(),
 	}
+	stream.dispatcher = platforms.NewDispatcher(stream.StreamBase, route)
+	stream.dispatcher.ReplyId = replyId
+	stream.dispatcher.Recover = stream.Reconnect
+	return stream
 }
 func (stream *UserDataStream) Login() error {
 	var timestamp = strconv.FormatInt(time.Now().UnixMilli(), 10)
@@ -45,6 +56,58 @@ func (stream *UserDataStream) Login() error {
 	})
 }
 
+// Reconnect dial and login again, then replay every subscription. The acks are left to the reader.
+func (stream *UserDataStream) Reconnect() error {
+	stream.mux.Lock()
+	defer stream.mux.Unlock()
+	if err := stream.Login(); err != nil {
+		return err
+	}
+	for _, topic := range stream.subscriptions {
+		if err := stream.WriteJSON(subscribePayload(topic)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func subscribePayload(topic string) map[string]any {
+	return map[string]any{
+		"op":   "subscribe",
+		"args": []string{topic},
+	}
+}
+
+// route table of a push.
+func route(msg []byte) string {
+	return utils.Json.Get(msg, "table").ToString()
+}
+
+// replyId bitmart replies carry no request id, only the event of the request.
+// Subscriptions are made one at a time, so the event is enough to match them.
+func replyId(msg []byte) (string, bool) {
+	event := utils.Json.Get(msg, "event").ToString()
+	return event, event != ""
+}
+
+// subscribe topic once for all the streams reading its table, handle receives its pushes until ctx is done.
+func (stream *UserDataStream) subscribe(ctx context.Context, topic string, handle func(msg []byte)) error {
+	table, _, _ := strings.Cut(topic, ":")
+	return stream.dispatcher.Subscribe(ctx, table, func() error {
+		reply, err := stream.dispatcher.Request("subscribe", subscribePayload(topic))
+		if err != nil {
+			return err
+		}
+		if code := utils.Json.Get(reply, "errorCode").ToString(); code != "" {
+			return fmt.Errorf("[Bitmart stream] subscribe %s failed: %s %s", topic, code, utils.Json.Get(reply, "errorMessage").ToString())
+		}
+		stream.mux.Lock()
+		stream.subscriptions = append(stream.subscriptions, topic)
+		stream.mux.Unlock()
+		return nil
+	}, handle)
+}
+
 func (stream *UserDataStream) Sign(timestamp string) string {
 	var message = new(bytes.Buffer)
 	message.WriteString(timestamp)
@@ -88,6 +151,38 @@ func (o OrderUpdate) GetSymbol() string {
 	return o.Symbol
 }
 
+func (o OrderUpdate) convert() types.OrderUpdateEntry {
+	symbol, _ := constants.StandardizeSymbol(o.Symbol)
+	price, _ := decimal.NewFromString(o.Price)
+	size, _ := decimal.NewFromString(o.Size)
+	filled, _ := decimal.NewFromString(o.FilledSize)
+	filledNotional, _ := decimal.NewFromString(o.FilledNotional)
+	lastPrice, _ := decimal.NewFromString(o.LastFillPrice)
+	lastCount, _ := decimal.NewFromString(o.LastFillCount)
+	fee, _ := decimal.NewFromString(o.DealFee)
+	eventTime, _ := strconv.ParseInt(o.MsT, 10, 64)
+	fillTime, _ := strconv.ParseInt(o.LastFillTime, 10, 64)
+	return types.OrderUpdateEntry{
+		OrderId:          o.OrderID,
+		ClientOrderId:    o.ClientOrderID,
+		Status:           OrderStatus(o.OrderState).Convert(),
+		Symbol:           symbol,
+		Side:             strings.ToUpper(o.Side),
+		Type:             OrderType(o.Type).Convert(),
+		Price:            price,
+		Quantity:         size,
+		FilledQuantity:   filled,
+		FilledQuote:      filledNotional,
+		LastFillPrice:    lastPrice,
+		LastFillQuantity: lastCount,
+		Fee:              fee,
+		IsMaker:          o.ExecType == ExecTypeMaker,
+		TradeId:          o.DetailID,
+		EventTime:        eventTime,
+		TransactionTime:  fillTime,
+	}
+}
+
 type BalanceUpdate struct {
 	EventType      string          `json:"event_type"`
 	BalanceDetails []BalanceDetail `json:"balance_details"`
@@ -104,73 +199,82 @@ type BalanceDetail struct {
 	Asset string `json:"ccy"`
 }
 
-func (stream *UserDataStream) BalanceStream(ctx context.Context, channel chan<- types.BalanceUpdateEntry) error {
-	err := stream.SendMessage(map[string]any{
-		"op": "subscribe",
-		"args": []string{
-			"spot/user/balance:BALANCE_UPDATE",
-		},
-	})
-	if err != nil {
-		return err
-	}
+// isTradeChange balance events caused by order placement, cancellation or execution
+func (b BalanceUpdate) isTradeChange() bool {
+	return b.EventType == "TRANSACTION_COMPLETED" || strings.HasPrefix(b.EventType, "ORDER")
+}
 
-	go func(ctx context.Context) {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				msg, err := stream.ReadMessage()
-				if err != nil {
-					continue
+// balances read BALANCE_UPDATE, bitmart pushes no delta so it is derived from the previous push.
+func (stream *UserDataStream) balances(ctx context.Context, handle func(update BalanceUpdate, detail BalanceDetail, free, locked, delta decimal.Decimal, timestamp int64)) error {
+	var last = make(map[string]decimal.Decimal)
+	return stream.subscribe(ctx, "spot/user/balance:BALANCE_UPDATE", func(msg []byte) {
+		var event StreamResp[BalanceUpdate]
+		if err := stream.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, update := range event.Data {
+			timestamp, _ := strconv.ParseInt(update.EventTime, 10, 64)
+			for _, detail := range update.BalanceDetails {
+				free, _ := decimal.NewFromString(detail.Free)
+				locked, _ := decimal.NewFromString(detail.Lock)
+				total := free.Add(locked)
+				var delta decimal.Decimal
+				if previous, ok := last[detail.Asset]; ok {
+					delta = total.Sub(previous)
 				}
-				var event StreamResp[BalanceUpdate]
-				_ = utils.Json.Unmarshal(msg, &event)
-				channel <- types.BalanceUpdateEntry{}
+				last[detail.Asset] = total
+				handle(update, detail, free, locked, delta, timestamp)
 			}
 		}
-	}(ctx)
-	return nil
+	})
 }
 
-func (stream *UserDataStream) OrderStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
-	err := stream.SendMessage(map[string]any{
-		"op": "subscribe",
-		"args": []string{
-			"spot/user/order:ALL_SYMBOLS",
-		},
+func (stream *UserDataStream) BalanceStream(ctx context.Context, channel chan<- types.BalanceUpdateEntry) error {
+	return stream.balances(ctx, func(update BalanceUpdate, detail BalanceDetail, free, locked, delta decimal.Decimal, timestamp int64) {
+		if update.isTradeChange() {
+			return
+		}
+		if !platforms.Deliver(ctx, channel, types.BalanceUpdateEntry{
+			Asset:     detail.Asset,
+			Free:      free,
+			Locked:    locked,
+			Delta:     delta,
+			Reason:    update.EventType,
+			Timestamp: timestamp,
+		}) {
+			return
+		}
 	})
-	if err != nil {
-		return err
-	}
+}
 
-	go func(ctx context.Context) {
-		for {
-			select {
-			case <-ctx.Done():
+func (stream *UserDataStream) OrderStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
+	return stream.subscribe(ctx, "spot/user/order:ALL_SYMBOLS", func(msg []byte) {
+		var event StreamResp[OrderUpdate]
+		if err := stream.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, e := range event.Data {
+			if !platforms.Deliver(ctx, channel, e.convert()) {
 				return
-			default:
-				msg, err := stream.ReadMessage()
-				if err != nil {
-					continue
-				}
-				var event StreamResp[OrderUpdate]
-				_ = utils.Json.Unmarshal(msg, &event)
-
-				for _, e := range event.Data {
-					channel <- types.OrderUpdateEntry{
-						OrderId:       e.OrderID,
-						ClientOrderId: e.ClientOrderID,
-						Status:        OrderStatus(e.OrderState).Convert(),
-					}
-				}
 			}
 		}
-	}(ctx)
-	return nil
+	})
 }
 
 func (stream *UserDataStream) AccountStream(ctx context.Context, channel chan<- types.AccountUpdateEntry) error {
-	return nil
+	return stream.balances(ctx, func(update BalanceUpdate, detail BalanceDetail, free, locked, delta decimal.Decimal, timestamp int64) {
+		if !update.isTradeChange() {
+			return
+		}
+		if !platforms.Deliver(ctx, channel, types.AccountUpdateEntry{
+			Asset:     detail.Asset,
+			Free:      free,
+			Locked:    locked,
+			Delta:     delta,
+			Reason:    update.EventType,
+			Timestamp: timestamp,
+		}) {
+			return
+		}
+	})
 }
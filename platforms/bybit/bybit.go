@@ -1,8 +1,11 @@
 package bybit
 
 import (
+	"fmt"
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
 	"net/http"
 	"strconv"
 )
@@ -10,6 +13,8 @@ import (
 type Connector struct {
 	*platforms.Credentials
 	Client *http.Client
+	platforms.Middlewares
+	platforms.Logging
 }
 
 func (c *Connector) Name() constants.Platform {
@@ -25,6 +30,46 @@ func NewConnector(cred *platforms.Credentials, client *http.Client) platforms.Sp
 	return &Connector{Credentials: cred, Client: client}
 }
 
+type FeeRates struct {
+	List []struct {
+		Symbol       string `json:"symbol"`
+		BaseCoin     string `json:"baseCoin"`
+		TakerFeeRate string `json:"takerFeeRate"`
+		MakerFeeRate string `json:"makerFeeRate"`
+	} `json:"list"`
+}
+
+func (FeeRates) String() string {
+	return ""
+}
+
+func (c *Connector) GetFeeRates(symbols []string) (map[string]types.FeeRateEntry, error) {
+	var result = make(map[string]types.FeeRateEntry, len(symbols))
+	for _, symbol := range symbols {
+		var resp RestResp[FeeRates, NullExt]
+		err := c.Call(http.MethodGet, FeeRateEndpoint, &platforms.ObjectBody{
+			"category": "spot",
+			"symbol":   c.SymbolPattern(symbol),
+		}, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Code != 0 {
+			return nil, fmt.Errorf("[Bybit] code: %d, msg: %s", resp.Code, resp.Msg)
+		}
+		for _, fee := range resp.Result.List {
+			maker, _ := decimal.NewFromString(fee.MakerFeeRate)
+			taker, _ := decimal.NewFromString(fee.TakerFeeRate)
+			result[fee.Symbol] = types.FeeRateEntry{
+				Symbol: fee.Symbol,
+				Maker:  maker,
+				Taker:  taker,
+			}
+		}
+	}
+	return result, nil
+}
+
 func timeConvert(interval string) string {
 	unit := interval[len(interval)-1]
 	value, _ := strconv.ParseInt(interval[:len(interval)-1], 10, 64)
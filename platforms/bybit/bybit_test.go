@@ -8,7 +8,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
 )
 
 func TestMarketStream(t *testing.T) {
@@ -55,3 +57,33 @@ func TestGetCandles(t *testing.T) {
 	}
 	fmt.Println(candles)
 }
+
+// QueryOrder reports the executed quantity as Filled, the remaining quantity was returned before.
+func TestQueryOrderConvert(t *testing.T) {
+	var info OrderInfo
+	_ = utils.Json.UnmarshalFromString(`{"orderId":"1","symbol":"BTCUSDT","qty":"2","cumExecQty":"0.5","orderStatus":"PartiallyFilled","createdTime":"1700000000000","updatedTime":"1700000001000"}`, &info)
+	order := info.convert()
+	if !order.Filled.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("want executed quantity 0.5, got %s", order.Filled)
+	}
+	// millisecond times overflowed the 16 bit parse before
+	if order.CreateTime != 1700000000000 || order.UpdateTime != 1700000001000 {
+		t.Errorf("unexpected times %d, %d", order.CreateTime, order.UpdateTime)
+	}
+}
+
+// the execution topic carries the fill detail the order topic lacks.
+func TestExecutionEntry(t *testing.T) {
+	var event DataStream[ExecutionEvent]
+	_ = utils.Json.UnmarshalFromString(`{"topic":"execution","creationTime":1700000000100,"data":[{"category":"spot","symbol":"BTCUSDT","orderId":"1","execId":"e1","execPrice":"100","execQty":"0.5","execFee":"0.0005","feeCurrency":"BTC","execType":"Trade","execTime":"1700000000000","isMaker":true}]}`, &event)
+	if len(event.Data) != 1 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	entry := event.Data[0].updateEntry()
+	if entry.OrderId != "1" || entry.TradeId != "e1" || !entry.IsMaker || entry.TransactionTime != 1700000000000 {
+		t.Errorf("unexpected execution %+v", entry)
+	}
+	if !entry.LastFillPrice.Equal(decimal.NewFromInt(100)) || !entry.LastFillQuantity.Equal(decimal.NewFromFloat(0.5)) || entry.FeeAsset != "BTC" {
+		t.Errorf("unexpected fill detail %+v", entry)
+	}
+}
@@ -21,7 +21,26 @@ func (c *Connector) Sign(params []byte) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
+// errorKinds retCode with a unified meaning
+var errorKinds = map[int]error{
+	110001: platforms.ErrOrderNotFound, // Order does not exist
+	170213: platforms.ErrOrderNotFound, // Order does not exist
+	170142: platforms.ErrOrderClosed,   // Order has already been filled or canceled
+}
+
+// apiError retCode and retMsg of a failed request
+func apiError(code int, msg string) error {
+	return &platforms.ExchangeError{Exchange: "Bybit", Code: strconv.Itoa(code), Message: msg, Err: errorKinds[code]}
+}
+
 func (c *Connector) Call(method string, route string, params platforms.Serializer, authType constants.AuthType, returnType any) error {
+	return c.Invoke(&platforms.Invocation{Platform: c.Name(), Method: method, Route: route, AuthType: authType,
+		Params: params, Result: returnType}, c.Logged(c.call))
+}
+
+// call the request of one invocation, the middlewares of the connector run around it
+func (c *Connector) call(invocation *platforms.Invocation) error {
+	method, route, params, authType, returnType := invocation.Method, invocation.Route, invocation.Params, invocation.AuthType, invocation.Result
 	// Add necessary parameters
 	var body io.Reader
 	bodyData, err := params.Serialize()
@@ -40,12 +59,14 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 
 	url := RestAPI + route
 	if method == http.MethodPost {
-		//signData.Write(bodyData)
-		_, err = signData.ReadFrom(bodyData)
+		payload, err := io.ReadAll(bodyData)
 		if err != nil {
 			return err
 		}
-		body = bodyData
+		signData.Write(payload)
+		// reading for the signature drained the serialized body
+		body = bytes.NewReader(payload)
+		invocation.RequestBody = payload
 	} else {
 		queryString, err := params.EncodeQuery()
 		if err != nil {
@@ -69,15 +90,18 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 		return err
 	}
 	req.Header = header
+	invocation.Request = req
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return err
+		return platforms.NetworkError(err)
 	}
 
+	defer resp.Body.Close()
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
+	invocation.StatusCode, invocation.Header, invocation.Response = resp.StatusCode, resp.Header, respBody
 
 	if resp.StatusCode != 200 {
 		return fmt.Errorf("[Bitmart] Response %s", resp.Status)
@@ -30,6 +30,10 @@ const (
 	OrderCancelAllEndpoint   = "/v5/order/cancel-all"
 	OrderBatchCancelEndpoint = "/v5/order/cancel-batch"
 	WalletBalanceEndpoint    = "/v5/account/wallet-balance"
+	ExecutionListEndpoint    = "/v5/execution/list"
+	OrderHistoryEndpoint     = "/v5/order/history"
+	FeeRateEndpoint          = "/v5/account/fee-rate"
+	DisconnectCancelEndpoint = "/v5/order/disconnected-cancel-all"
 )
 const (
 	SpotMainnetChannel            = "/v5/public/spot"
@@ -37,6 +41,7 @@ const (
 	USDCMainnetChannel            = "v5/public/option"
 	InverseContractMainnetChannel = "/v5/public/inverse"
 	PrivateChannel                = "/v5/private"
+	TradeChannel                  = "/v5/trade"
 )
 
 type RestResp[T, Ext fmt.Stringer] struct {
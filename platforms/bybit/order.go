@@ -1,14 +1,20 @@
 package bybit
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Order struct {
@@ -44,6 +50,9 @@ func (c *Connector) RawPlaceOrder(params platforms.Serializer) (Order, error) {
 	if err != nil {
 		return Order{}, err
 	}
+	if resp.Code != 0 {
+		return Order{}, apiError(resp.Code, resp.Msg)
+	}
 	return resp.Result, nil
 }
 
@@ -60,6 +69,9 @@ func (c *Connector) PlaceOrder(params types.OrderEntry) (string, error) {
 	if !params.Price.IsZero() {
 		p.Set("price", params.Price.StringFixed(12))
 	}
+	if params.TradeNo != "" {
+		p.Set("orderLinkId", params.TradeNo)
+	}
 	order, err := c.RawPlaceOrder(p)
 	if err != nil {
 		return "", err
@@ -91,7 +103,7 @@ type OrdersExt struct {
 func (ext OrdersExt) String() string {
 	return ""
 }
-func (c *Connector) RawBatchOrder(orders []map[string]any) (OrderList, error) {
+func (c *Connector) RawBatchOrder(orders []map[string]any) (OrderList, OrdersExt, error) {
 	var resp RestResp[OrderList, OrdersExt]
 
 	err := c.Call(http.MethodPost, BatchPlaceOrderEndpoint, &platforms.ObjectBody{
@@ -99,14 +111,27 @@ func (c *Connector) RawBatchOrder(orders []map[string]any) (OrderList, error) {
 		"request":  orders,
 	}, constants.Signed, &resp)
 	if err != nil {
-		return OrderList{}, err
+		return OrderList{}, OrdersExt{}, err
 	}
-	return resp.Result, nil
+	if resp.Code != 0 {
+		return OrderList{}, OrdersExt{}, fmt.Errorf("[Bybit] code: %d, msg: %s", resp.Code, resp.Msg)
+	}
+	return resp.Result, resp.Ext, nil
 }
-func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]string, error) {
-	var result []string
+
+// batchOrderLimit maximum spot orders per batch request
+const batchOrderLimit = 10
+
+// BatchOrder https://bybit-exchange.github.io/docs/v5/order/batch-place
+func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	var results = make([]types.BatchOrderResult, len(orders))
 	var params = make([]map[string]any, len(orders))
 	for i, order := range orders {
+		clientId := order.TradeNo
+		if clientId == "" {
+			clientId = uuid.New().String()
+		}
+		results[i].TradeNo = clientId
 		params[i] = map[string]any{
 			"category":    "spot",
 			"symbol":      order.Symbol,
@@ -115,19 +140,47 @@ func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]string, error) {
 			"qty":         order.Quantity.StringFixed(2),
 			"price":       order.Price.StringFixed(12),
 			"timeInForce": "GTC",
-			"orderLinkId": uuid.New().String(),
+			"orderLinkId": clientId,
 			"mmp":         false,
 			"reduceOnly":  false,
 		}
 	}
-	resp, err := c.RawBatchOrder(params)
-	if err != nil {
-		return nil, err
-	}
-	for _, ord := range resp.List {
-		result = append(result, ord.OrderID)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	for _, chunk := range platforms.ChunkOrders(orders, batchOrderLimit, false) {
+		wg.Add(1)
+		go func(chunk []int) {
+			defer wg.Done()
+			var batch = make([]map[string]any, len(chunk))
+			for j, index := range chunk {
+				batch[j] = params[index]
+			}
+			list, ext, err := c.RawBatchOrder(batch)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				for _, index := range chunk {
+					results[index].Message = err.Error()
+				}
+				return
+			}
+			// result and retExtInfo lists are both in request order
+			for j, index := range chunk {
+				if j < len(ext.List) && ext.List[j].Code != 0 {
+					results[index].Code = strconv.FormatInt(ext.List[j].Code, 10)
+					results[index].Message = ext.List[j].Msg
+					continue
+				}
+				if j < len(list.List) {
+					results[index].OrderId = list.List[j].OrderID
+				}
+			}
+		}(chunk)
 	}
-	return result, nil
+	wg.Wait()
+	return results, errors.Join(errs...)
 }
 
 type OrderInfo struct {
@@ -149,6 +202,7 @@ type OrderInfo struct {
 	CumExecQty         string `json:"cumExecQty"`
 	CumExecValue       string `json:"cumExecValue"`
 	CumExecFee         string `json:"cumExecFee"`
+	FeeCurrency        string `json:"feeCurrency"`
 	TimeInForce        string `json:"timeInForce"`
 	OrderType          string `json:"orderType"`
 	StopOrderType      string `json:"stopOrderType"`
@@ -205,30 +259,72 @@ func (c *Connector) GetOrderStatus(symbol string, orderId string) (constants.Ord
 	return OrderStatus(order.OrderStatus).Convert(), nil
 }
 func (c *Connector) QueryOrder(symbol string, orderId string) (types.QueryOrder, error) {
-	var result types.QueryOrder
 	order, err := c.RawOrder(&platforms.ObjectBody{
 		"symbol":  symbol,
 		"orderId": orderId,
 	})
 	if err != nil {
-		return result, err
+		return types.QueryOrder{}, err
 	}
+	return order.convert(), nil
+}
 
+func (order OrderInfo) convert() types.QueryOrder {
+	var result types.QueryOrder
 	result.OrderId = order.OrderId
 	result.Symbol = order.Symbol
 	result.Side = strings.ToUpper(order.Side)
+	result.Type = OrderType(order.OrderType).Convert()
 	qty, _ := decimal.NewFromString(order.Qty)
 	result.Quantity = qty
 	price, _ := decimal.NewFromString(order.Price)
 	result.Price = price
 	executed, _ := decimal.NewFromString(order.CumExecQty)
-	result.Filled = qty.Sub(executed)
+	result.Filled = executed
 	result.TradeNo = order.OrderLinkId
 	result.Status = OrderStatus(order.OrderStatus).Convert()
 	created, _ := strconv.ParseInt(order.CreatedTime, 10, 64)
 	result.CreateTime = created
-	updated, _ := strconv.ParseInt(order.UpdatedTime, 10, 16)
+	updated, _ := strconv.ParseInt(order.UpdatedTime, 10, 64)
 	result.UpdateTime = updated
+	return result
+}
+
+func (c *Connector) OrderHistory(ctx context.Context, symbol string, since, until int64, statuses []constants.OrderStatus) ([]types.QueryOrder, error) {
+	var result []types.QueryOrder
+	for start := since; start <= until; start += executionWindow + 1 {
+		var params = &platforms.ObjectBody{
+			"category":  "spot",
+			"symbol":    symbol,
+			"startTime": start,
+			"endTime":   min(start+executionWindow, until),
+			"limit":     50,
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			var resp RestResp[OrderInfos, NullExt]
+			err := c.Call(http.MethodGet, OrderHistoryEndpoint, params, constants.Signed, &resp)
+			if err != nil {
+				return nil, err
+			}
+			if resp.Code != 0 {
+				return nil, fmt.Errorf("[Bybit] code: %d, msg: %s", resp.Code, resp.Msg)
+			}
+			for _, info := range resp.Result.List {
+				order := info.convert()
+				if len(statuses) > 0 && !slices.Contains(statuses, order.Status) {
+					continue
+				}
+				result = append(result, order)
+			}
+			if resp.Result.NextPageCursor == "" || len(resp.Result.List) == 0 {
+				break
+			}
+			params.Set("cursor", resp.Result.NextPageCursor)
+		}
+	}
 	return result, nil
 }
 func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
@@ -241,42 +337,94 @@ func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if resp.Code != 0 {
+		return false, apiError(resp.Code, resp.Msg)
+	}
 	return true, nil
 }
 
-type Orders []Order
-
-func (Orders) String() string {
-	return ""
-}
-
 func (c *Connector) CancelAll(symbol string) error {
-	var resp RestResp[Orders, NullExt]
-	return c.Call(http.MethodPost, OrderCancelAllEndpoint, &platforms.ObjectBody{
+	var resp RestResp[OrderList, NullExt]
+	err := c.Call(http.MethodPost, OrderCancelAllEndpoint, &platforms.ObjectBody{
 		"symbol":   symbol,
 		"category": "spot",
 	}, constants.Signed, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		return apiError(resp.Code, resp.Msg)
+	}
+	return nil
 }
 
-func (c *Connector) CancelByIds(symbol string, orderIds []string) (map[string]bool, error) {
-	var resp RestResp[OrderList, OrdersExt]
-	var req = make([]map[string]string, len(orderIds))
-	var result = make(map[string]bool)
-	for i, id := range orderIds {
-		req[i] = map[string]string{
-			"symbol":  symbol,
-			"orderId": id,
-		}
+// SetDisconnectWindow set the disconnection protection window of spot, bybit cancels every order
+// when no private connection is alive for timeout, which must be 10 to 300 seconds.
+// The protection fires on losing the websocket rather than on a countdown refreshed over rest,
+// so bybit is no CountdownCanceler and a DeadManSwitch emulates the switch instead.
+// The protection itself is switched on in the account settings.
+// https://bybit-exchange.github.io/docs/v5/order/dcp
+func (c *Connector) SetDisconnectWindow(timeout time.Duration) error {
+	var resp RestResp[NullExt, NullExt]
+	err := c.Call(http.MethodPost, DisconnectCancelEndpoint, &platforms.ObjectBody{
+		"product":    "SPOT",
+		"timeWindow": int64(timeout / time.Second),
+	}, constants.Signed, &resp)
+	if err != nil {
+		return err
 	}
-	err := c.Call(http.MethodPost, OrderBatchCancelEndpoint, &platforms.ObjectBody{
+	if resp.Code != 0 {
+		return apiError(resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// CancelAllSymbols cancel-all without symbol cancels every spot order.
+func (c *Connector) CancelAllSymbols() error {
+	var resp RestResp[OrderList, NullExt]
+	err := c.Call(http.MethodPost, OrderCancelAllEndpoint, &platforms.ObjectBody{
 		"category": "spot",
-		"request":  req,
 	}, constants.Signed, &resp)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	for i, info := range resp.Ext.List {
-		result[resp.Result.List[i].OrderID] = info.Code == 0 || info.Msg == "OK"
+	if resp.Code != 0 {
+		return apiError(resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// CancelByIds https://bybit-exchange.github.io/docs/v5/order/batch-cancel
+func (c *Connector) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	var result = make([]types.CancelResult, len(orderIds))
+	for start := 0; start < len(orderIds); start += batchOrderLimit {
+		end := min(start+batchOrderLimit, len(orderIds))
+		var req = make([]map[string]string, 0, end-start)
+		for _, id := range orderIds[start:end] {
+			req = append(req, map[string]string{
+				"symbol":  symbol,
+				"orderId": id,
+			})
+		}
+		var resp RestResp[OrderList, OrdersExt]
+		err := c.Call(http.MethodPost, OrderBatchCancelEndpoint, &platforms.ObjectBody{
+			"category": "spot",
+			"request":  req,
+		}, constants.Signed, &resp)
+		if err == nil && resp.Code != 0 {
+			err = apiError(resp.Code, resp.Msg)
+		}
+		for j, id := range orderIds[start:end] {
+			index := start + j
+			result[index].OrderId = id
+			switch {
+			case err != nil:
+				result[index].Err = err
+			// retExtInfo list is in request order
+			case j < len(resp.Ext.List) && resp.Ext.List[j].Code != 0:
+				result[index].Err = apiError(int(resp.Ext.List[j].Code), resp.Ext.List[j].Msg)
+			}
+		}
 	}
 	return result, nil
 }
@@ -376,3 +524,88 @@ func (c *Connector) Balance(symbols []string) (map[string]types.BalanceEntry, er
 
 	return result, nil
 }
+
+type Execution struct {
+	Symbol      string `json:"symbol"`
+	OrderId     string `json:"orderId"`
+	OrderLinkId string `json:"orderLinkId"`
+	Side        string `json:"side"`
+	OrderPrice  string `json:"orderPrice"`
+	OrderQty    string `json:"orderQty"`
+	LeavesQty   string `json:"leavesQty"`
+	OrderType   string `json:"orderType"`
+	ExecFee     string `json:"execFee"`
+	FeeCurrency string `json:"feeCurrency"`
+	ExecId      string `json:"execId"`
+	ExecPrice   string `json:"execPrice"`
+	ExecQty     string `json:"execQty"`
+	ExecType    string `json:"execType"`
+	ExecValue   string `json:"execValue"`
+	ExecTime    string `json:"execTime"`
+	FeeRate     string `json:"feeRate"`
+	IsMaker     bool   `json:"isMaker"`
+	Seq         int64  `json:"seq"`
+}
+
+type Executions struct {
+	Category       string      `json:"category"`
+	List           []Execution `json:"list"`
+	NextPageCursor string      `json:"nextPageCursor"`
+}
+
+func (Executions) String() string {
+	return ""
+}
+
+// executionWindow the widest startTime/endTime span accepted by execution/list and order/history.
+const executionWindow = int64(7*24*time.Hour/time.Millisecond) - 1
+
+func (c *Connector) GetMyTrades(ctx context.Context, symbol string, since, until int64) ([]types.FillEntry, error) {
+	var result []types.FillEntry
+	for start := since; start <= until; start += executionWindow + 1 {
+		var params = &platforms.ObjectBody{
+			"category":  "spot",
+			"symbol":    symbol,
+			"startTime": start,
+			"endTime":   min(start+executionWindow, until),
+			"limit":     100,
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			var resp RestResp[Executions, NullExt]
+			err := c.Call(http.MethodGet, ExecutionListEndpoint, params, constants.Signed, &resp)
+			if err != nil {
+				return nil, err
+			}
+			if resp.Code != 0 {
+				return nil, fmt.Errorf("[Bybit] code: %d, msg: %s", resp.Code, resp.Msg)
+			}
+			for _, exec := range resp.Result.List {
+				price, _ := decimal.NewFromString(exec.ExecPrice)
+				qty, _ := decimal.NewFromString(exec.ExecQty)
+				fee, _ := decimal.NewFromString(exec.ExecFee)
+				ts, _ := strconv.ParseInt(exec.ExecTime, 10, 64)
+				result = append(result, types.FillEntry{
+					Symbol:      exec.Symbol,
+					TradeId:     exec.ExecId,
+					OrderId:     exec.OrderId,
+					TradeNo:     exec.OrderLinkId,
+					Side:        strings.ToUpper(exec.Side),
+					Price:       price,
+					Quantity:    qty,
+					Fee:         fee,
+					FeeCurrency: exec.FeeCurrency,
+					IsMaker:     exec.IsMaker,
+					Timestamp:   ts,
+				})
+			}
+			if resp.Result.NextPageCursor == "" || len(resp.Result.List) == 0 {
+				break
+			}
+			params.Set("cursor", resp.Result.NextPageCursor)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package bybit
+
+import (
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// PlaceOrderList OTO and OTOCO attach take profit and stop loss to the spot entry order,
+// bybit has no spot OCO. The exits are created on trigger, so their ids are left empty.
+// https://bybit-exchange.github.io/docs/v5/order/create-order
+func (c *Connector) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	if err := platforms.ValidateOrderList(list); err != nil {
+		return types.OrderListResult{}, err
+	}
+	if list.Type == types.OCO {
+		return types.OrderListResult{}, platforms.ErrNotSupported
+	}
+	var p = &platforms.ObjectBody{
+		"category":  "spot",
+		"symbol":    c.SymbolPattern(list.Symbol),
+		"side":      FirstSide(list.Entry.Side),
+		"orderType": c.MatchOrderType(list.Entry.Type).String(),
+		"qty":       list.Entry.Quantity.String(),
+	}
+	if !list.Entry.Price.IsZero() {
+		p.Set("price", list.Entry.Price.String())
+	}
+	if list.Entry.TradeNo != "" {
+		p.Set("orderLinkId", list.Entry.TradeNo)
+	}
+	if tp := list.TakeProfit; tp != nil {
+		// take profit triggers at StopPrice when set, otherwise at its limit price;
+		// without a price it executes at market
+		trigger := tp.StopPrice
+		if trigger.IsZero() {
+			trigger = tp.Price
+		}
+		p.Set("takeProfit", trigger.String())
+		if tp.Price.IsZero() {
+			p.Set("tpOrderType", OrderTypeMarket.String())
+		} else {
+			p.Set("tpOrderType", OrderTypeLimit.String())
+			p.Set("tpLimitPrice", tp.Price.String())
+		}
+	}
+	if sl := list.StopLoss; sl != nil {
+		p.Set("stopLoss", sl.StopPrice.String())
+		if sl.Price.IsZero() {
+			p.Set("slOrderType", OrderTypeMarket.String())
+		} else {
+			p.Set("slOrderType", OrderTypeLimit.String())
+			p.Set("slLimitPrice", sl.Price.String())
+		}
+	}
+	order, err := c.RawPlaceOrder(p)
+	if err != nil {
+		return types.OrderListResult{}, err
+	}
+	return types.OrderListResult{ListId: order.OrderId, EntryOrderId: order.OrderId}, nil
+}
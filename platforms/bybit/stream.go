@@ -6,7 +6,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
-	"github.com/xavierzho/go-cexs/utils"
 )
 
 type MarketStream struct {
@@ -59,13 +58,14 @@ func (m *MarketStream) DepthStream(ctx context.Context, symbol string, channel c
 					continue
 				}
 				var event PublicStream[DepthEvent]
-				err = utils.Json.Unmarshal(msg, &event)
+				err = m.Decode(msg, &event)
 				if err != nil {
 					continue
 				}
 				channel <- types.DepthEntry{
-					Bids: event.Data.Bids,
-					Asks: event.Data.Asks,
+					Bids:     event.Data.Bids,
+					Asks:     event.Data.Asks,
+					Snapshot: event.Type == "snapshot",
 				}
 			}
 		}
@@ -118,7 +118,7 @@ func (m *MarketStream) CandleStream(ctx context.Context, symbol, interval string
 					continue
 				}
 				var event PublicStream[CandleEvent]
-				_ = utils.Json.Unmarshal(msg, &event)
+				_ = m.Decode(msg, &event)
 				for _, e := range event.Data {
 					var list = []any{e.Start, e.Open, e.High, e.Low, e.Close, e.Volume, e.Turnover}
 					var line = make([]float64, len(list))
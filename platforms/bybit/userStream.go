@@ -7,15 +7,22 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
 	"github.com/xavierzho/go-cexs/utils"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 type UserDataStream struct {
 	*platforms.Credentials
 	*platforms.StreamBase
+	dispatcher    *platforms.Dispatcher
+	mux           sync.Mutex
+	subscriptions []string // topics, replayed after re-authentication
 }
 type DataStream[T fmt.Stringer] struct {
 	ID           string `json:"id"`
@@ -25,31 +32,114 @@ type DataStream[T fmt.Stringer] struct {
 }
 
 func (stream *UserDataStream) Sign(expires int64) string {
-	mac := hmac.New(sha256.New, []byte(stream.APISecret))
+	return wsSign(stream.Credentials, expires)
+}
+
+func wsSign(cred *platforms.Credentials, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(cred.APISecret))
 	mac.Write([]byte(fmt.Sprintf("GET/realtime%d", expires)))
 	return hex.EncodeToString(mac.Sum(nil))
 }
+
 func (stream *UserDataStream) Login() error {
 	err := stream.Connect(fmt.Sprintf("%s%s", StreamAPI, PrivateChannel))
 	if err != nil {
 		return err
 	}
+	return wsAuth(stream.StreamBase, stream.Credentials)
+}
+
+// Reconnect dial and authenticate again, then replay every subscription. The acks are left to the reader.
+func (stream *UserDataStream) Reconnect() error {
+	stream.mux.Lock()
+	defer stream.mux.Unlock()
+	if err := stream.Login(); err != nil {
+		return err
+	}
+	for _, topic := range stream.subscriptions {
+		if err := stream.WriteJSON(subscribePayload(stream.dispatcher.NextId(), topic)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func subscribePayload(id, topic string) map[string]any {
+	return map[string]any{
+		"req_id": id,
+		"op":     "subscribe",
+		"args":   []string{topic},
+	}
+}
+
+// route topic of a push, replies carry an op instead.
+func route(msg []byte) string {
+	if utils.Json.Get(msg, "op").ToString() != "" {
+		return ""
+	}
+	return utils.Json.Get(msg, "topic").ToString()
+}
+
+// replyId bybit echoes the req_id of a request in its reply.
+func replyId(msg []byte) (string, bool) {
+	id := utils.Json.Get(msg, "req_id").ToString()
+	return id, id != "" && utils.Json.Get(msg, "op").ToString() != ""
+}
+
+// subscribe topic once for all the streams reading it, handle receives its pushes until ctx is done.
+func (stream *UserDataStream) subscribe(ctx context.Context, topic string, handle func(msg []byte)) error {
+	return stream.dispatcher.Subscribe(ctx, topic, func() error {
+		id := stream.dispatcher.NextId()
+		reply, err := stream.dispatcher.Request(id, subscribePayload(id, topic))
+		if err != nil {
+			return err
+		}
+		var ack AuthReply
+		_ = utils.Json.Unmarshal(reply, &ack)
+		if ack.Success != nil && !*ack.Success {
+			return fmt.Errorf("[Bybit stream] subscribe %s failed: %s", topic, ack.RetMsg)
+		}
+		stream.mux.Lock()
+		stream.subscriptions = append(stream.subscriptions, topic)
+		stream.mux.Unlock()
+		return nil
+	}, handle)
+}
+
+// AuthReply the private channel answers with success, the trade channel with retCode.
+type AuthReply struct {
+	Success *bool  `json:"success"`
+	RetMsg  string `json:"ret_msg"`
+	RetCode int    `json:"retCode"`
+	Msg     string `json:"retMsg"`
+}
+
+// wsAuth authenticate a private or trade connection.
+// https://bybit-exchange.github.io/docs/v5/ws/connect#authentication
+func wsAuth(stream *platforms.StreamBase, cred *platforms.Credentials) error {
 	expires := time.Now().Add(time.Second * 10)
 	exp := expires.UnixNano() / 1e6
-	err = stream.SendMessage(map[string]any{
+	data, err := stream.Request(map[string]any{
 		"req_id": uuid.New().String(), // optional
 		"op":     "auth",
 		"args": []any{
-			stream.APIKey,
+			cred.APIKey,
 			exp, // expires; is greater than your current timestamp
-			stream.Sign(exp),
+			wsSign(cred, exp),
 		},
 	})
 	if err != nil {
 		return err
 	}
-	_, err = stream.ReadMessage()
-	return err
+	var reply AuthReply
+	_ = utils.Json.Unmarshal(data, &reply)
+	if reply.Success != nil && !*reply.Success {
+		return fmt.Errorf("[Bybit stream] auth failed: %s", reply.RetMsg)
+	}
+	if reply.RetCode != 0 {
+		return apiError(reply.RetCode, reply.Msg)
+	}
+	return nil
 }
 
 type OrderEvent []OrderInfo
@@ -57,39 +147,88 @@ type OrderEvent []OrderInfo
 func (OrderEvent) String() string {
 	return ""
 }
+
+// updateEntry the order topic only reports cumulative execution, the fill fields and the fee
+// of the fill come with the execution topic, see Execution.updateEntry.
+func (order OrderInfo) updateEntry() types.OrderUpdateEntry {
+	price, _ := decimal.NewFromString(order.Price)
+	qty, _ := decimal.NewFromString(order.Qty)
+	executed, _ := decimal.NewFromString(order.CumExecQty)
+	executedValue, _ := decimal.NewFromString(order.CumExecValue)
+	updated, _ := strconv.ParseInt(order.UpdatedTime, 10, 64)
+	var entry = types.OrderUpdateEntry{
+		OrderId:         order.OrderId,
+		ClientOrderId:   order.OrderLinkId,
+		Status:          OrderStatus(order.OrderStatus).Convert(),
+		Symbol:          order.Symbol,
+		Side:            strings.ToUpper(order.Side),
+		Type:            OrderType(order.OrderType).Convert(),
+		Price:           price,
+		Quantity:        qty,
+		FilledQuantity:  executed,
+		FilledQuote:     executedValue,
+		FeeAsset:        order.FeeCurrency,
+		TransactionTime: updated,
+	}
+	if order.RejectReason != "EC_NoError" {
+		entry.RejectReason = order.RejectReason
+	}
+	return entry
+}
+
+type ExecutionEvent []Execution
+
+func (ExecutionEvent) String() string {
+	return ""
+}
+
+// updateEntry the fill detail of an execution, merged into the update of its order.
+func (exec Execution) updateEntry() types.OrderUpdateEntry {
+	price, _ := decimal.NewFromString(exec.ExecPrice)
+	qty, _ := decimal.NewFromString(exec.ExecQty)
+	fee, _ := decimal.NewFromString(exec.ExecFee)
+	ts, _ := strconv.ParseInt(exec.ExecTime, 10, 64)
+	return types.OrderUpdateEntry{
+		OrderId:          exec.OrderId,
+		LastFillPrice:    price,
+		LastFillQuantity: qty,
+		Fee:              fee,
+		FeeAsset:         exec.FeeCurrency,
+		IsMaker:          exec.IsMaker,
+		TradeId:          exec.ExecId,
+		TransactionTime:  ts,
+	}
+}
+
+// OrderStream joins the order topic, carrying status and cumulative execution,
+// with the execution topic, carrying price, quantity and fee of every fill.
 func (stream *UserDataStream) OrderStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
-	err := stream.SendMessage(map[string]any{
-		"op":   "subscribe",
-		"args": []string{"order"},
+	merger := platforms.NewFillMerger(ctx, channel)
+	err := stream.subscribe(ctx, "order", func(msg []byte) {
+		var event DataStream[OrderEvent]
+		if err := stream.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, e := range event.Data {
+			entry := e.updateEntry()
+			entry.EventTime = event.CreationTime
+			merger.OnOrder(entry)
+		}
 	})
 	if err != nil {
 		return err
 	}
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				_ = stream.Close()
-				return
-			default:
-				msg, err := stream.ReadMessage()
-				if err != nil {
-					continue
-				}
-				var event DataStream[OrderEvent]
-				_ = utils.Json.Unmarshal(msg, &event)
-				for _, e := range event.Data {
-					channel <- types.OrderUpdateEntry{
-						OrderId:       e.OrderId,
-						ClientOrderId: e.OrderLinkId,
-						Status:        OrderStatus(e.OrderStatus).Convert(),
-					}
-				}
-
+	return stream.subscribe(ctx, "execution", func(msg []byte) {
+		var event DataStream[ExecutionEvent]
+		if err := stream.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, exec := range event.Data {
+			if exec.ExecType == "Trade" {
+				merger.OnFill(exec.updateEntry())
 			}
 		}
-	}()
-	return nil
+	})
 }
 
 type BalanceEvent []WalletAccountInfo
@@ -98,39 +237,68 @@ func (BalanceEvent) String() string {
 	return ""
 }
 
-func (stream *UserDataStream) BalanceStream(ctx context.Context, channel chan<- types.BalanceUpdateEntry) error {
-	err := stream.SendMessage(map[string]any{})
-	if err != nil {
-		return err
-	}
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				_ = stream.Close()
-				return
-			default:
-				msg, err := stream.ReadMessage()
-				if err != nil {
-					continue
+// walletStream read the wallet topic, bybit pushes the same wallet snapshot for every
+// kind of change and does not report a reason, so delta is derived from the previous push.
+func (stream *UserDataStream) walletStream(ctx context.Context, emit func(asset string, free, locked, delta decimal.Decimal, timestamp int64)) error {
+	var last = make(map[string]decimal.Decimal)
+	return stream.subscribe(ctx, "wallet", func(msg []byte) {
+		var event DataStream[BalanceEvent]
+		if err := stream.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, account := range event.Data {
+			for _, coin := range account.Coins {
+				balance, _ := decimal.NewFromString(coin.WalletBalance)
+				locked, _ := decimal.NewFromString(coin.Locked)
+				var delta decimal.Decimal
+				if previous, ok := last[coin.Coin]; ok {
+					delta = balance.Sub(previous)
 				}
-				var event DataStream[BalanceEvent]
-				_ = utils.Json.Unmarshal(msg, &event)
-				channel <- types.BalanceUpdateEntry{}
+				last[coin.Coin] = balance
+				emit(coin.Coin, balance.Sub(locked), locked, delta, event.CreationTime)
 			}
 		}
-	}()
+	})
+}
 
-	return nil
+// BalanceStream the wallet snapshots, the same as AccountStream: bybit does not tell
+// transfers from trades, so the two streams cannot be split by cause.
+func (stream *UserDataStream) BalanceStream(ctx context.Context, channel chan<- types.BalanceUpdateEntry) error {
+	return stream.walletStream(ctx, func(asset string, free, locked, delta decimal.Decimal, timestamp int64) {
+		if !platforms.Deliver(ctx, channel, types.BalanceUpdateEntry{
+			Asset:     asset,
+			Free:      free,
+			Locked:    locked,
+			Delta:     delta,
+			Timestamp: timestamp,
+		}) {
+			return
+		}
+	})
 }
 
+// AccountStream the wallet snapshots, the same as BalanceStream.
 func (stream *UserDataStream) AccountStream(ctx context.Context, channel chan<- types.AccountUpdateEntry) error {
-	return fmt.Errorf("not support this method")
+	return stream.walletStream(ctx, func(asset string, free, locked, delta decimal.Decimal, timestamp int64) {
+		if !platforms.Deliver(ctx, channel, types.AccountUpdateEntry{
+			Asset:     asset,
+			Free:      free,
+			Locked:    locked,
+			Delta:     delta,
+			Timestamp: timestamp,
+		}) {
+			return
+		}
+	})
 }
 
 func NewUserStream(cred *platforms.Credentials) platforms.UserDataStreamer {
-	return &UserDataStream{
+	stream := &UserDataStream{
 		StreamBase:  platforms.NewStream(),
 		Credentials: cred,
 	}
+	stream.dispatcher = platforms.NewDispatcher(stream.StreamBase, route)
+	stream.dispatcher.ReplyId = replyId
+	stream.dispatcher.Recover = stream.Reconnect
+	return stream
 }
@@ -0,0 +1,98 @@
+package bybit
+
+import (
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WsOrderProtocol order entry over the trade channel.
+// https://bybit-exchange.github.io/docs/v5/websocket/trade/guideline
+type WsOrderProtocol struct {
+	*Connector
+}
+
+func NewWsTrader(cred *platforms.Credentials, client *http.Client) *platforms.WsTrader {
+	connector := &Connector{Credentials: cred, Client: client}
+	return platforms.NewWsTrader(connector, &WsOrderProtocol{connector})
+}
+
+func (p *WsOrderProtocol) URL() string {
+	return StreamAPI + TradeChannel
+}
+
+func (p *WsOrderProtocol) Login(stream *platforms.StreamBase) error {
+	return wsAuth(stream, p.Credentials)
+}
+
+// Ping bybit expects a ping op every 20 seconds
+func (p *WsOrderProtocol) Ping() string {
+	return `{"op":"ping"}`
+}
+
+func (p *WsOrderProtocol) request(id, op string, args map[string]any) map[string]any {
+	return map[string]any{
+		"reqId": id,
+		"header": map[string]any{
+			timestampKey:  strconv.FormatInt(time.Now().UnixMilli(), 10),
+			recvWindowKey: "5000",
+		},
+		"op":   op,
+		"args": []map[string]any{args},
+	}
+}
+
+func (p *WsOrderProtocol) PlaceOrder(id string, order types.OrderEntry) map[string]any {
+	args := map[string]any{
+		"category":  "spot",
+		"symbol":    p.SymbolPattern(order.Symbol),
+		"side":      FirstSide(order.Side),
+		"orderType": p.MatchOrderType(order.Type).String(),
+		"qty":       order.Quantity.StringFixed(2),
+	}
+	if !order.Price.IsZero() {
+		args["price"] = order.Price.StringFixed(12)
+	}
+	if order.TradeNo != "" {
+		args["orderLinkId"] = order.TradeNo
+	}
+	return p.request(id, "order.create", args)
+}
+
+func (p *WsOrderProtocol) Cancel(id, symbol, orderId string) map[string]any {
+	return p.request(id, "order.cancel", map[string]any{
+		"category": "spot",
+		"symbol":   p.SymbolPattern(symbol),
+		"orderId":  orderId,
+	})
+}
+
+type WsReply struct {
+	ReqId string `json:"reqId"`
+	Code  int    `json:"retCode"`
+	Msg   string `json:"retMsg"`
+	Op    string `json:"op"`
+	Data  Order  `json:"data"`
+}
+
+func (p *WsOrderProtocol) ReplyId(msg []byte) (string, bool) {
+	var reply WsReply
+	if err := utils.Json.Unmarshal(msg, &reply); err != nil || reply.ReqId == "" {
+		return "", false
+	}
+	return reply.ReqId, true
+}
+
+func (p *WsOrderProtocol) OrderId(msg []byte) (string, error) {
+	var reply WsReply
+	if err := utils.Json.Unmarshal(msg, &reply); err != nil {
+		return "", err
+	}
+	if reply.Code != 0 {
+		return "", apiError(reply.Code, reply.Msg)
+	}
+	return reply.Data.OrderId, nil
+}
@@ -0,0 +1,284 @@
+// Package conditional client-side stop, take profit and trailing stop orders for any SpotConnector.
+// Triggers are evaluated against last prices and place a plain order once met.
+package conditional
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Kind condition of a trigger, the direction follows the side of the order to place.
+type Kind string
+
+const (
+	// Stop a sell fires at or below TriggerPrice, a buy at or above.
+	Stop Kind = "STOP"
+	// TakeProfit a sell fires at or above TriggerPrice, a buy at or below.
+	TakeProfit Kind = "TAKE_PROFIT"
+	// Trailing follows the best price since activation, a sell fires once the price falls
+	// TrailingDelta below the peak, a buy once it rises TrailingDelta above the trough.
+	// A non-zero TriggerPrice delays activation until it is reached in the favourable direction.
+	Trailing Kind = "TRAILING"
+)
+
+// Trigger pending conditional order.
+type Trigger struct {
+	Id   string `json:"id"`
+	Kind Kind   `json:"kind"`
+	// Order placed when the condition is met
+	Order        types.OrderEntry `json:"order"`
+	TriggerPrice decimal.Decimal  `json:"trigger_price"`
+	// TrailingDelta distance from the extreme as a ratio, e.g. 0.01 for 1%
+	TrailingDelta decimal.Decimal `json:"trailing_delta,omitempty"`
+	// Activated, Extreme trailing state, persisted so a restart keeps following the same peak
+	Activated bool            `json:"activated,omitempty"`
+	Extreme   decimal.Decimal `json:"extreme,omitempty"`
+	// CreateTime in milliseconds
+	CreateTime int64 `json:"create_time"`
+}
+
+func (t *Trigger) sell() bool {
+	return strings.ToUpper(t.Order.Side) == "SELL"
+}
+
+// observe feed a price, report whether the trigger fires and whether its state changed.
+func (t *Trigger) observe(price decimal.Decimal) (fire, changed bool) {
+	switch t.Kind {
+	case Stop:
+		if t.sell() {
+			return price.LessThanOrEqual(t.TriggerPrice), false
+		}
+		return price.GreaterThanOrEqual(t.TriggerPrice), false
+	case TakeProfit:
+		if t.sell() {
+			return price.GreaterThanOrEqual(t.TriggerPrice), false
+		}
+		return price.LessThanOrEqual(t.TriggerPrice), false
+	case Trailing:
+		if !t.Activated {
+			if !t.TriggerPrice.IsZero() &&
+				((t.sell() && price.LessThan(t.TriggerPrice)) || (!t.sell() && price.GreaterThan(t.TriggerPrice))) {
+				return false, false
+			}
+			t.Activated, t.Extreme = true, price
+			return false, true
+		}
+		if (t.sell() && price.GreaterThan(t.Extreme)) || (!t.sell() && price.LessThan(t.Extreme)) {
+			t.Extreme = price
+			return false, true
+		}
+		distance := t.Extreme.Mul(t.TrailingDelta)
+		if t.sell() {
+			return price.LessThanOrEqual(t.Extreme.Sub(distance)), false
+		}
+		return price.GreaterThanOrEqual(t.Extreme.Add(distance)), false
+	}
+	return false, false
+}
+
+func (t *Trigger) validate() error {
+	switch t.Kind {
+	case Stop, TakeProfit:
+		if !t.TriggerPrice.IsPositive() {
+			return fmt.Errorf("%s trigger needs a trigger price", t.Kind)
+		}
+	case Trailing:
+		if !t.TrailingDelta.IsPositive() || t.TrailingDelta.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+			return errors.New("trailing delta must be between 0 and 1")
+		}
+	default:
+		return fmt.Errorf("unknown trigger kind %q", t.Kind)
+	}
+	if t.Order.Symbol == "" || !t.Order.Quantity.IsPositive() {
+		return errors.New("trigger order needs symbol and quantity")
+	}
+	return nil
+}
+
+// Event a fired trigger, OrderId is empty and Err set when placing the order failed.
+type Event struct {
+	Trigger Trigger
+	// Price the last price that met the condition
+	Price   decimal.Decimal
+	OrderId string
+	Err     error
+	// Timestamp in milliseconds
+	Timestamp int64
+}
+
+// Engine evaluates pending triggers against price updates and places their orders.
+// A trigger is removed and persisted before its order is sent, so a crash can not fire it twice.
+type Engine struct {
+	connector platforms.SpotConnector
+	store     Store
+	events    chan Event
+	mux       sync.Mutex
+	triggers  map[string]*Trigger
+	sequence  atomic.Uint64
+}
+
+// eventBuffer events the consumer may lag behind before price handling blocks
+const eventBuffer = 64
+
+// NewEngine restore the pending triggers of store, a nil store keeps them in memory only.
+func NewEngine(connector platforms.SpotConnector, store Store) (*Engine, error) {
+	engine := &Engine{
+		connector: connector,
+		store:     store,
+		events:    make(chan Event, eventBuffer),
+		triggers:  make(map[string]*Trigger),
+	}
+	// ids stay unique across restarts
+	engine.sequence.Store(uint64(time.Now().UnixNano()))
+	if store == nil {
+		return engine, nil
+	}
+	triggers, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range triggers {
+		engine.triggers[triggers[i].Id] = &triggers[i]
+	}
+	return engine, nil
+}
+
+// Events fired triggers, must be drained.
+func (e *Engine) Events() <-chan Event {
+	return e.events
+}
+
+// Add register a trigger and return its id.
+func (e *Engine) Add(trigger Trigger) (string, error) {
+	if err := trigger.validate(); err != nil {
+		return "", err
+	}
+	trigger.Id = strconv.FormatUint(e.sequence.Add(1), 10)
+	trigger.Activated, trigger.Extreme = false, decimal.Zero
+	trigger.CreateTime = time.Now().UnixMilli()
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.triggers[trigger.Id] = &trigger
+	if err := e.save(); err != nil {
+		delete(e.triggers, trigger.Id)
+		return "", err
+	}
+	return trigger.Id, nil
+}
+
+// Remove drop a pending trigger, unknown ids are ignored.
+func (e *Engine) Remove(id string) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if _, ok := e.triggers[id]; !ok {
+		return nil
+	}
+	delete(e.triggers, id)
+	return e.save()
+}
+
+// Triggers snapshot of the pending triggers.
+func (e *Engine) Triggers() []Trigger {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	var triggers = make([]Trigger, 0, len(e.triggers))
+	for _, trigger := range e.triggers {
+		triggers = append(triggers, *trigger)
+	}
+	return triggers
+}
+
+// OnPrice evaluate the triggers of symbol against its last price.
+// Fired triggers are only placed once their removal is persisted.
+func (e *Engine) OnPrice(symbol string, price decimal.Decimal) {
+	symbol = standardize(symbol)
+	var fired []Trigger
+	var changed bool
+	e.mux.Lock()
+	for id, trigger := range e.triggers {
+		if standardize(trigger.Order.Symbol) != symbol {
+			continue
+		}
+		fire, updated := trigger.observe(price)
+		changed = changed || updated
+		if fire {
+			fired = append(fired, *trigger)
+			delete(e.triggers, id)
+		}
+	}
+	if changed || len(fired) > 0 {
+		if err := e.save(); err != nil {
+			platforms.LoggerOf(e.connector).Error("persist triggers failed", slog.String("error", err.Error()))
+			// a restart would fire them again, they stay pending and fire on a later price once saved
+			for i := range fired {
+				e.triggers[fired[i].Id] = &fired[i]
+			}
+			fired = nil
+		}
+	}
+	e.mux.Unlock()
+	for _, trigger := range fired {
+		orderId, err := e.connector.PlaceOrder(trigger.Order)
+		e.events <- Event{
+			Trigger:   trigger,
+			Price:     price,
+			OrderId:   orderId,
+			Err:       err,
+			Timestamp: time.Now().UnixMilli(),
+		}
+	}
+}
+
+// Watch feed the close of symbol's 1m candles as last price until ctx is done.
+func (e *Engine) Watch(ctx context.Context, streamer platforms.MarketStreamer, symbol string) error {
+	candles := make(chan types.CandleEntry)
+	if err := streamer.CandleStream(ctx, symbol, "1m", candles); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case candle := <-candles:
+				// [start_timestamp, open, high, low, close, volume, (volume_usd)]
+				if len(candle) > 4 && candle[4] > 0 {
+					e.OnPrice(symbol, decimal.NewFromFloat(candle[4]))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// save persist the pending triggers, the caller holds mux.
+func (e *Engine) save() error {
+	if e.store == nil {
+		return nil
+	}
+	var triggers = make([]Trigger, 0, len(e.triggers))
+	for _, trigger := range e.triggers {
+		triggers = append(triggers, *trigger)
+	}
+	return e.store.Save(triggers)
+}
+
+func standardize(symbol string) string {
+	standard, err := constants.StandardizeSymbol(symbol)
+	if err != nil {
+		return symbol
+	}
+	return standard
+}
@@ -0,0 +1,127 @@
+package conditional
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+type recorder struct {
+	platforms.SpotConnector
+	placed []types.OrderEntry
+}
+
+func (c *recorder) PlaceOrder(order types.OrderEntry) (string, error) {
+	c.placed = append(c.placed, order)
+	return "order-1", nil
+}
+
+func price(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
+func TestEngine(t *testing.T) {
+	connector := &recorder{}
+	store := NewFileStore(filepath.Join(t.TempDir(), "triggers.json"))
+	engine, err := NewEngine(connector, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop, err := engine.Add(Trigger{
+		Kind:         Stop,
+		TriggerPrice: price(95),
+		Order:        types.OrderEntry{Symbol: "BTC_USDT", Side: "SELL", Quantity: price(1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = engine.Add(Trigger{
+		Kind:          Trailing,
+		TrailingDelta: price(0.1),
+		Order:         types.OrderEntry{Symbol: "ETHUSDT", Side: "SELL", Quantity: price(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = engine.Add(Trigger{Kind: Trailing, TrailingDelta: price(1.5)}); err == nil {
+		t.Error("expected invalid trailing delta to be rejected")
+	}
+
+	engine.OnPrice("BTCUSDT", price(96))
+	engine.OnPrice("ETHUSDT", price(100))
+	engine.OnPrice("ETHUSDT", price(120))
+	if len(connector.placed) != 0 {
+		t.Fatalf("fired too early: %+v", connector.placed)
+	}
+
+	// a restart keeps the trailing peak
+	engine, err = NewEngine(connector, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(engine.Triggers()) != 2 {
+		t.Fatalf("expected 2 restored triggers, got %+v", engine.Triggers())
+	}
+	engine.OnPrice("ETHUSDT", price(109))
+	if len(connector.placed) != 0 {
+		t.Fatalf("trailing fired above peak minus delta: %+v", connector.placed)
+	}
+	engine.OnPrice("ETH-USDT", price(108))
+	event := <-engine.Events()
+	if event.Trigger.Kind != Trailing || event.OrderId != "order-1" || !event.Price.Equal(price(108)) {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	engine.OnPrice("BTCUSDT", price(95))
+	event = <-engine.Events()
+	if event.Trigger.Id != stop {
+		t.Errorf("expected stop %s to fire, got %+v", stop, event)
+	}
+	if len(connector.placed) != 2 || len(engine.Triggers()) != 0 {
+		t.Errorf("unexpected state, placed %+v, pending %+v", connector.placed, engine.Triggers())
+	}
+	restored, err := store.Load()
+	if err != nil || len(restored) != 0 {
+		t.Errorf("fired triggers still persisted: %+v, %v", restored, err)
+	}
+}
+
+// failing refuses every save after the first fails times
+type failing struct {
+	Store
+	fails int
+}
+
+func (s *failing) Save(triggers []Trigger) error {
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("disk full")
+	}
+	return s.Store.Save(triggers)
+}
+
+func TestSaveFailureKeepsTriggers(t *testing.T) {
+	connector := &recorder{}
+	store := &failing{Store: NewFileStore(filepath.Join(t.TempDir(), "triggers.json"))}
+	engine, err := NewEngine(connector, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = engine.Add(Trigger{Kind: Stop, TriggerPrice: price(95), Order: types.OrderEntry{Symbol: "BTCUSDT", Side: "SELL", Quantity: price(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	store.fails = 1
+	engine.OnPrice("BTCUSDT", price(94))
+	if len(connector.placed) != 0 || len(engine.Triggers()) != 1 {
+		t.Fatalf("placed although the removal was not saved, placed %+v", connector.placed)
+	}
+	engine.OnPrice("BTCUSDT", price(94))
+	<-engine.Events()
+	if len(connector.placed) != 1 || len(engine.Triggers()) != 0 {
+		t.Errorf("trigger not fired once saved, placed %+v", connector.placed)
+	}
+}
@@ -0,0 +1,63 @@
+package conditional
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/xavierzho/go-cexs/utils"
+)
+
+// Store persistence of pending triggers.
+type Store interface {
+	Load() ([]Trigger, error)
+	// Save replace the stored triggers.
+	Save(triggers []Trigger) error
+}
+
+// FileStore keeps the triggers as a json file, replaced atomically on every save.
+type FileStore struct {
+	Path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// Load a missing file means no pending triggers.
+func (s *FileStore) Load() ([]Trigger, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var triggers []Trigger
+	err = utils.Json.Unmarshal(data, &triggers)
+	return triggers, err
+}
+
+func (s *FileStore) Save(triggers []Trigger) error {
+	data, err := utils.Json.Marshal(triggers)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
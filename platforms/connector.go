@@ -1,6 +1,8 @@
 package platforms
 
 import (
+	"context"
+
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/types"
 )
@@ -27,6 +29,9 @@ type SpotConnector interface {
 	Name() constants.Platform
 	// SymbolPattern formats a symbol into a standardized pattern (e.g., BTC_USDT).
 	SymbolPattern(symbol string) string
+	// GetFeeRates retrieves the account's maker/taker commission rates keyed by symbol.
+	// symbols: A slice of trading pair symbols.
+	GetFeeRates(symbols []string) (map[string]types.FeeRateEntry, error)
 	// Trade Embeds interface for trading operations.
 	Trade
 	// SpotMarketData Embeds interface for market data retrieval.
@@ -56,9 +61,14 @@ type Trade interface {
 	// PlaceOrder places a new order.
 	// params: Order parameters.
 	PlaceOrder(params types.OrderEntry) (string, error)
-	// BatchOrder places multiple orders at once.
+	// BatchOrder places multiple orders at once, chunked to the exchange batch limit.
+	// The results are in input order; a non-nil error means at least one chunk failed as a whole.
 	// orders: A slice of order parameters.
-	BatchOrder(orders []types.OrderEntry) ([]string, error)
+	BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error)
+	// PlaceOrderList places an OCO, OTO or OTOCO order list.
+	// Returns ErrNotSupported when the exchange has no native order lists, see OrderListEmulator.
+	// list: Legs of the order list.
+	PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error)
 	// QueryOrder retrieve order
 	// symbol: Trading pair symbol.
 	// orderId: ID of the order.
@@ -74,16 +84,32 @@ type Trade interface {
 	// CancelAll cancels all pending orders for a given symbol.
 	// symbol: Trading pair symbol.
 	CancelAll(symbol string) error
-	// CancelByIds cancels orders by their IDs.
+	// CancelAllSymbols cancels every pending order of the account across all symbols.
+	// Returns ErrNotSupported when the exchange cannot list or cancel orders without a symbol.
+	CancelAllSymbols() error
+	// CancelByIds cancels orders by their IDs, the results are in input order.
+	// A failed cancel carries an error matching ErrOrderNotFound, ErrOrderFilled, ErrOrderClosed or ErrNetwork when known.
 	// symbol: Trading pair symbol.
 	// orderIds: A slice of order IDs.
-	CancelByIds(symbol string, orderIds []string) (map[string]bool, error)
+	CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error)
 	// Balance retrieves account balances. If symbols is empty, retrieves all balances.
 	// symbols: A slice of symbols to retrieve balances for (optional).
 	Balance(symbols []string) (map[string]types.BalanceEntry, error)
 	// PendingOrders retrieves all pending (open) orders for a given symbol.
 	// symbol: Trading pair symbol.
 	PendingOrders(symbol string) ([]types.OpenOrderEntry, error)
+	// GetMyTrades retrieves the account's own executions (fills) for a given symbol.
+	// Pagination over the exchange cursor is handled internally.
+	// symbol: Trading pair symbol.
+	// since: Start time in milliseconds (inclusive).
+	// until: End time in milliseconds (inclusive).
+	GetMyTrades(ctx context.Context, symbol string, since, until int64) ([]types.FillEntry, error)
+	// OrderHistory retrieves historical orders for a given symbol, paging through the exchange history internally.
+	// symbol: Trading pair symbol.
+	// since: Start time in milliseconds (inclusive).
+	// until: End time in milliseconds (inclusive).
+	// statuses: Unified statuses to keep (optional). If empty, every order is returned.
+	OrderHistory(ctx context.Context, symbol string, since, until int64, statuses []constants.OrderStatus) ([]types.QueryOrder, error)
 }
 
 // Credentials each exchange oauth keys
@@ -0,0 +1,138 @@
+package platforms
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CountdownCanceler exchange-side dead-man's switch, every pending order is canceled
+// unless the countdown is refreshed before it expires.
+type CountdownCanceler interface {
+	// CancelAllAfter arm or refresh the countdown, a zero timeout disarms it.
+	CancelAllAfter(timeout time.Duration) error
+}
+
+// CancelAllAfter arm the countdown of connector, ErrNotSupported where it has none.
+// Wrappers embedding a connector forward CancelAllAfter through it, so they are CountdownCanceler
+// whatever they wrap and a DeadManSwitch falls back to emulation on ErrNotSupported.
+func CancelAllAfter(connector SpotConnector, timeout time.Duration) error {
+	canceler, ok := connector.(CountdownCanceler)
+	if !ok {
+		return ErrNotSupported
+	}
+	return canceler.CancelAllAfter(timeout)
+}
+
+// DeadManSwitch keep the exchange countdown armed while the process is alive.
+// Where the connector is no CountdownCanceler, or its CancelAllAfter reports ErrNotSupported,
+// the switch is emulated client-side:
+// the exchange is probed every interval and once it has been unreachable for timeout,
+// CancelAllSymbols is retried until it goes through.
+// Emulation can not help when the process itself dies.
+type DeadManSwitch struct {
+	connector SpotConnector
+	timeout   time.Duration
+	interval  time.Duration
+	mux       sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+	// emulated the countdown turned out not supported on arming
+	emulated atomic.Bool
+}
+
+// NewDeadManSwitch the countdown is refreshed every third of timeout.
+func NewDeadManSwitch(connector SpotConnector, timeout time.Duration) *DeadManSwitch {
+	return &DeadManSwitch{
+		connector: connector,
+		timeout:   timeout,
+		interval:  timeout / 3,
+	}
+}
+
+// Native whether the exchange runs the countdown itself.
+// A wrapper over a connector without one is found out by Start.
+func (s *DeadManSwitch) Native() bool {
+	_, ok := s.connector.(CountdownCanceler)
+	return ok && !s.emulated.Load()
+}
+
+// Start arm the countdown and keep refreshing it until ctx is done or Stop is called.
+// When ctx is done without Stop the exchange countdown is left armed and will fire.
+func (s *DeadManSwitch) Start(ctx context.Context) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.timeout <= 0 {
+		return errors.New("dead man's switch timeout must be positive")
+	}
+	if s.cancel != nil {
+		return errors.New("dead man's switch already started")
+	}
+	if err := s.arm(); err != nil {
+		return err
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	go s.run(ctx)
+	return nil
+}
+
+// Stop stop refreshing and disarm the exchange countdown, pending orders stay on the book.
+func (s *DeadManSwitch) Stop() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+	if s.Native() {
+		return CancelAllAfter(s.connector, 0)
+	}
+	return nil
+}
+
+func (s *DeadManSwitch) arm() error {
+	if s.Native() {
+		err := CancelAllAfter(s.connector, s.timeout)
+		if !errors.Is(err, ErrNotSupported) {
+			return err
+		}
+		s.emulated.Store(true)
+	}
+	_, err := s.connector.GetServerTime()
+	return err
+}
+
+func (s *DeadManSwitch) run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	alive := time.Now()
+	fired := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.arm()
+			if err == nil {
+				alive, fired = time.Now(), false
+				continue
+			}
+			LoggerOf(s.connector).Warn("dead man's switch refresh failed", slog.String("platform", string(s.connector.Name())), slog.String("error", err.Error()))
+			if s.Native() || fired || time.Since(alive) < s.timeout {
+				continue
+			}
+			if err = s.connector.CancelAllSymbols(); err != nil {
+				LoggerOf(s.connector).Error("dead man's switch cancel failed", slog.String("platform", string(s.connector.Name())), slog.String("error", err.Error()))
+				continue
+			}
+			fired = true
+		}
+	}
+}
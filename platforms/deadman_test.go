@@ -0,0 +1,113 @@
+package platforms
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xavierzho/go-cexs/constants"
+)
+
+type probed struct {
+	SpotConnector
+	down     atomic.Bool
+	canceled atomic.Int32
+}
+
+func (*probed) Name() constants.Platform { return constants.Binance }
+
+func (c *probed) GetServerTime() (int64, error) {
+	if c.down.Load() {
+		return 0, ErrNetwork
+	}
+	return time.Now().UnixMilli(), nil
+}
+
+func (c *probed) CancelAllSymbols() error {
+	c.canceled.Add(1)
+	return nil
+}
+
+type countdown struct {
+	probed
+	timeouts []time.Duration
+}
+
+func (c *countdown) CancelAllAfter(timeout time.Duration) error {
+	c.timeouts = append(c.timeouts, timeout)
+	return nil
+}
+
+func TestDeadManSwitchNative(t *testing.T) {
+	connector := &countdown{}
+	s := NewDeadManSwitch(connector, 30*time.Millisecond)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(connector.timeouts) < 3 || connector.timeouts[len(connector.timeouts)-1] != 0 {
+		t.Fatalf("expected arm, refresh and disarm, got %v", connector.timeouts)
+	}
+	if slices.Contains(connector.timeouts[:len(connector.timeouts)-1], 0) {
+		t.Errorf("disarmed while running %v", connector.timeouts)
+	}
+}
+
+func TestDeadManSwitchEmulated(t *testing.T) {
+	connector := &probed{}
+	s := NewDeadManSwitch(connector, 30*time.Millisecond)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	connector.down.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	if n := connector.canceled.Load(); n != 1 {
+		t.Errorf("expected a single cancel after losing the exchange, got %d", n)
+	}
+}
+
+// wrapped forwards the countdown the way the risk, journal and tracing connectors do.
+type wrapped struct {
+	SpotConnector
+}
+
+func (w *wrapped) CancelAllAfter(timeout time.Duration) error {
+	return CancelAllAfter(w.SpotConnector, timeout)
+}
+
+func TestDeadManSwitchWrapped(t *testing.T) {
+	native := &countdown{}
+	s := NewDeadManSwitch(&wrapped{native}, time.Minute)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Native() || len(native.timeouts) != 2 || native.timeouts[1] != 0 {
+		t.Errorf("expected the wrapped countdown armed and disarmed, got %v", native.timeouts)
+	}
+
+	emulated := &probed{}
+	s = NewDeadManSwitch(&wrapped{emulated}, 30*time.Millisecond)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s.Native() {
+		t.Error("a wrapper over a connector without countdown reported native")
+	}
+	emulated.down.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if n := emulated.canceled.Load(); n != 1 {
+		t.Errorf("expected a single emulated cancel, got %d", n)
+	}
+}
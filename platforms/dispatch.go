@@ -0,0 +1,204 @@
+package platforms
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Dispatcher the single reader of a stream shared by several subscriptions, e.g. the order,
+// balance and account streams of one private connection. A websocket allows one concurrent
+// reader only, so subscriptions register handlers by topic instead of reading themselves.
+type Dispatcher struct {
+	// Route topic of a push, "" for anything else (acks, pongs).
+	Route func(msg []byte) string
+	// ReplyId request id of a reply to Request, false for anything else. Nil when the venue sends no replies.
+	ReplyId func(msg []byte) (string, bool)
+	// Recover re-establish the connection after a read failure, replaying subscriptions without
+	// waiting for their acks. StreamBase.Reconnect by default.
+	Recover func() error
+	// Timeout how long Request waits for a reply.
+	Timeout time.Duration
+
+	stream      *StreamBase
+	mux         sync.Mutex
+	handlers    map[string]map[uint64]func(msg []byte) // topic -> handler
+	pending     map[string]chan []byte                 // request id -> reply
+	running     bool
+	sequence    atomic.Uint64
+	subscribing sync.Mutex
+}
+
+func NewDispatcher(stream *StreamBase, route func(msg []byte) string) *Dispatcher {
+	return &Dispatcher{
+		Route:    route,
+		Recover:  stream.Reconnect,
+		Timeout:  defaultWriteWait,
+		stream:   stream,
+		handlers: make(map[string]map[uint64]func(msg []byte)),
+		pending:  make(map[string]chan []byte),
+	}
+}
+
+// NextId a request id unique to the dispatcher.
+func (d *Dispatcher) NextId() string {
+	return strconv.FormatUint(d.sequence.Add(1), 10)
+}
+
+// Subscribe deliver the pushes of topic to handle until ctx is done. subscribe is called for
+// the first handler of a topic only, so streams split from one channel subscribe it once.
+// The stream is closed once the last handler is gone.
+func (d *Dispatcher) Subscribe(ctx context.Context, topic string, subscribe func() error, handle func(msg []byte)) error {
+	d.subscribing.Lock()
+	defer d.subscribing.Unlock()
+	d.start()
+	// registered before subscribing, the first pushes may follow the ack immediately
+	id := d.sequence.Add(1)
+	d.mux.Lock()
+	_, subscribed := d.handlers[topic]
+	if !subscribed {
+		d.handlers[topic] = make(map[uint64]func(msg []byte))
+	}
+	d.handlers[topic][id] = handle
+	d.mux.Unlock()
+	if !subscribed {
+		if err := subscribe(); err != nil {
+			d.remove(topic, id)
+			return err
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		if d.remove(topic, id) {
+			_ = d.stream.Close()
+		}
+	}()
+	return nil
+}
+
+// remove a handler, true if it was the last of the stream.
+func (d *Dispatcher) remove(topic string, id uint64) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	delete(d.handlers[topic], id)
+	if len(d.handlers[topic]) == 0 {
+		delete(d.handlers, topic)
+	}
+	return len(d.handlers) == 0
+}
+
+// Request send payload tagged with id and wait for its reply, which the reader routes by ReplyId.
+func (d *Dispatcher) Request(id string, payload map[string]any) ([]byte, error) {
+	d.start()
+	reply := make(chan []byte, 1)
+	d.mux.Lock()
+	d.pending[id] = reply
+	d.mux.Unlock()
+	defer func() {
+		d.mux.Lock()
+		delete(d.pending, id)
+		d.mux.Unlock()
+	}()
+	if err := d.stream.WriteJSON(payload); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(d.Timeout)
+	defer timer.Stop()
+	select {
+	case msg, ok := <-reply:
+		if !ok {
+			return nil, NetworkError(fmt.Errorf("connection lost before reply to request %s", id))
+		}
+		return msg, nil
+	case <-timer.C:
+		return nil, NetworkError(fmt.Errorf("no reply to request %s within %s", id, d.Timeout))
+	case <-d.stream.Done():
+		return nil, NetworkError(fmt.Errorf("stream closed before reply to request %s", id))
+	}
+}
+
+// start the reader unless it runs already.
+func (d *Dispatcher) start() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if !d.running {
+		d.running = true
+		go d.read()
+	}
+}
+
+// read route every message to the handlers of its topic or the request it replies to.
+// A broken connection fails the pending requests and is recovered, the reader stops when
+// the stream is closed or cannot be recovered.
+func (d *Dispatcher) read() {
+	defer func() {
+		d.mux.Lock()
+		d.running = false
+		d.mux.Unlock()
+	}()
+	for {
+		msg, err := d.stream.ReadMessage()
+		if err != nil {
+			d.failPending()
+			select {
+			case <-d.stream.Done():
+				return
+			default:
+			}
+			if err = d.Recover(); err != nil {
+				d.stream.Logger().Error("stream stopped", slog.String("url", d.stream.url), slog.String("error", err.Error()))
+				return
+			}
+			continue
+		}
+		if d.ReplyId != nil {
+			if id, ok := d.ReplyId(msg); ok {
+				d.mux.Lock()
+				reply, ok := d.pending[id]
+				delete(d.pending, id)
+				d.mux.Unlock()
+				if ok {
+					reply <- msg
+				}
+				continue
+			}
+		}
+		topic := d.Route(msg)
+		if topic == "" {
+			continue
+		}
+		d.mux.Lock()
+		handlers := make([]func(msg []byte), 0, len(d.handlers[topic]))
+		for _, handle := range d.handlers[topic] {
+			handlers = append(handlers, handle)
+		}
+		d.mux.Unlock()
+		for _, handle := range handlers {
+			handle(msg)
+		}
+	}
+}
+
+// Deliver send value on channel, false when ctx is done first. Handlers run on the reader of
+// every subscription of the stream, a consumer that stopped reading must not block the others.
+func Deliver[T any](ctx context.Context, channel chan<- T, value T) bool {
+	select {
+	case channel <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (d *Dispatcher) failPending() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	for id, reply := range d.pending {
+		close(reply)
+		delete(d.pending, id)
+	}
+}
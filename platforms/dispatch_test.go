@@ -0,0 +1,159 @@
+package platforms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// topicServer acks every request by id. A subscribe is preceded by a push to the topics
+// subscribed already, and every ack followed by a push to each subscribed topic.
+func topicServer(t *testing.T) *httptest.Server {
+	var upgrader websocket.Upgrader
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		var topics []string
+		push := func() {
+			for _, topic := range topics {
+				_ = conn.WriteJSON(map[string]string{"topic": topic})
+			}
+		}
+		for {
+			var request map[string]string
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			if topic := request["topic"]; topic != "" {
+				push()
+				topics = append(topics, topic)
+			}
+			_ = conn.WriteJSON(map[string]string{"id": request["id"]})
+			push()
+		}
+	}))
+}
+
+type counter struct {
+	mux    sync.Mutex
+	counts map[string]int
+}
+
+func (c *counter) handle(topic string) func(msg []byte) {
+	return func(msg []byte) {
+		var push map[string]string
+		_ = json.Unmarshal(msg, &push)
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		// a push of another topic counts under its own name with the handler's
+		c.counts[topic+"<-"+push["topic"]]++
+	}
+}
+
+func (c *counter) get(key string) int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.counts[key]
+}
+
+func TestDispatcherRoutesByTopic(t *testing.T) {
+	server := topicServer(t)
+	defer server.Close()
+	stream := NewStream()
+	if err := stream.Connect("ws" + strings.TrimPrefix(server.URL, "http")); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := NewDispatcher(stream, func(msg []byte) string {
+		var push map[string]string
+		_ = json.Unmarshal(msg, &push)
+		return push["topic"]
+	})
+	dispatcher.ReplyId = func(msg []byte) (string, bool) {
+		var reply map[string]string
+		_ = json.Unmarshal(msg, &reply)
+		return reply["id"], reply["id"] != ""
+	}
+	subscribe := func(topic string) func() error {
+		return func() error {
+			id := dispatcher.NextId()
+			_, err := dispatcher.Request(id, map[string]any{"id": id, "topic": topic})
+			return err
+		}
+	}
+	received := &counter{counts: make(map[string]int)}
+	orders, stopOrders := context.WithCancel(context.Background())
+	balances, stopBalances := context.WithCancel(context.Background())
+	defer stopBalances()
+	if err := dispatcher.Subscribe(orders, "orders", subscribe("orders"), received.handle("orders")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Subscribe(balances, "balances", subscribe("balances"), received.handle("balances")); err != nil {
+		t.Fatal(err)
+	}
+	// orders: after its ack, before and after the balances ack
+	waitFor(t, func() bool { return received.get("orders<-orders") == 3 && received.get("balances<-balances") == 1 })
+	// a second handler of a topic subscribes nothing
+	if err := dispatcher.Subscribe(balances, "balances", func() error {
+		t.Error("balances subscribed twice")
+		return nil
+	}, received.handle("account")); err != nil {
+		t.Fatal(err)
+	}
+
+	stopOrders()
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-stream.Done():
+		t.Fatal("stream closed while balances are still read")
+	default:
+	}
+	if _, err := dispatcher.Request("ping", map[string]any{"id": "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return received.get("balances<-balances") == 2 && received.get("account<-balances") == 1 })
+	if received.get("orders<-orders") != 3 || received.get("orders<-balances") != 0 || received.get("balances<-orders") != 0 {
+		t.Errorf("pushes routed to the wrong handler: %v", received.counts)
+	}
+
+	stopBalances()
+	select {
+	case <-stream.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stream not closed after the last handler left")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDeliverStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	channel := make(chan int, 1)
+	if !Deliver(ctx, channel, 1) {
+		t.Fatal("send with room failed")
+	}
+	cancel()
+	// nobody reads the full channel any more
+	if Deliver(ctx, channel, 2) {
+		t.Error("send succeeded on a full channel")
+	}
+}
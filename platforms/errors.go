@@ -0,0 +1,41 @@
+package platforms
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors shared by every exchange, match them with errors.Is.
+var (
+	// ErrOrderNotFound the exchange does not know the order.
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrOrderFilled the order is already fully filled.
+	ErrOrderFilled = errors.New("order already filled")
+	// ErrOrderClosed the order is already finished, the exchange does not tell whether filled or canceled.
+	ErrOrderClosed = errors.New("order already closed")
+	// ErrNetwork the request did not reach the exchange or no response was received.
+	ErrNetwork = errors.New("network failure")
+	// ErrNotSupported the exchange has no api for the operation.
+	ErrNotSupported = errors.New("not supported")
+)
+
+// ExchangeError error returned by an exchange api, Err is the matching sentinel error if any.
+type ExchangeError struct {
+	Exchange string
+	Code     string
+	Message  string
+	Err      error
+}
+
+func (e *ExchangeError) Error() string {
+	return fmt.Sprintf("[%s] code: %s, msg: %s", e.Exchange, e.Code, e.Message)
+}
+
+func (e *ExchangeError) Unwrap() error {
+	return e.Err
+}
+
+// NetworkError wrap a transport error so it matches ErrNetwork.
+func NetworkError(err error) error {
+	return fmt.Errorf("%w: %w", ErrNetwork, err)
+}
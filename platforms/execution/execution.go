@@ -0,0 +1,389 @@
+// Package execution works large parent orders through child orders following
+// TWAP, VWAP or participation of volume schedules on any SpotConnector.
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Instrument trading rules of the symbol. Connectors expose no instrument metadata,
+// so they are supplied by the caller; zero values disable the corresponding rule.
+type Instrument struct {
+	TickSize    decimal.Decimal
+	StepSize    decimal.Decimal
+	MinQuantity decimal.Decimal
+	MinNotional decimal.Decimal
+}
+
+// ParentOrder the order to work.
+type ParentOrder struct {
+	Symbol   string
+	Side     string
+	Quantity decimal.Decimal
+	// LimitPrice worst acceptable price, a buy never pays above it and a sell never sells below.
+	// Zero sends market child orders.
+	LimitPrice decimal.Decimal
+	Instrument Instrument
+	// Start, End in milliseconds, Start defaults to now; End is optional for POV only
+	Start int64
+	End   int64
+	// Interval between child orders, the unfilled rest of a child is canceled and rolled into the next.
+	Interval time.Duration
+	// Participation share of market volume for POV, e.g. 0.1 for 10%
+	Participation decimal.Decimal
+}
+
+// Progress state of an execution.
+type Progress struct {
+	Symbol   string
+	Side     string
+	Quantity decimal.Decimal
+	Filled   decimal.Decimal
+	// AveragePrice volume weighted price of the fills, zero before the first fill
+	AveragePrice decimal.Decimal
+	// ArrivalPrice last price when the execution started
+	ArrivalPrice decimal.Decimal
+	// Slippage of AveragePrice versus ArrivalPrice as a ratio, positive when worse for the side
+	Slippage decimal.Decimal
+	Children int
+	Done     bool
+}
+
+const (
+	// earlyLimit updates of unknown orders kept at most, the oldest is dropped first
+	earlyLimit = 256
+	// earlyTTL how long an update of an unknown order waits for its PlaceOrder reply
+	earlyTTL = time.Minute
+)
+
+// early an update of an order not known yet
+type early struct {
+	update   types.OrderUpdateEntry
+	received time.Time
+}
+
+// child order sent for the parent
+type child struct {
+	// orderId empty while the PlaceOrder reply was lost, the child is then known by tradeNo
+	orderId string
+	tradeNo string
+	sent    time.Time
+	filled  decimal.Decimal
+	quote   decimal.Decimal
+	closed  bool
+}
+
+type execution struct {
+	connector platforms.SpotConnector
+	parent    ParentOrder
+	schedule  Schedule
+	progress  chan<- Progress
+	children  []*child
+	// updates of orders not known yet, the stream may be faster than the PlaceOrder reply.
+	// Updates of other orders land here too, so entries expire after earlyTTL.
+	early   map[string]early
+	arrival decimal.Decimal
+}
+
+// Execute work parent following schedule until it is filled, End has passed or ctx is done.
+// updates must carry the account's order updates, e.g. from UserDataStreamer.OrderStream, updates
+// of other orders are ignored. Snapshots are sent to progress when it is not nil and has room.
+// The last Progress is returned, with the context error when ctx ended the execution and
+// the query error when the fill of the last child could not be read back.
+func Execute(ctx context.Context, connector platforms.SpotConnector, parent ParentOrder, schedule Schedule,
+	updates <-chan types.OrderUpdateEntry, progress chan<- Progress) (Progress, error) {
+	if !parent.Quantity.IsPositive() || parent.Interval <= 0 {
+		return Progress{}, errors.New("parent order needs quantity and interval")
+	}
+	if _, ok := schedule.(*pov); parent.End == 0 && !ok {
+		return Progress{}, errors.New("parent order needs End unless following POV")
+	}
+	if parent.Start == 0 {
+		parent.Start = time.Now().UnixMilli()
+	}
+	ticker, err := connector.GetTicker(parent.Symbol)
+	if err != nil {
+		return Progress{}, err
+	}
+	x := &execution{
+		connector: connector,
+		parent:    parent,
+		schedule:  schedule,
+		progress:  progress,
+		early:     make(map[string]early),
+		arrival:   ticker.Price,
+	}
+	clock := time.NewTicker(parent.Interval)
+	defer clock.Stop()
+	if wait := time.Until(time.UnixMilli(parent.Start)); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return x.report(false), ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	if err = x.step(); err != nil {
+		platforms.LoggerOf(connector).Warn("execution step failed", slog.String("symbol", parent.Symbol), slog.String("error", err.Error()))
+	}
+	for {
+		if x.complete() || x.expired() {
+			err = x.closeChild()
+			return x.report(true), err
+		}
+		select {
+		case <-ctx.Done():
+			return x.report(false), errors.Join(ctx.Err(), x.closeChild())
+		case update, ok := <-updates:
+			if !ok {
+				updates = nil // closed, fills are read back from QueryOrder
+				continue
+			}
+			if x.apply(update) {
+				x.report(false)
+			}
+		case <-clock.C:
+			if err = x.step(); err != nil {
+				platforms.LoggerOf(connector).Warn("execution step failed", slog.String("symbol", parent.Symbol), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (x *execution) buy() bool {
+	return strings.ToUpper(x.parent.Side) == "BUY"
+}
+
+func (x *execution) filled() (base, quote decimal.Decimal) {
+	for _, c := range x.children {
+		base = base.Add(c.filled)
+		quote = quote.Add(c.quote)
+	}
+	return base, quote
+}
+
+// complete nothing placeable is left
+func (x *execution) complete() bool {
+	base, _ := x.filled()
+	rest := x.parent.Quantity.Sub(base)
+	return !rest.IsPositive() || rest.LessThan(x.parent.Instrument.StepSize)
+}
+
+// expired the last slice had its Interval to fill
+func (x *execution) expired() bool {
+	return x.parent.End != 0 && time.Now().UnixMilli() >= x.parent.End+x.parent.Interval.Milliseconds()
+}
+
+// apply record an order update, report whether it belongs to the parent.
+func (x *execution) apply(update types.OrderUpdateEntry) bool {
+	for _, c := range x.children {
+		if c.orderId == "" && update.ClientOrderId != "" && c.tradeNo == update.ClientOrderId {
+			c.orderId = update.OrderId
+		}
+		if c.orderId != update.OrderId {
+			continue
+		}
+		c.filled = decimal.Max(c.filled, update.FilledQuantity)
+		quote := update.FilledQuote
+		if quote.IsZero() {
+			quote = update.Price.Mul(update.FilledQuantity)
+		}
+		c.quote = decimal.Max(c.quote, quote)
+		if update.Status == constants.Filled || update.Status == constants.Canceled {
+			c.closed = true
+		}
+		return true
+	}
+	x.keepEarly(update)
+	return false
+}
+
+// keepEarly hold the update of an unknown order until its PlaceOrder reply, within earlyLimit and earlyTTL.
+func (x *execution) keepEarly(update types.OrderUpdateEntry) {
+	now := time.Now()
+	var oldest string
+	for orderId, e := range x.early {
+		if now.Sub(e.received) > earlyTTL {
+			delete(x.early, orderId)
+		} else if oldest == "" || e.received.Before(x.early[oldest].received) {
+			oldest = orderId
+		}
+	}
+	if _, ok := x.early[update.OrderId]; !ok && len(x.early) >= earlyLimit {
+		delete(x.early, oldest)
+	}
+	x.early[update.OrderId] = early{update: update, received: now}
+}
+
+// closeChild cancel the open child, its final fill is read back so nothing is sent twice.
+// The child stays open when the query fails, its state is unknown until a later attempt or update.
+func (x *execution) closeChild() error {
+	if len(x.children) == 0 {
+		return nil
+	}
+	c := x.children[len(x.children)-1]
+	if c.closed {
+		return nil
+	}
+	if c.orderId == "" {
+		if err := x.resolve(c); err != nil || c.closed {
+			return err
+		}
+	}
+	_, err := x.connector.Cancel(x.parent.Symbol, c.orderId)
+	if err != nil && !errors.Is(err, platforms.ErrOrderNotFound) &&
+		!errors.Is(err, platforms.ErrOrderFilled) && !errors.Is(err, platforms.ErrOrderClosed) {
+		platforms.LoggerOf(x.connector).Warn("cancel child failed", slog.String("symbol", x.parent.Symbol), slog.String("order", c.orderId), slog.String("error", err.Error()))
+	}
+	order, err := x.connector.QueryOrder(x.parent.Symbol, c.orderId)
+	if err != nil {
+		return fmt.Errorf("query child %s: %w", c.orderId, err)
+	}
+	if order.Filled.GreaterThan(c.filled) {
+		price := order.Price
+		if !price.IsPositive() {
+			price = x.arrival // market orders report no price
+		}
+		c.quote = c.quote.Add(order.Filled.Sub(c.filled).Mul(price))
+		c.filled = order.Filled
+	}
+	c.closed = true
+	return nil
+}
+
+// resolve find the child whose PlaceOrder reply was lost by its client order id. A child found
+// on neither the book nor the history after an Interval never reached the exchange and is closed.
+func (x *execution) resolve(c *child) error {
+	pending, err := x.connector.PendingOrders(x.parent.Symbol)
+	if err != nil {
+		return fmt.Errorf("resolve child %s: %w", c.tradeNo, err)
+	}
+	for _, order := range pending {
+		if order.TradeNo == c.tradeNo {
+			c.orderId = order.OrderId
+			return nil
+		}
+	}
+	history, err := x.connector.OrderHistory(context.Background(), x.parent.Symbol, c.sent.UnixMilli(), time.Now().UnixMilli(), nil)
+	if err != nil {
+		return fmt.Errorf("resolve child %s: %w", c.tradeNo, err)
+	}
+	for _, order := range history {
+		if order.TradeNo == c.tradeNo {
+			c.orderId = order.OrderId
+			return nil
+		}
+	}
+	if time.Since(c.sent) < x.parent.Interval {
+		return fmt.Errorf("child %s not found yet", c.tradeNo)
+	}
+	c.closed = true
+	return nil
+}
+
+// step roll the unfilled rest of the open child into a new child sized to the schedule.
+// Nothing is placed while the fill of the previous child is unknown.
+func (x *execution) step() error {
+	if err := x.closeChild(); err != nil {
+		return err
+	}
+	target, err := x.schedule.Target(x.parent, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	base, _ := x.filled()
+	quantity := roundDown(decimal.Min(target, x.parent.Quantity).Sub(base), x.parent.Instrument.StepSize)
+	if !quantity.IsPositive() || quantity.LessThan(x.parent.Instrument.MinQuantity) {
+		return nil
+	}
+	order := types.OrderEntry{
+		Symbol:   x.parent.Symbol,
+		Side:     x.parent.Side,
+		Type:     constants.Market,
+		Quantity: quantity,
+		TradeNo:  platforms.NewClientId(),
+	}
+	reference := x.arrival
+	if x.parent.LimitPrice.IsPositive() {
+		order.Type = constants.Limit
+		// rounding stays inside the limit
+		if x.buy() {
+			order.Price = roundDown(x.parent.LimitPrice, x.parent.Instrument.TickSize)
+		} else {
+			order.Price = roundUp(x.parent.LimitPrice, x.parent.Instrument.TickSize)
+		}
+		reference = order.Price
+	}
+	if quantity.Mul(reference).LessThan(x.parent.Instrument.MinNotional) {
+		return nil
+	}
+	orderId, err := x.connector.PlaceOrder(order)
+	if err != nil && !errors.Is(err, platforms.ErrNetwork) {
+		return fmt.Errorf("place child: %w", err)
+	}
+	// a child lost in transit may have reached the exchange, it is resolved before the next one
+	x.children = append(x.children, &child{orderId: orderId, tradeNo: order.TradeNo, sent: time.Now()})
+	for id, e := range x.early {
+		if orderId != "" && id == orderId || e.update.ClientOrderId == order.TradeNo {
+			delete(x.early, id)
+			x.apply(e.update)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("place child: %w", err)
+	}
+	x.report(false)
+	return nil
+}
+
+func (x *execution) report(done bool) Progress {
+	base, quote := x.filled()
+	p := Progress{
+		Symbol:       x.parent.Symbol,
+		Side:         x.parent.Side,
+		Quantity:     x.parent.Quantity,
+		Filled:       base,
+		ArrivalPrice: x.arrival,
+		Children:     len(x.children),
+		Done:         done,
+	}
+	if base.IsPositive() {
+		p.AveragePrice = quote.Div(base)
+		if x.arrival.IsPositive() {
+			p.Slippage = p.AveragePrice.Sub(x.arrival).Div(x.arrival)
+			if !x.buy() {
+				p.Slippage = p.Slippage.Neg()
+			}
+		}
+	}
+	if x.progress != nil {
+		select {
+		case x.progress <- p:
+		default:
+		}
+	}
+	return p
+}
+
+func roundDown(value, step decimal.Decimal) decimal.Decimal {
+	if !step.IsPositive() {
+		return value
+	}
+	return value.Div(step).Floor().Mul(step)
+}
+
+func roundUp(value, step decimal.Decimal) decimal.Decimal {
+	if !step.IsPositive() {
+		return value
+	}
+	return value.Div(step).Ceil().Mul(step)
+}
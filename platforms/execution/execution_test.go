@@ -0,0 +1,214 @@
+package execution
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// venue fills every child at price right away and reports it on updates
+type venue struct {
+	platforms.SpotConnector
+	price   decimal.Decimal
+	candles types.CandlesEntry
+	placed  []types.OrderEntry
+	updates chan types.OrderUpdateEntry
+}
+
+func (v *venue) GetTicker(symbol string) (types.TickerEntry, error) {
+	return types.TickerEntry{Symbol: symbol, Price: decimal.NewFromInt(100)}, nil
+}
+
+func (v *venue) GetCandles(string, string, int64) (types.CandlesEntry, error) {
+	return v.candles, nil
+}
+
+func (v *venue) PlaceOrder(order types.OrderEntry) (string, error) {
+	v.placed = append(v.placed, order)
+	orderId := strconv.Itoa(len(v.placed))
+	v.updates <- types.OrderUpdateEntry{
+		OrderId:        orderId,
+		Status:         constants.Filled,
+		FilledQuantity: order.Quantity,
+		FilledQuote:    order.Quantity.Mul(v.price),
+	}
+	return orderId, nil
+}
+
+func TestTWAP(t *testing.T) {
+	parent := ParentOrder{Quantity: decimal.NewFromInt(9), Start: 0, End: 3000, Interval: time.Second}
+	for now, want := range map[int64]int64{-1: 0, 0: 3, 999: 3, 1000: 6, 2500: 9, 10000: 9} {
+		if got, _ := TWAP().Target(parent, now); !got.Equal(decimal.NewFromInt(want)) {
+			t.Errorf("at %d want %d, got %s", now, want, got)
+		}
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	hour := time.Hour.Milliseconds()
+	day := 20000 * 24 * hour
+	// two days of hourly history, the second hour trades three times the first
+	connector := &venue{candles: types.CandlesEntry{
+		{float64(day), 1, 1, 1, 1, 10}, {float64(day + hour), 1, 1, 1, 1, 30},
+		{float64(day + 24*hour), 1, 1, 1, 1, 10}, {float64(day + 25*hour), 1, 1, 1, 1, 30},
+	}}
+	schedule, err := VWAP(connector, "BTCUSDT", "1h", 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := ParentOrder{Quantity: decimal.NewFromInt(8), Start: day + 48*hour, End: day + 50*hour, Interval: time.Hour}
+	if got, _ := schedule.Target(parent, parent.Start); !got.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("first slice want 2, got %s", got)
+	}
+	if got, _ := schedule.Target(parent, parent.Start+hour); !got.Equal(decimal.NewFromInt(8)) {
+		t.Errorf("second slice want 8, got %s", got)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	connector := &venue{price: decimal.NewFromInt(101), updates: make(chan types.OrderUpdateEntry, 16)}
+	start := time.Now().UnixMilli()
+	parent := ParentOrder{
+		Symbol:     "BTCUSDT",
+		Side:       "BUY",
+		Quantity:   decimal.NewFromFloat(1.05),
+		LimitPrice: decimal.NewFromFloat(102.37),
+		Instrument: Instrument{TickSize: decimal.NewFromFloat(0.1), StepSize: decimal.NewFromFloat(0.1)},
+		Start:      start,
+		End:        start + 60,
+		Interval:   20 * time.Millisecond,
+	}
+	progress, err := Execute(context.Background(), connector, parent, TWAP(), connector.updates, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !progress.Done || !progress.Filled.Equal(decimal.NewFromInt(1)) || progress.Children != 3 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	for _, order := range connector.placed {
+		if order.Type != constants.Limit || !order.Price.Equal(decimal.NewFromFloat(102.3)) {
+			t.Errorf("child outside limit or tick %+v", order)
+		}
+	}
+	if !progress.AveragePrice.Equal(decimal.NewFromInt(101)) || !progress.Slippage.Equal(decimal.NewFromFloat(0.01)) {
+		t.Errorf("unexpected price report %s, %s", progress.AveragePrice, progress.Slippage)
+	}
+}
+
+// stuck leaves every child open and fails the queries until queryFails is spent
+type stuck struct {
+	venue
+	queryFails int
+}
+
+func (v *stuck) PlaceOrder(order types.OrderEntry) (string, error) {
+	v.placed = append(v.placed, order)
+	return strconv.Itoa(len(v.placed)), nil
+}
+
+func (v *stuck) Cancel(string, string) (bool, error) {
+	return true, nil
+}
+
+func (v *stuck) QueryOrder(string, string) (types.QueryOrder, error) {
+	if v.queryFails > 0 {
+		v.queryFails--
+		return types.QueryOrder{}, platforms.ErrNetwork
+	}
+	return types.QueryOrder{Status: constants.Canceled}, nil
+}
+
+func TestUnknownChildBlocksPlacement(t *testing.T) {
+	connector := &stuck{queryFails: 2}
+	x := &execution{
+		connector: connector,
+		parent:    ParentOrder{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromInt(3), Interval: time.Second},
+		schedule:  TWAP(),
+		early:     make(map[string]early),
+		arrival:   decimal.NewFromInt(100),
+	}
+	if err := x.step(); err != nil || len(connector.placed) != 1 {
+		t.Fatalf("first child not placed: %v", err)
+	}
+	for range 2 {
+		if err := x.step(); err == nil || len(connector.placed) != 1 {
+			t.Fatalf("placed while the previous child is unknown: %v", err)
+		}
+	}
+	if err := x.step(); err != nil || len(connector.placed) != 2 {
+		t.Fatalf("placement not resumed: %v", err)
+	}
+}
+
+func TestEarlyBounded(t *testing.T) {
+	x := &execution{early: make(map[string]early)}
+	x.early["stale"] = early{received: time.Now().Add(-2 * earlyTTL)}
+	for i := range earlyLimit + 10 {
+		x.apply(types.OrderUpdateEntry{OrderId: strconv.Itoa(i)})
+	}
+	if _, ok := x.early["stale"]; ok || len(x.early) != earlyLimit {
+		t.Errorf("early holds %d updates", len(x.early))
+	}
+	if _, ok := x.early[strconv.Itoa(earlyLimit+9)]; !ok {
+		t.Error("latest update dropped")
+	}
+}
+
+func TestEndRequired(t *testing.T) {
+	parent := ParentOrder{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromInt(1), Interval: time.Second}
+	if _, err := Execute(context.Background(), &venue{}, parent, TWAP(), nil, nil); err == nil {
+		t.Error("TWAP without End accepted")
+	}
+}
+
+// lossy loses the reply of the first child, which still rests on the book
+type lossy struct {
+	stuck
+	pending []types.OpenOrderEntry
+}
+
+func (v *lossy) PlaceOrder(order types.OrderEntry) (string, error) {
+	orderId, _ := v.stuck.PlaceOrder(order)
+	if len(v.placed) == 1 {
+		v.pending = append(v.pending, types.OpenOrderEntry{OrderId: orderId, TradeNo: order.TradeNo})
+		return "", platforms.NetworkError(context.DeadlineExceeded)
+	}
+	return orderId, nil
+}
+
+func (v *lossy) PendingOrders(string) ([]types.OpenOrderEntry, error) {
+	return v.pending, nil
+}
+
+func (v *lossy) QueryOrder(_ string, orderId string) (types.QueryOrder, error) {
+	return types.QueryOrder{OrderId: orderId, Status: constants.Canceled, Price: decimal.NewFromInt(100), Filled: decimal.NewFromInt(1)}, nil
+}
+
+func TestLostChild(t *testing.T) {
+	connector := &lossy{}
+	x := &execution{
+		connector: connector,
+		parent:    ParentOrder{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromInt(3), End: 1, Interval: time.Second},
+		schedule:  TWAP(),
+		early:     make(map[string]early),
+		arrival:   decimal.NewFromInt(100),
+	}
+	if err := x.step(); err == nil || len(x.children) != 1 || connector.placed[0].TradeNo == "" {
+		t.Fatalf("lost child not recorded: %v", err)
+	}
+	if err := x.step(); err != nil {
+		t.Fatal(err)
+	}
+	if base, _ := x.filled(); !base.Equal(decimal.NewFromInt(1)) || x.children[0].orderId != "1" {
+		t.Errorf("fill of the lost child not found by client id, filled %s", base)
+	}
+	if len(connector.placed) != 2 || !connector.placed[1].Quantity.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("next child not sized after the lost one %+v", connector.placed)
+	}
+}
@@ -0,0 +1,151 @@
+package execution
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Schedule how much of the parent order should have been sent at a point in time.
+type Schedule interface {
+	// Target cumulative quantity of parent to have sent by now (milliseconds).
+	Target(parent ParentOrder, now int64) (decimal.Decimal, error)
+}
+
+// slices number of Interval slices between Start and End, at least one.
+func slices(parent ParentOrder) int64 {
+	interval := parent.Interval.Milliseconds()
+	n := (parent.End - parent.Start + interval - 1) / interval
+	return max(n, 1)
+}
+
+// slice index of the slice now falls in, the first slice is sent at Start.
+func slice(parent ParentOrder, now int64) int64 {
+	if now < parent.Start {
+		return 0
+	}
+	k := (now-parent.Start)/parent.Interval.Milliseconds() + 1
+	return min(k, slices(parent))
+}
+
+type twap struct{}
+
+// TWAP equal slices every Interval between Start and End.
+func TWAP() Schedule {
+	return twap{}
+}
+
+func (twap) Target(parent ParentOrder, now int64) (decimal.Decimal, error) {
+	k := slice(parent, now)
+	return parent.Quantity.Mul(decimal.NewFromInt(k)).Div(decimal.NewFromInt(slices(parent))), nil
+}
+
+type vwap struct {
+	candles types.CandlesEntry
+	// cumulative share of the parent after each slice, built on the first Target
+	curve []decimal.Decimal
+}
+
+// VWAP slices weighted by the historical volume traded at the same time of day.
+// candles are fetched with GetCandles(symbol, interval, limit), interval in the exchange notation;
+// a history without volume falls back to equal slices.
+func VWAP(connector platforms.SpotConnector, symbol, interval string, limit int64) (Schedule, error) {
+	candles, err := connector.GetCandles(symbol, interval, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &vwap{candles: candles}, nil
+}
+
+func (s *vwap) build(parent ParentOrder) {
+	const day = int64(24 * time.Hour / time.Millisecond)
+	n := slices(parent)
+	step := parent.Interval.Milliseconds()
+	var weights = make([]float64, n)
+	var total float64
+	for _, candle := range s.candles {
+		// [start_timestamp, open, high, low, close, volume, (volume_usd)]
+		if len(candle) < 6 {
+			continue
+		}
+		start := int64(candle[0])
+		if start < 1e12 {
+			start *= 1000 // seconds
+		}
+		for k := int64(0); k < n; k++ {
+			from := (parent.Start + k*step) % day
+			offset := ((start % day) - from + day) % day
+			if offset < step {
+				weights[k] += candle[5]
+				total += candle[5]
+			}
+		}
+	}
+	var curve = make([]decimal.Decimal, n)
+	var cumulative float64
+	for k := range weights {
+		if total > 0 {
+			cumulative += weights[k] / total
+		} else {
+			cumulative += 1 / float64(n)
+		}
+		curve[k] = decimal.NewFromFloat(cumulative)
+	}
+	curve[n-1] = decimal.NewFromInt(1)
+	s.curve = curve
+}
+
+func (s *vwap) Target(parent ParentOrder, now int64) (decimal.Decimal, error) {
+	if s.curve == nil {
+		s.build(parent)
+	}
+	k := slice(parent, now)
+	if k == 0 {
+		return decimal.Zero, nil
+	}
+	return parent.Quantity.Mul(s.curve[k-1]), nil
+}
+
+type pov struct {
+	connector platforms.SpotConnector
+	// volume of every 1m candle since Start, by candle start
+	volumes map[int64]float64
+}
+
+// POV follow Participation of the market volume traded since Start.
+// The market volume is read from the latest 1m candles on every Target call,
+// so Interval should stay below a few minutes.
+func POV(connector platforms.SpotConnector) Schedule {
+	return &pov{connector: connector, volumes: make(map[int64]float64)}
+}
+
+// povCandles recent candles read on every poll
+const povCandles = 5
+
+func (s *pov) Target(parent ParentOrder, _ int64) (decimal.Decimal, error) {
+	candles, err := s.connector.GetCandles(parent.Symbol, "1m", povCandles)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	since := parent.Start - parent.Start%time.Minute.Milliseconds()
+	for _, candle := range candles {
+		if len(candle) < 6 {
+			continue
+		}
+		start := int64(candle[0])
+		if start < 1e12 {
+			start *= 1000
+		}
+		if start >= since {
+			// the current candle keeps growing, its latest volume replaces the earlier read
+			s.volumes[start] = max(s.volumes[start], candle[5])
+		}
+	}
+	var traded float64
+	for _, volume := range s.volumes {
+		traded += volume
+	}
+	return parent.Participation.Mul(decimal.NewFromFloat(traded)), nil
+}
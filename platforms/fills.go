@@ -0,0 +1,132 @@
+package platforms
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// FillMergeWait how long an order update reporting a fill waits for its trade, and a trade for its order update
+const FillMergeWait = time.Second
+
+// FillMerger one update per order event for venues pushing order state and trades on separate
+// channels, e.g. mexc deals, gate usertrades and bybit execution. An order update whose cumulative
+// quantity grew is held until a trade of the order arrives, at most FillMergeWait, then goes out
+// without fill detail. Trades pair with the fills of their order in arrival order,
+// a trade whose order update never comes is dropped.
+type FillMerger struct {
+	ctx     context.Context
+	channel chan<- types.OrderUpdateEntry
+	mux     sync.Mutex
+	filled  map[string]decimal.Decimal           // order id -> cumulative quantity reported
+	held    map[string][]*types.OrderUpdateEntry // order id -> fills waiting for their trade
+	trades  map[string][]*types.OrderUpdateEntry // order id -> trades waiting for their fill
+}
+
+// NewFillMerger send the merged updates to channel until ctx is done.
+func NewFillMerger(ctx context.Context, channel chan<- types.OrderUpdateEntry) *FillMerger {
+	return &FillMerger{
+		ctx:     ctx,
+		channel: channel,
+		filled:  make(map[string]decimal.Decimal),
+		held:    make(map[string][]*types.OrderUpdateEntry),
+		trades:  make(map[string][]*types.OrderUpdateEntry),
+	}
+}
+
+// OnOrder an update of the order channel, carrying status and cumulative quantity.
+func (m *FillMerger) OnOrder(entry types.OrderUpdateEntry) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	id := entry.OrderId
+	if !entry.FilledQuantity.GreaterThan(m.filled[id]) {
+		// nothing filled since, the fills before it go first
+		m.flush(id, nil)
+		m.emit(entry)
+		return
+	}
+	m.filled[id] = entry.FilledQuantity
+	if trades := m.trades[id]; len(trades) > 0 {
+		m.trades[id] = trades[1:]
+		fill(&entry, trades[0])
+		m.emit(entry)
+		return
+	}
+	held := &entry
+	m.held[id] = append(m.held[id], held)
+	time.AfterFunc(FillMergeWait, func() {
+		m.mux.Lock()
+		defer m.mux.Unlock()
+		m.flush(id, held)
+	})
+}
+
+// OnFill a trade of the trade channel, only OrderId and the last fill fields of trade are read:
+// LastFillPrice, LastFillQuantity, Fee, FeeAsset, IsMaker, TradeId and TransactionTime.
+func (m *FillMerger) OnFill(trade types.OrderUpdateEntry) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	id := trade.OrderId
+	if held := m.held[id]; len(held) > 0 {
+		m.held[id] = held[1:]
+		fill(held[0], &trade)
+		m.emit(*held[0])
+		return
+	}
+	m.trades[id] = append(m.trades[id], &trade)
+	time.AfterFunc(FillMergeWait, func() {
+		m.mux.Lock()
+		defer m.mux.Unlock()
+		trades := m.trades[id]
+		for i, waiting := range trades {
+			if waiting == &trade {
+				m.trades[id] = append(trades[:i:i], trades[i+1:]...)
+				break
+			}
+		}
+		if len(m.trades[id]) == 0 {
+			delete(m.trades, id)
+		}
+	})
+}
+
+// fill the fill detail of trade into the update of its order.
+func fill(entry *types.OrderUpdateEntry, trade *types.OrderUpdateEntry) {
+	entry.LastFillPrice, entry.LastFillQuantity = trade.LastFillPrice, trade.LastFillQuantity
+	entry.Fee, entry.IsMaker, entry.TradeId = trade.Fee, trade.IsMaker, trade.TradeId
+	if trade.FeeAsset != "" {
+		entry.FeeAsset = trade.FeeAsset
+	}
+	if trade.TransactionTime != 0 {
+		entry.TransactionTime = trade.TransactionTime
+	}
+}
+
+// flush emit the held fills of an order without their trade, up to until, or all of them for nil.
+func (m *FillMerger) flush(id string, until *types.OrderUpdateEntry) {
+	held := m.held[id]
+	count := len(held)
+	if until != nil {
+		// gone already when its trade arrived or a later update flushed it
+		count = slices.Index(held, until) + 1
+	}
+	for _, entry := range held[:count] {
+		m.emit(*entry)
+	}
+	if m.held[id] = held[count:]; len(m.held[id]) == 0 {
+		delete(m.held, id)
+	}
+}
+
+func (m *FillMerger) emit(entry types.OrderUpdateEntry) {
+	switch entry.Status {
+	case constants.Filled, constants.Canceled, constants.PartiallyCanceled, constants.Error:
+		delete(m.filled, entry.OrderId)
+	}
+	Deliver(m.ctx, m.channel, entry)
+}
@@ -21,8 +21,28 @@ func (c *Connector) Sign(params []byte) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
+// ErrorResponse https://www.gate.io/docs/developers/apiv4/#error-response
+type ErrorResponse struct {
+	Label   string `json:"label"`
+	Message string `json:"message"`
+}
+
+// errorKinds error labels with a unified meaning
+var errorKinds = map[string]error{
+	"ORDER_NOT_FOUND": platforms.ErrOrderNotFound,
+	"ORDER_CLOSED":    platforms.ErrOrderClosed,
+	"ORDER_CANCELLED": platforms.ErrOrderClosed,
+}
+
 // Call Reference https://www.gate.io/docs/developers/apiv4/#apiv4-signed-request-requirements
 func (c *Connector) Call(method string, route string, params platforms.Serializer, authType constants.AuthType, returnType interface{}) error {
+	return c.Invoke(&platforms.Invocation{Platform: c.Name(), Method: method, Route: route, AuthType: authType,
+		Params: params, Result: returnType}, c.Logged(c.call))
+}
+
+// call the request of one invocation, the middlewares of the connector run around it
+func (c *Connector) call(invocation *platforms.Invocation) error {
+	method, route, params, authType, returnType := invocation.Method, invocation.Route, invocation.Params, invocation.AuthType, invocation.Result
 	// Add necessary parameters
 	var body io.Reader
 	symbol, ok := params.Exists(SymbolFiled)
@@ -35,7 +55,8 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 	}
 	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
 	sha := sha512.New()
-	_, _ = io.Copy(sha, bodyData)
+	var payload = new(bytes.Buffer)
+	_, _ = io.Copy(io.MultiWriter(sha, payload), bodyData)
 	queryString := ""
 	switch method {
 	case http.MethodDelete, http.MethodGet:
@@ -45,7 +66,8 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 		}
 		queryString = query
 	case http.MethodPost:
-		body = bodyData
+		body = payload
+		invocation.RequestBody = payload.Bytes()
 	}
 	url := fmt.Sprintf("%s%s?%s", RestAPI, route, queryString)
 	req, err := http.NewRequest(method, url, body)
@@ -80,16 +102,23 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 	default:
 		// default None
 	}
+	invocation.Request = req
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return err
-	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("[Gate] Response %s", resp.Status)
+		return platforms.NetworkError(err)
 	}
+	defer resp.Body.Close()
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
+	invocation.StatusCode, invocation.Header, invocation.Response = resp.StatusCode, resp.Header, respBody
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		var errResp ErrorResponse
+		if utils.Json.Unmarshal(respBody, &errResp) != nil || errResp.Label == "" {
+			return fmt.Errorf("[Gate] Response %s", resp.Status)
+		}
+		return &platforms.ExchangeError{Exchange: "Gate", Code: errResp.Label, Message: errResp.Message, Err: errorKinds[errResp.Label]}
+	}
 	return utils.Json.Unmarshal(respBody, returnType)
 }
@@ -12,6 +12,8 @@ const (
 	TimestampHeader = "Timestamp"
 
 	SymbolFiled = "trading_pair"
+	// PairField the pair of an order request
+	PairField = "currency_pair"
 )
 
 const (
@@ -25,6 +27,14 @@ const (
 	BatchCancelEndpoint    = APIPrefix + "/spot/cancel_batch_orders"
 	OpenOrdersEndpoint     = APIPrefix + "/spot/open_orders"
 	SmallBalanceEndpoint   = APIPrefix + "/wallet/small_balance"
+	MyTradesEndpoint       = APIPrefix + "/spot/my_trades"
+	WalletFeeEndpoint      = APIPrefix + "/wallet/fee"
+	CountdownEndpoint      = APIPrefix + "/spot/countdown_cancel_all"
+)
+
+const (
+	QueryStatusOpen     = "open"
+	QueryStatusFinished = "finished"
 )
 
 type OrderStatus string
@@ -32,7 +42,7 @@ type OrderStatus string
 const (
 	OrderStatusOpen     OrderStatus = "open"
 	OrderStatusClosed   OrderStatus = "closed"
-	OrderStatusCanceled OrderStatus = "canceled"
+	OrderStatusCanceled OrderStatus = "cancelled"
 )
 
 func (o OrderStatus) String() string {
@@ -73,6 +83,18 @@ func (o OrderType) Convert() constants.OrderType {
 	}
 }
 
+// spot.orders event
+const (
+	OrderEventPut    = "put"
+	OrderEventUpdate = "update"
+	OrderEventFinish = "finish"
+)
+
+const (
+	RoleMaker = "maker"
+	RoleTaker = "taker"
+)
+
 const (
 	TimeInForceGTC = "gtc"
 	TimeInForceIOC = "ioc"
@@ -1,14 +1,18 @@
 package gate
 
 import (
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
 	"net/http"
 )
 
 type Connector struct {
 	*platforms.Credentials
 	Client *http.Client
+	platforms.Middlewares
+	platforms.Logging
 }
 
 func (c *Connector) Name() constants.Platform {
@@ -22,3 +26,41 @@ func (c *Connector) SymbolPattern(symbol string) string {
 func NewConnector(cred *platforms.Credentials, client *http.Client) platforms.SpotConnector {
 	return &Connector{Credentials: cred, Client: client}
 }
+
+type WalletFee struct {
+	UserId     int64  `json:"user_id"`
+	TakerFee   string `json:"taker_fee"`
+	MakerFee   string `json:"maker_fee"`
+	GtDiscount bool   `json:"gt_discount"`
+	GtTakerFee string `json:"gt_taker_fee"`
+	GtMakerFee string `json:"gt_maker_fee"`
+	LoanFee    string `json:"loan_fee"`
+	PointType  string `json:"point_type"`
+}
+
+func (c *Connector) GetFeeRates(symbols []string) (map[string]types.FeeRateEntry, error) {
+	var result = make(map[string]types.FeeRateEntry, len(symbols))
+	for _, symbol := range symbols {
+		var resp WalletFee
+		err := c.Call(http.MethodGet, WalletFeeEndpoint, &platforms.ObjectBody{
+			"currency_pair": c.SymbolPattern(symbol),
+		}, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		maker, _ := decimal.NewFromString(resp.MakerFee)
+		taker, _ := decimal.NewFromString(resp.TakerFee)
+		entry := types.FeeRateEntry{
+			Symbol: symbol,
+			Maker:  maker,
+			Taker:  taker,
+		}
+		if resp.GtDiscount {
+			entry.DiscountAsset = "GT"
+			entry.DiscountMaker, _ = decimal.NewFromString(resp.GtMakerFee)
+			entry.DiscountTaker, _ = decimal.NewFromString(resp.GtTakerFee)
+		}
+		result[symbol] = entry
+	}
+	return result, nil
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
 	"net/http"
 	"testing"
 	"time"
@@ -69,3 +72,61 @@ func TestGetCandles(t *testing.T) {
 	}
 	fmt.Println(candles)
 }
+
+func TestClientText(t *testing.T) {
+	id := newTradeNo()
+	if text := clientText(id); len(text) != 28 || tradeNo(text) != id {
+		t.Errorf("unexpected text %q of %q", text, id)
+	}
+	// a caller's id that happens to start with the prefix round-trips as well
+	if got := tradeNo(clientText("t-1")); got != "t-1" {
+		t.Errorf("unexpected trade no %q", got)
+	}
+}
+
+func TestWsOrderPayload(t *testing.T) {
+	protocol := &WsOrderProtocol{&Connector{}}
+	param := func(request map[string]any) map[string]any {
+		return request["payload"].(map[string]any)["req_param"].(map[string]any)
+	}
+	place := param(protocol.PlaceOrder("1", types.OrderEntry{Symbol: "BTCUSDT", TradeNo: "abc"}))
+	if place["currency_pair"] != "BTC_USDT" || place["text"] != "t-abc" {
+		t.Errorf("unexpected order_place param %v", place)
+	}
+	cancel := param(protocol.Cancel("2", "BTCUSDT", "42"))
+	if cancel["currency_pair"] != "BTC_USDT" || cancel["order_id"] != "42" {
+		t.Errorf("unexpected order_cancel param %v", cancel)
+	}
+	if _, ok := place[SymbolFiled]; ok {
+		t.Errorf("order_place param carries %s", SymbolFiled)
+	}
+}
+
+// gate spells the canceled status "cancelled" and QueryOrder reports millisecond times.
+func TestQueryOrderConvert(t *testing.T) {
+	var order Order
+	_ = utils.Json.UnmarshalFromString(`{"id":"1","currency_pair":"BTC_USDT","status":"cancelled","amount":"2","filled_amount":"0.5","create_time":"1700000000","create_time_ms":1700000000123,"update_time_ms":1700000001456}`, &order)
+	result := order.convert()
+	if result.Status != constants.Canceled {
+		t.Errorf("want canceled, got %d", result.Status)
+	}
+	if result.CreateTime != 1700000000123 || result.UpdateTime != 1700000001456 {
+		t.Errorf("unexpected times %d, %d", result.CreateTime, result.UpdateTime)
+	}
+	if !result.Filled.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("unexpected filled %s", result.Filled)
+	}
+}
+
+// spot.usertrades carries the fill detail spot.orders lacks.
+func TestUserTradeEntry(t *testing.T) {
+	var trade UserTrade
+	_ = utils.Json.UnmarshalFromString(`{"id":5736713,"order_id":"30784428","currency_pair":"BTC_USDT","create_time_ms":"1605176741123.456","side":"buy","amount":"0.5","role":"maker","price":"100","fee":"0.001","fee_currency":"BTC","text":"t-abc"}`, &trade)
+	entry := trade.entry()
+	if entry.OrderId != "30784428" || entry.TradeId != "5736713" || !entry.IsMaker || entry.TransactionTime != 1605176741123 {
+		t.Errorf("unexpected trade %+v", entry)
+	}
+	if !entry.LastFillPrice.Equal(decimal.NewFromInt(100)) || !entry.LastFillQuantity.Equal(decimal.NewFromFloat(0.5)) || entry.FeeAsset != "BTC" {
+		t.Errorf("unexpected fill detail %+v", entry)
+	}
+}
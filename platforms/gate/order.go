@@ -1,10 +1,15 @@
 package gate
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/xavierzho/go-cexs/platforms"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -51,13 +56,19 @@ type Order struct {
 	StpID              int    `json:"stp_id,omitempty"`
 	OrderId            string `json:"order_id,omitempty"`
 	Role               string `json:"role,omitempty"`
+	Succeeded          *bool  `json:"succeeded,omitempty"` // only returned by batch orders
+	Label              string `json:"label,omitempty"`     // error label of a failed batch order
+	Message            string `json:"message,omitempty"`   // error message of a failed batch order
 }
 
 func (c *Connector) PlaceOrder(params types.OrderEntry) (string, error) {
 	var resp Order
+	if params.TradeNo == "" {
+		params.TradeNo = newTradeNo()
+	}
 	var param = &platforms.ObjectBody{
 		SymbolFiled:     params.Symbol,
-		"text":          fmt.Sprintf("t-%s", uuid.New().String()),
+		"text":          clientText(params.TradeNo),
 		"type":          c.MatchOrderType(params.Type),
 		"side":          strings.ToLower(params.Side),
 		"amount":        params.Quantity.StringFixed(10),
@@ -84,29 +95,89 @@ func (c *Connector) MatchOrderType(orderType constants.OrderType) OrderType {
 		return OrderTypeMarket
 	}
 }
-func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]string, error) {
-	var params platforms.ArrayBody
-	var result []string
-	var resp []Order
-	for _, order := range orders {
-		params = append(params, map[string]any{
-			SymbolFiled:     order.Symbol,
-			"text":          fmt.Sprintf("t-%s", uuid.New().String()),
+
+// clientText the text of an order placed with tradeNo, gate requires the "t-" prefix
+// and keeps 28 bytes at most.
+func clientText(tradeNo string) string {
+	return "t-" + tradeNo
+}
+
+// tradeNo the client order id of an order text, the reverse of clientText.
+func tradeNo(text string) string {
+	return strings.TrimPrefix(text, "t-")
+}
+
+// newTradeNo a random client order id that fits the text once prefixed.
+func newTradeNo() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")[:26]
+}
+
+// batchOrderLimit maximum orders per batch request
+const batchOrderLimit = 10
+
+// PlaceOrderList gate spot has no order lists, see platforms.OrderListEmulator.
+func (c *Connector) PlaceOrderList(types.OrderListEntry) (types.OrderListResult, error) {
+	return types.OrderListResult{}, platforms.ErrNotSupported
+}
+
+// BatchOrder https://www.gate.io/docs/developers/apiv4/#create-a-batch-of-orders
+func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	var results = make([]types.BatchOrderResult, len(orders))
+	var params = make([]map[string]any, len(orders))
+	for i, order := range orders {
+		if order.TradeNo == "" {
+			order.TradeNo = newTradeNo()
+		}
+		results[i].TradeNo = order.TradeNo
+		params[i] = map[string]any{
+			PairField:       c.SymbolPattern(order.Symbol),
+			"text":          clientText(order.TradeNo),
 			"type":          c.MatchOrderType(order.Type),
 			"side":          strings.ToLower(order.Side),
 			"amount":        order.Quantity.StringFixed(10),
 			"price":         order.Price.StringFixed(10),
 			"time_in_force": TimeInForceGTC,
-		})
-	}
-	err := c.Call(http.MethodPost, BatchOrdersEndpoint, &params, constants.Signed, &resp)
-	if err != nil {
-		return nil, err
+		}
 	}
-	for _, r := range resp {
-		result = append(result, r.OrderId)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	for _, chunk := range platforms.ChunkOrders(orders, batchOrderLimit, false) {
+		wg.Add(1)
+		go func(chunk []int) {
+			defer wg.Done()
+			var batch = make(platforms.ArrayBody, len(chunk))
+			for j, index := range chunk {
+				batch[j] = params[index]
+			}
+			var resp []Order
+			err := c.Call(http.MethodPost, BatchOrdersEndpoint, &batch, constants.Signed, &resp)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				for _, index := range chunk {
+					results[index].Message = err.Error()
+				}
+				return
+			}
+			// response is in request order
+			for j, r := range resp {
+				if j >= len(chunk) {
+					break
+				}
+				index := chunk[j]
+				if r.Succeeded != nil && !*r.Succeeded {
+					results[index].Code = r.Label
+					results[index].Message = r.Message
+					continue
+				}
+				results[index].OrderId = r.ID
+			}
+		}(chunk)
 	}
-	return result, nil
+	wg.Wait()
+	return results, errors.Join(errs...)
 }
 
 func (c *Connector) queryOrder(symbol string, orderId string) (Order, error) {
@@ -124,9 +195,17 @@ func (c *Connector) QueryOrder(symbol string, orderId string) (types.QueryOrder,
 	if err != nil {
 		return types.QueryOrder{}, err
 	}
+	result := order.convert()
+	result.Symbol = symbol
+	result.OrderId = orderId
+	return result, nil
+}
+
+func (order Order) convert() types.QueryOrder {
 	price, _ := decimal.NewFromString(order.Price)
 	amount, _ := decimal.NewFromString(order.Amount)
 	filled, _ := decimal.NewFromString(order.FilledAmount)
+	symbol, _ := constants.StandardizeSymbol(order.CurrencyPair)
 	return types.QueryOrder{
 		Symbol:     symbol,
 		Type:       OrderType(order.Type).Convert(),
@@ -135,11 +214,45 @@ func (c *Connector) QueryOrder(symbol string, orderId string) (types.QueryOrder,
 		Price:      price,
 		Quantity:   amount,
 		Filled:     filled,
-		CreateTime: int64(order.CreateTimeMs / 1000),
-		UpdateTime: int64(order.UpdateTimeMs / 1000),
-		OrderId:    orderId,
-		TradeNo:    order.Text,
-	}, nil
+		CreateTime: int64(order.CreateTimeMs),
+		UpdateTime: int64(order.UpdateTimeMs),
+		OrderId:    order.ID,
+		TradeNo:    tradeNo(order.Text),
+	}
+}
+
+const orderHistoryLimit = 100
+
+func (c *Connector) OrderHistory(ctx context.Context, symbol string, since, until int64, statuses []constants.OrderStatus) ([]types.QueryOrder, error) {
+	var result []types.QueryOrder
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var resp []Order
+		err := c.Call(http.MethodGet, OrderEndpoint, &platforms.ObjectBody{
+			"currency_pair": c.SymbolPattern(symbol),
+			"status":        QueryStatusFinished,
+			"from":          since / 1000,
+			"to":            until / 1000,
+			"limit":         orderHistoryLimit,
+			"page":          page,
+		}, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range resp {
+			order := o.convert()
+			if len(statuses) > 0 && !slices.Contains(statuses, order.Status) {
+				continue
+			}
+			result = append(result, order)
+		}
+		if len(resp) < orderHistoryLimit {
+			break
+		}
+	}
+	return result, nil
 }
 func (c *Connector) GetOrderStatus(symbol string, orderId string) (constants.OrderStatus, error) {
 	order, err := c.queryOrder(symbol, orderId)
@@ -157,7 +270,7 @@ func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return resp.Status == OrderStatusClosed.String(), nil
+	return resp.Status == OrderStatusCanceled.String(), nil
 }
 
 func (c *Connector) CancelAll(symbol string) error {
@@ -167,6 +280,17 @@ func (c *Connector) CancelAll(symbol string) error {
 	}, constants.Signed, &resp)
 }
 
+// CancelAllAfter cancel the orders of every pair once timeout expires, 0 disarms the countdown.
+// https://www.gate.io/docs/developers/apiv4/#countdown-cancel-orders
+func (c *Connector) CancelAllAfter(timeout time.Duration) error {
+	var resp struct {
+		TriggerTime int64 `json:"triggerTime"`
+	}
+	return c.Call(http.MethodPost, CountdownEndpoint, &platforms.ObjectBody{
+		"timeout": int64(timeout / time.Second),
+	}, constants.Signed, &resp)
+}
+
 type CancelById struct {
 	CurrencyPair string `json:"currency_pair"`
 	ID           string `json:"id"`
@@ -176,27 +300,74 @@ type CancelById struct {
 	Succeeded    bool   `json:"succeeded"`
 }
 
-func (c *Connector) CancelByIds(symbol string, orderIds []string) (map[string]bool, error) {
-	var body []map[string]any
+// cancelBatchLimit maximum orders per cancel_batch_orders request
+const cancelBatchLimit = 20
 
-	for _, id := range orderIds {
-		body = append(body, map[string]any{
-			SymbolFiled: symbol,
-			"order_id":  id,
-		})
+// CancelByIds https://www.gate.io/docs/developers/apiv4/#cancel-a-batch-of-orders-with-an-id-list
+func (c *Connector) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	var result = make([]types.CancelResult, len(orderIds))
+	for start := 0; start < len(orderIds); start += cancelBatchLimit {
+		end := min(start+cancelBatchLimit, len(orderIds))
+		var body platforms.ArrayBody
+		for _, id := range orderIds[start:end] {
+			body = append(body, map[string]any{
+				SymbolFiled: c.SymbolPattern(symbol),
+				"id":        id,
+			})
+		}
+		var resp []CancelById
+		err := c.Call(http.MethodPost, BatchCancelEndpoint, &body, constants.Signed, &resp)
+		var outcomes = make(map[string]CancelById, len(resp))
+		for _, cancel := range resp {
+			outcomes[cancel.ID] = cancel
+		}
+		for j, id := range orderIds[start:end] {
+			index := start + j
+			result[index].OrderId = id
+			if err != nil {
+				result[index].Err = err
+				continue
+			}
+			cancel, ok := outcomes[id]
+			if !ok {
+				result[index].Err = &platforms.ExchangeError{Exchange: "Gate", Message: "missing from cancel response"}
+				continue
+			}
+			if !cancel.Succeeded {
+				result[index].Err = &platforms.ExchangeError{Exchange: "Gate", Code: cancel.Label, Message: cancel.Message, Err: errorKinds[cancel.Label]}
+			}
+		}
 	}
-	var resp []CancelById
-	err := c.Call(http.MethodPost, BatchCancelEndpoint, &platforms.ObjectBody{
-		"orders": body,
-	}, constants.Signed, &resp)
-	if err != nil {
-		return nil, err
+	return result, nil
+}
+
+// CancelAllSymbols open orders are listed per currency pair, then each pair is canceled.
+func (c *Connector) CancelAllSymbols() error {
+	// collect every pair before canceling, canceled pairs would shift the pages
+	var pairs []string
+	for page := 1; ; page++ {
+		var resp []PendingOrder
+		err := c.Call(http.MethodGet, OpenOrdersEndpoint, &platforms.ObjectBody{
+			"page":  page,
+			"limit": 100,
+		}, constants.Signed, &resp)
+		if err != nil {
+			return err
+		}
+		for _, pair := range resp {
+			pairs = append(pairs, pair.Symbol)
+		}
+		if len(resp) < 100 {
+			break
+		}
 	}
-	var result = make(map[string]bool)
-	for _, cancel := range resp {
-		result[cancel.ID] = cancel.Succeeded
+	var errs []error
+	for _, pair := range pairs {
+		if err := c.CancelAll(pair); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return result, nil
+	return errors.Join(errs...)
 }
 
 type PendingOrder struct {
@@ -256,3 +427,66 @@ func (c *Connector) Balance(symbols []string) (map[string]types.BalanceEntry, er
 	}
 	return result, nil
 }
+
+type MyTrade struct {
+	ID           string `json:"id"`
+	CreateTime   string `json:"create_time"`
+	CreateTimeMs string `json:"create_time_ms"`
+	CurrencyPair string `json:"currency_pair"`
+	Side         string `json:"side"`
+	Role         string `json:"role"`
+	Amount       string `json:"amount"`
+	Price        string `json:"price"`
+	OrderId      string `json:"order_id"`
+	Fee          string `json:"fee"`
+	FeeCurrency  string `json:"fee_currency"`
+	PointFee     string `json:"point_fee"`
+	GtFee        string `json:"gt_fee"`
+	Text         string `json:"text"`
+}
+
+const myTradesLimit = 1000
+
+func (c *Connector) GetMyTrades(ctx context.Context, symbol string, since, until int64) ([]types.FillEntry, error) {
+	var result []types.FillEntry
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var resp []MyTrade
+		err := c.Call(http.MethodGet, MyTradesEndpoint, &platforms.ObjectBody{
+			"currency_pair": c.SymbolPattern(symbol),
+			"from":          since / 1000,
+			"to":            until / 1000,
+			"limit":         myTradesLimit,
+			"page":          page,
+		}, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, trade := range resp {
+			price, _ := decimal.NewFromString(trade.Price)
+			qty, _ := decimal.NewFromString(trade.Amount)
+			fee, _ := decimal.NewFromString(trade.Fee)
+			ts, _ := decimal.NewFromString(trade.CreateTimeMs)
+			symbol, _ := constants.StandardizeSymbol(trade.CurrencyPair)
+			result = append(result, types.FillEntry{
+				Symbol:      symbol,
+				TradeId:     trade.ID,
+				OrderId:     trade.OrderId,
+				TradeNo:     tradeNo(trade.Text),
+				Side:        strings.ToUpper(trade.Side),
+				Price:       price,
+				Quantity:    qty,
+				Fee:         fee,
+				FeeCurrency: trade.FeeCurrency,
+				IsMaker:     trade.Role == RoleMaker,
+				Timestamp:   ts.IntPart(),
+			})
+		}
+		if len(resp) < myTradesLimit {
+			break
+		}
+	}
+	return result, nil
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
-	"github.com/xavierzho/go-cexs/utils"
 	"time"
 )
 
@@ -61,7 +60,7 @@ func (m *MarketStream) DepthStream(ctx context.Context, symbol string, channel c
 					continue
 				}
 				var event Event[DepthUpdate]
-				err = utils.Json.Unmarshal(msg, &event)
+				err = m.Decode(msg, &event)
 				if err != nil {
 					continue
 				}
@@ -118,7 +117,7 @@ func (m *MarketStream) CandleStream(ctx context.Context, symbol, interval string
 					continue
 				}
 				var event Event[CandleUpdate]
-				err = utils.Json.Unmarshal(msg, &event)
+				err = m.Decode(msg, &event)
 				if err != nil {
 					continue
 				}
@@ -7,16 +7,28 @@ import (
 	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
-	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
 	"github.com/xavierzho/go-cexs/utils"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 type UserDataStream struct {
 	*platforms.Credentials
 	*platforms.StreamBase
+	dispatcher    *platforms.Dispatcher
+	mux           sync.Mutex
+	subscriptions []subscription // replayed after reconnecting
+}
+
+type subscription struct {
+	channel string
+	payload []string
 }
 
 func (u *UserDataStream) Login() error {
@@ -26,6 +38,71 @@ func (u *UserDataStream) Login() error {
 	}
 	return nil
 }
+
+// Reconnect dial again and replay every subscription, each signed anew. The acks are left to the reader.
+func (u *UserDataStream) Reconnect() error {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	if err := u.Login(); err != nil {
+		return err
+	}
+	for _, sub := range u.subscriptions {
+		if err := u.WriteJSON(u.subscribePayload(u.dispatcher.NextId(), sub)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribePayload private channels are authenticated per subscription.
+func (u *UserDataStream) subscribePayload(id string, sub subscription) map[string]any {
+	const event = "subscribe"
+	var timestamp = time.Now().Unix()
+	requestId, _ := strconv.ParseInt(id, 10, 64)
+	payload := map[string]any{
+		"id":      requestId,
+		"time":    timestamp,
+		"channel": sub.channel,
+		"event":   event,
+		"auth":    u.sign(sub.channel, event, timestamp),
+	}
+	if sub.payload != nil {
+		payload["payload"] = sub.payload
+	}
+	return payload
+}
+
+// route channel of a push, replies carry the event of their request instead.
+func route(msg []byte) string {
+	if utils.Json.Get(msg, "event").ToString() != OrderEventUpdate {
+		return ""
+	}
+	return utils.Json.Get(msg, "channel").ToString()
+}
+
+// replyId gate echoes the id of a request in its reply.
+func replyId(msg []byte) (string, bool) {
+	id := utils.Json.Get(msg, "id").ToString()
+	return id, id != "" && utils.Json.Get(msg, "event").ToString() != OrderEventUpdate
+}
+
+// subscribe channel once for all the streams reading it, handle receives its pushes until ctx is done.
+func (u *UserDataStream) subscribe(ctx context.Context, sub subscription, handle func(msg []byte)) error {
+	return u.dispatcher.Subscribe(ctx, sub.channel, func() error {
+		id := u.dispatcher.NextId()
+		reply, err := u.dispatcher.Request(id, u.subscribePayload(id, sub))
+		if err != nil {
+			return err
+		}
+		if message := utils.Json.Get(reply, "error", "message").ToString(); message != "" {
+			return fmt.Errorf("[Gate stream] subscribe %s failed: %s", sub.channel, message)
+		}
+		u.mux.Lock()
+		u.subscriptions = append(u.subscriptions, sub)
+		u.mux.Unlock()
+		return nil
+	}, handle)
+}
 func (u *UserDataStream) sign(channel, event string, timestamp int64) map[string]any {
 	buf := bytes.NewBufferString(fmt.Sprintf("channel=%s&event=%s&timestamp=%d", channel, event, timestamp))
 	mac := hmac.New(sha512.New, []byte(u.APISecret))
@@ -37,48 +114,147 @@ func (u *UserDataStream) sign(channel, event string, timestamp int64) map[string
 	}
 }
 
-type OrderUpdate []Order
+// OrderUpdate spot.orders payload, timestamps are strings unlike the rest api.
+type OrderUpdate struct {
+	ID           string `json:"id"`
+	Text         string `json:"text"`
+	CurrencyPair string `json:"currency_pair"`
+	Type         string `json:"type"`
+	Side         string `json:"side"`
+	Amount       string `json:"amount"`
+	Price        string `json:"price"`
+	Left         string `json:"left"`
+	FilledTotal  string `json:"filled_total"`
+	Fee          string `json:"fee"`
+	FeeCurrency  string `json:"fee_currency"`
+	CreateTimeMs string `json:"create_time_ms"`
+	UpdateTimeMs string `json:"update_time_ms"`
+	Event        string `json:"event"`
+	FinishAs     string `json:"finish_as"`
+}
+
+// status spot.orders reports the lifecycle through event and finish_as instead of status.
+func (o OrderUpdate) status(filled decimal.Decimal) constants.OrderStatus {
+	switch o.Event {
+	case OrderEventPut:
+		return constants.Open
+	case OrderEventUpdate:
+		return constants.PartiallyFilled
+	case OrderEventFinish:
+		if o.FinishAs == "filled" {
+			return constants.Filled
+		}
+		if filled.IsPositive() {
+			return constants.PartiallyCanceled
+		}
+		return constants.Canceled
+	default:
+		return constants.Error
+	}
+}
+
+// convert spot.orders carries no per-fill detail, the fill fields and the fee of the fill
+// come with spot.usertrades, see UserTrade.entry.
+func (o OrderUpdate) convert() types.OrderUpdateEntry {
+	price, _ := decimal.NewFromString(o.Price)
+	amount, _ := decimal.NewFromString(o.Amount)
+	left, _ := decimal.NewFromString(o.Left)
+	filledTotal, _ := decimal.NewFromString(o.FilledTotal)
+	// millisecond timestamps come with a fractional part
+	updated, _ := decimal.NewFromString(o.UpdateTimeMs)
+	symbol, _ := constants.StandardizeSymbol(o.CurrencyPair)
+	filled := amount.Sub(left)
+	return types.OrderUpdateEntry{
+		OrderId:         o.ID,
+		ClientOrderId:   tradeNo(o.Text),
+		Status:          o.status(filled),
+		Symbol:          symbol,
+		Side:            strings.ToUpper(o.Side),
+		Type:            OrderType(o.Type).Convert(),
+		Price:           price,
+		Quantity:        amount,
+		FilledQuantity:  filled,
+		FilledQuote:     filledTotal,
+		FeeAsset:        o.FeeCurrency,
+		RejectReason:    o.rejectReason(),
+		TransactionTime: updated.IntPart(),
+	}
+}
+
+// rejectReason finish_as values other than filled/cancelled explain why the order was closed.
+func (o OrderUpdate) rejectReason() string {
+	if o.Event != OrderEventFinish {
+		return ""
+	}
+	switch o.FinishAs {
+	case "filled", "cancelled", "open":
+		return ""
+	default:
+		return o.FinishAs
+	}
+}
+
+// UserTrade spot.usertrades payload, one fill of the account.
+type UserTrade struct {
+	ID           int64  `json:"id"`
+	OrderId      string `json:"order_id"`
+	CurrencyPair string `json:"currency_pair"`
+	CreateTimeMs string `json:"create_time_ms"`
+	Side         string `json:"side"`
+	Amount       string `json:"amount"`
+	Role         string `json:"role"`
+	Price        string `json:"price"`
+	Fee          string `json:"fee"`
+	FeeCurrency  string `json:"fee_currency"`
+	Text         string `json:"text"`
+}
+
+// entry the fill detail of a trade, merged into the update of its order.
+func (t UserTrade) entry() types.OrderUpdateEntry {
+	price, _ := decimal.NewFromString(t.Price)
+	amount, _ := decimal.NewFromString(t.Amount)
+	fee, _ := decimal.NewFromString(t.Fee)
+	// millisecond timestamps come with a fractional part
+	created, _ := decimal.NewFromString(t.CreateTimeMs)
+	return types.OrderUpdateEntry{
+		OrderId:          t.OrderId,
+		LastFillPrice:    price,
+		LastFillQuantity: amount,
+		Fee:              fee,
+		FeeAsset:         t.FeeCurrency,
+		IsMaker:          t.Role == "maker",
+		TradeId:          strconv.FormatInt(t.ID, 10),
+		TransactionTime:  created.IntPart(),
+	}
+}
 
+// OrderStream joins spot.orders, carrying status and cumulative quantity,
+// with spot.usertrades, carrying price, quantity and fee of every fill.
 func (u *UserDataStream) OrderStream(ctx context.Context, channels chan<- types.OrderUpdateEntry) error {
-	const channel = "spot.usertrades"
-	const event = "subscribe"
-	var timestamp = time.Now().Unix()
-	err := u.SendMessage(map[string]any{
-		"id":      uuid.New().ID(),
-		"time":    timestamp,
-		"channel": channel,
-		"event":   event,
-		"auth":    u.sign(channel, event, timestamp),
+	merger := platforms.NewFillMerger(ctx, channels)
+	err := u.subscribe(ctx, subscription{channel: "spot.orders", payload: []string{"!all"}}, func(msg []byte) {
+		var event Event[[]OrderUpdate]
+		if err := u.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, order := range event.Result {
+			entry := order.convert()
+			entry.EventTime = event.TimeMs
+			merger.OnOrder(entry)
+		}
 	})
 	if err != nil {
 		return err
 	}
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				u.Close()
-				return
-			default:
-				msg, err := u.ReadMessage()
-				if err != nil {
-					continue
-				}
-				var event Event[OrderUpdate]
-				_ = utils.Json.Unmarshal(msg, &event)
-
-				for _, order := range event.Result {
-					channels <- types.OrderUpdateEntry{
-						OrderId:       order.ID,
-						Status:        OrderStatus(order.Status).Convert(),
-						ClientOrderId: order.Text,
-					}
-				}
-			}
+	return u.subscribe(ctx, subscription{channel: "spot.usertrades", payload: []string{"!all"}}, func(msg []byte) {
+		var event Event[[]UserTrade]
+		if err := u.Decode(msg, &event); err != nil {
+			return
 		}
-	}()
-	return nil
+		for _, trade := range event.Result {
+			merger.OnFill(trade.entry())
+		}
+	})
 }
 
 type BalanceUpdate struct {
@@ -94,53 +270,77 @@ type BalanceUpdate struct {
 	Timestamp    string `json:"timestamp"`
 }
 
-func (u *UserDataStream) BalanceStream(ctx context.Context, channels chan<- types.BalanceUpdateEntry) error {
-	const channel = "spot.balances"
-	const event = "subscribe"
-	var timestamp = time.Now().Unix()
-	err := u.SendMessage(map[string]any{
-		"id":      uuid.New().ID(),
-		"time":    timestamp,
-		"channel": channel,
-		"event":   event,
-		"auth":    u.sign(channel, event, timestamp),
+// isTradeChange change_type of order lifecycle and trade fee, the rest are fund transfers.
+func (b BalanceUpdate) isTradeChange() bool {
+	return strings.HasPrefix(b.ChangeType, "order") || strings.HasPrefix(b.ChangeType, "fee")
+}
+
+func (b BalanceUpdate) values() (free, locked, delta decimal.Decimal, timestamp int64) {
+	free, _ = decimal.NewFromString(b.Available)
+	locked, _ = decimal.NewFromString(b.Freeze)
+	delta, _ = decimal.NewFromString(b.Change)
+	timestamp, _ = strconv.ParseInt(b.TimestampMs, 10, 64)
+	return
+}
+
+// balances read spot.balances, both balance and account streams are split from it by change_type.
+func (u *UserDataStream) balances(ctx context.Context, handle func(update BalanceUpdate)) error {
+	return u.subscribe(ctx, subscription{channel: "spot.balances"}, func(msg []byte) {
+		var event Event[[]BalanceUpdate]
+		if err := u.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, update := range event.Result {
+			handle(update)
+		}
 	})
-	if err != nil {
-		return err
-	}
+}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				u.Close()
-				return
-			default:
-				msg, err := u.ReadMessage()
-				if err != nil {
-					continue
-				}
-				var event Event[[]BalanceUpdate]
-				_ = utils.Json.Unmarshal(msg, &event)
-
-				for _, update := range event.Result {
-					channels <- types.BalanceUpdateEntry{}
-					fmt.Println(update)
-				}
-
-			}
+func (u *UserDataStream) BalanceStream(ctx context.Context, channels chan<- types.BalanceUpdateEntry) error {
+	return u.balances(ctx, func(update BalanceUpdate) {
+		if update.isTradeChange() {
+			return
 		}
-	}()
-	return nil
+		free, locked, delta, timestamp := update.values()
+		if !platforms.Deliver(ctx, channels, types.BalanceUpdateEntry{
+			Asset:     update.Currency,
+			Free:      free,
+			Locked:    locked,
+			Delta:     delta,
+			Reason:    update.ChangeType,
+			Timestamp: timestamp,
+		}) {
+			return
+		}
+	})
 }
 
 func (u *UserDataStream) AccountStream(ctx context.Context, channels chan<- types.AccountUpdateEntry) error {
-	return fmt.Errorf("not support stream")
+	return u.balances(ctx, func(update BalanceUpdate) {
+		if !update.isTradeChange() {
+			return
+		}
+		free, locked, delta, timestamp := update.values()
+		if !platforms.Deliver(ctx, channels, types.AccountUpdateEntry{
+			Asset:     update.Currency,
+			Free:      free,
+			Locked:    locked,
+			Delta:     delta,
+			Reason:    update.ChangeType,
+			Timestamp: timestamp,
+		}) {
+			return
+		}
+	})
 }
 
 func NewUserStream(cred *platforms.Credentials) platforms.UserDataStreamer {
-	return &UserDataStream{
+	stream := &UserDataStream{
 		StreamBase:  platforms.NewStream(),
 		Credentials: cred,
 	}
+	stream.dispatcher = platforms.NewDispatcher(stream.StreamBase, route)
+	stream.dispatcher.ReplyId = replyId
+	stream.dispatcher.Recover = stream.Reconnect
+	return stream
 }
@@ -0,0 +1,135 @@
+package gate
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
+)
+
+// WsOrderProtocol order entry over the websocket api.
+// https://www.gate.io/docs/developers/apiv4/ws/en/#websocket-api
+type WsOrderProtocol struct {
+	*Connector
+}
+
+func NewWsTrader(cred *platforms.Credentials, client *http.Client) *platforms.WsTrader {
+	connector := &Connector{Credentials: cred, Client: client}
+	return platforms.NewWsTrader(connector, &WsOrderProtocol{connector})
+}
+
+func (p *WsOrderProtocol) URL() string {
+	return StreamAPI
+}
+
+func (p *WsOrderProtocol) Login(stream *platforms.StreamBase) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	data, err := stream.Request(map[string]any{
+		"time":    time.Now().Unix(),
+		"channel": "spot.login",
+		"event":   "api",
+		"payload": map[string]any{
+			"api_key":   p.APIKey,
+			"signature": p.Sign([]byte(fmt.Sprintf("api\nspot.login\n\n%s", timestamp))),
+			"timestamp": timestamp,
+			"req_id":    uuid.New().String(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = p.result(data)
+	return err
+}
+
+func (p *WsOrderProtocol) Ping() string {
+	return `{"channel":"spot.ping"}`
+}
+
+func (p *WsOrderProtocol) request(id, channel string, param map[string]any) map[string]any {
+	return map[string]any{
+		"time":    time.Now().Unix(),
+		"channel": channel,
+		"event":   "api",
+		"payload": map[string]any{
+			"req_id":    id,
+			"req_param": param,
+		},
+	}
+}
+
+func (p *WsOrderProtocol) PlaceOrder(id string, order types.OrderEntry) map[string]any {
+	if order.TradeNo == "" {
+		order.TradeNo = newTradeNo()
+	}
+	param := map[string]any{
+		"text":          clientText(order.TradeNo),
+		PairField:       p.SymbolPattern(order.Symbol),
+		"type":          p.MatchOrderType(order.Type),
+		"account":       "spot",
+		"side":          strings.ToLower(order.Side),
+		"amount":        order.Quantity.StringFixed(10),
+		"price":         order.Price.StringFixed(10),
+		"time_in_force": TimeInForceGTC,
+	}
+	if order.Type == constants.Iceberg {
+		param["iceberg"] = order.Quantity.StringFixed(10)
+	}
+	return p.request(id, "spot.order_place", param)
+}
+
+func (p *WsOrderProtocol) Cancel(id, symbol, orderId string) map[string]any {
+	return p.request(id, "spot.order_cancel", map[string]any{
+		"order_id": orderId,
+		PairField:  p.SymbolPattern(symbol),
+	})
+}
+
+// WsReply gate acknowledges a request first, the reply with ack false carries the result.
+type WsReply struct {
+	RequestId string `json:"request_id"`
+	Ack       bool   `json:"ack"`
+	Header    struct {
+		Status string `json:"status"`
+	} `json:"header"`
+	Data struct {
+		Result Order         `json:"result"`
+		Errs   ErrorResponse `json:"errs"`
+	} `json:"data"`
+}
+
+func (p *WsOrderProtocol) ReplyId(msg []byte) (string, bool) {
+	var reply WsReply
+	if err := utils.Json.Unmarshal(msg, &reply); err != nil || reply.RequestId == "" || reply.Ack {
+		return "", false
+	}
+	return reply.RequestId, true
+}
+
+func (p *WsOrderProtocol) result(msg []byte) (Order, error) {
+	var reply WsReply
+	if err := utils.Json.Unmarshal(msg, &reply); err != nil {
+		return Order{}, err
+	}
+	if reply.Header.Status != "200" {
+		return Order{}, &platforms.ExchangeError{
+			Exchange: "Gate",
+			Code:     reply.Data.Errs.Label,
+			Message:  reply.Data.Errs.Message,
+			Err:      errorKinds[reply.Data.Errs.Label],
+		}
+	}
+	return reply.Data.Result, nil
+}
+
+func (p *WsOrderProtocol) OrderId(msg []byte) (string, error) {
+	order, err := p.result(msg)
+	return order.ID, err
+}
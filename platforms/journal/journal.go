@@ -0,0 +1,452 @@
+// Package journal append-only record of the orders sent through the Trade interface,
+// replayed after a restart to find orphaned orders and missed fills.
+package journal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
+)
+
+// Kind of a journal record.
+type Kind string
+
+const (
+	// Intent written before an order is sent
+	Intent Kind = "INTENT"
+	// Ack the exchange accepted the order
+	Ack Kind = "ACK"
+	// Reject the exchange refused the order or the request failed before it was sent
+	Reject Kind = "REJECT"
+	// Unknown the request failed in transit, the order may exist; Recover looks it up by client id
+	Unknown Kind = "UNKNOWN"
+	// Fill an execution, Quantity and Price of the fill
+	Fill Kind = "FILL"
+	// CancelIntent written before a cancel is sent, OrderId empty for all orders of Symbol
+	CancelIntent Kind = "CANCEL_INTENT"
+	// Canceled the exchange accepted the cancel
+	Canceled Kind = "CANCELED"
+	// CancelFailed the cancel was refused or the request failed
+	CancelFailed Kind = "CANCEL_FAILED"
+)
+
+// Record one journal entry.
+type Record struct {
+	Seq   uint64             `json:"seq"`
+	Kind  Kind               `json:"kind"`
+	Venue constants.Platform `json:"venue"`
+	// IntentId links the Ack, Reject or Unknown to its Intent
+	IntentId string            `json:"intent_id,omitempty"`
+	OrderId  string            `json:"order_id,omitempty"`
+	Symbol   string            `json:"symbol,omitempty"`
+	Order    *types.OrderEntry `json:"order,omitempty"`
+	// List the order list an Intent sent, its legs are acknowledged one by one
+	List     *types.OrderListEntry `json:"list,omitempty"`
+	Quantity decimal.Decimal       `json:"quantity,omitempty"`
+	Price    decimal.Decimal       `json:"price,omitempty"`
+	// Reason why the order was sent, given by the caller
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Timestamp in milliseconds
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Backend durable storage of the records. An embedded database such as bbolt or SQLite
+// fits behind it as well; FileBackend is the one shipped.
+type Backend interface {
+	// Append store record durably before returning.
+	Append(record Record) error
+	// Replay call fn with every record in append order.
+	Replay(fn func(Record) error) error
+	Close() error
+}
+
+// FileBackend JSON lines file, synced after every record.
+type FileBackend struct {
+	mux  sync.Mutex
+	file *os.File
+}
+
+func NewFileBackend(path string) (*FileBackend, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err = truncateTorn(file); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &FileBackend{file: file}, nil
+}
+
+// truncateTorn drop a last line without newline, left by a crash during a write,
+// so the next record does not continue it.
+func truncateTorn(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	var chunk = make([]byte, 4096)
+	end := info.Size()
+	for offset := end; offset > 0; {
+		size := min(int64(len(chunk)), offset)
+		offset -= size
+		if _, err = file.ReadAt(chunk[:size], offset); err != nil {
+			return err
+		}
+		for i := size - 1; i >= 0; i-- {
+			if chunk[i] == '\n' {
+				if offset+i+1 == end {
+					return nil
+				}
+				return file.Truncate(offset + i + 1)
+			}
+		}
+	}
+	if end == 0 {
+		return nil
+	}
+	return file.Truncate(0)
+}
+
+func (f *FileBackend) Append(record Record) error {
+	line, err := utils.Json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if _, err = f.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+// Replay read the file from the start.
+func (f *FileBackend) Replay(fn func(Record) error) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	reader := bufio.NewReader(io.NewSectionReader(f.file, 0, 1<<62))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var record Record
+		if err = utils.Json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *FileBackend) Close() error {
+	return f.file.Close()
+}
+
+// Journal appends records with increasing sequence numbers.
+type Journal struct {
+	backend Backend
+	mux     sync.Mutex
+	seq     uint64
+	// fills journaled per venue:order, the base of fills derived from cumulative quantities
+	fillMux sync.Mutex
+	fills   map[string]fill
+}
+
+// fill cumulative execution of an order
+type fill struct {
+	quantity decimal.Decimal
+	quote    decimal.Decimal
+}
+
+// Open continue the journal of backend after its last record.
+func Open(backend Backend) (*Journal, error) {
+	journal := &Journal{backend: backend, fills: make(map[string]fill)}
+	err := backend.Replay(func(record Record) error {
+		journal.seq = max(journal.seq, record.Seq)
+		if record.Kind == Fill {
+			key := string(record.Venue) + ":" + record.OrderId
+			f := journal.fills[key]
+			journal.fills[key] = fill{f.quantity.Add(record.Quantity), f.quote.Add(record.Quantity.Mul(record.Price))}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// Append number and store record.
+func (j *Journal) Append(record Record) (Record, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	j.seq++
+	record.Seq = j.seq
+	if record.Timestamp == 0 {
+		record.Timestamp = time.Now().UnixMilli()
+	}
+	if err := j.backend.Append(record); err != nil {
+		j.seq--
+		return record, err
+	}
+	return record, nil
+}
+
+// Replay call fn with every record in append order.
+func (j *Journal) Replay(fn func(Record) error) error {
+	return j.backend.Replay(fn)
+}
+
+// RecordFill journal an execution from the order stream, updates without a fill are ignored.
+// An update whose trade did not reach a FillMerger in time leaves the last fill empty, the
+// fill is then the growth of FilledQuantity since the fills journaled for the order.
+func (j *Journal) RecordFill(venue constants.Platform, update types.OrderUpdateEntry) error {
+	key := string(venue) + ":" + update.OrderId
+	j.fillMux.Lock()
+	defer j.fillMux.Unlock()
+	previous := j.fills[key]
+	quantity, price := update.LastFillQuantity, update.LastFillPrice
+	if !quantity.IsPositive() {
+		quantity = update.FilledQuantity.Sub(previous.quantity)
+		price = update.Price
+		if quantity.IsPositive() && update.FilledQuote.IsPositive() {
+			price = update.FilledQuote.Sub(previous.quote).Div(quantity)
+		}
+	}
+	if !quantity.IsPositive() {
+		return nil
+	}
+	_, err := j.Append(Record{
+		Kind:      Fill,
+		Venue:     venue,
+		OrderId:   update.OrderId,
+		Symbol:    update.Symbol,
+		Quantity:  quantity,
+		Price:     price,
+		Timestamp: update.TransactionTime,
+	})
+	if err != nil {
+		return err
+	}
+	j.fills[key] = fill{previous.quantity.Add(quantity), previous.quote.Add(quantity.Mul(price))}
+	return nil
+}
+
+// Trade SpotConnector whose order entry and cancels are journaled.
+// The intent is stored before the request is sent, a request whose intent can not be stored is not sent.
+// Orders without TradeNo are given one, so Recover can find them when the request fails in transit.
+type Trade struct {
+	platforms.SpotConnector
+	journal *Journal
+}
+
+func NewTrade(connector platforms.SpotConnector, journal *Journal) *Trade {
+	return &Trade{SpotConnector: connector, journal: journal}
+}
+
+func (t *Trade) PlaceOrder(order types.OrderEntry) (string, error) {
+	return t.PlaceOrderFor(order, "")
+}
+
+// PlaceOrderFor place order and journal why it was sent.
+func (t *Trade) PlaceOrderFor(order types.OrderEntry, reason string) (string, error) {
+	if order.TradeNo == "" {
+		order.TradeNo = platforms.NewClientId()
+	}
+	intent, err := t.journal.Append(Record{
+		Kind:   Intent,
+		Venue:  t.Name(),
+		Symbol: order.Symbol,
+		Order:  &order,
+		Reason: reason,
+	})
+	if err != nil {
+		return "", err
+	}
+	orderId, err := t.SpotConnector.PlaceOrder(order)
+	t.outcome(intent, orderId, err)
+	return orderId, err
+}
+
+func (t *Trade) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	orders = slices.Clone(orders)
+	var intents = make([]Record, len(orders))
+	for i := range orders {
+		if orders[i].TradeNo == "" {
+			orders[i].TradeNo = platforms.NewClientId()
+		}
+		intent, err := t.journal.Append(Record{
+			Kind:   Intent,
+			Venue:  t.Name(),
+			Symbol: orders[i].Symbol,
+			Order:  &orders[i],
+		})
+		if err != nil {
+			return nil, err
+		}
+		intents[i] = intent
+	}
+	results, err := t.SpotConnector.BatchOrder(orders)
+	for i, intent := range intents {
+		if i < len(results) {
+			var failure error
+			if !results[i].Success() {
+				failure = fmt.Errorf("%s %s", results[i].Code, results[i].Message)
+				if results[i].Code == "" && errors.Is(err, platforms.ErrNetwork) {
+					// the chunk failed as a whole in transit
+					failure = err
+				}
+			}
+			t.outcome(intent, results[i].OrderId, failure)
+		} else if err != nil {
+			t.outcome(intent, "", err)
+		}
+	}
+	return results, err
+}
+
+// PlaceOrderList place list and journal it, every leg the exchange returned an id for is
+// acknowledged under the one intent.
+func (t *Trade) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	for _, leg := range []**types.OrderEntry{&list.Entry, &list.TakeProfit, &list.StopLoss} {
+		if *leg == nil {
+			continue
+		}
+		order := **leg
+		if order.TradeNo == "" {
+			order.TradeNo = platforms.NewClientId()
+		}
+		*leg = &order
+	}
+	intent, err := t.journal.Append(Record{
+		Kind:   Intent,
+		Venue:  t.Name(),
+		Symbol: list.Symbol,
+		List:   &list,
+	})
+	if err != nil {
+		return types.OrderListResult{}, err
+	}
+	result, err := t.SpotConnector.PlaceOrderList(list)
+	if err != nil {
+		t.outcome(intent, "", err)
+		return result, err
+	}
+	acknowledged := false
+	for _, orderId := range []string{result.EntryOrderId, result.TakeProfitOrderId, result.StopLossOrderId} {
+		if orderId != "" {
+			t.outcome(intent, orderId, nil)
+			acknowledged = true
+		}
+	}
+	if !acknowledged {
+		// no leg exists before its trigger, the intent is answered still
+		t.outcome(intent, "", nil)
+	}
+	return result, nil
+}
+
+func (t *Trade) Cancel(symbol, orderId string) (bool, error) {
+	if err := t.cancelIntent(symbol, orderId); err != nil {
+		return false, err
+	}
+	ok, err := t.SpotConnector.Cancel(symbol, orderId)
+	t.cancelOutcome(symbol, orderId, err)
+	return ok, err
+}
+
+func (t *Trade) CancelAll(symbol string) error {
+	if err := t.cancelIntent(symbol, ""); err != nil {
+		return err
+	}
+	err := t.SpotConnector.CancelAll(symbol)
+	t.cancelOutcome(symbol, "", err)
+	return err
+}
+
+func (t *Trade) CancelAllSymbols() error {
+	if err := t.cancelIntent("", ""); err != nil {
+		return err
+	}
+	err := t.SpotConnector.CancelAllSymbols()
+	t.cancelOutcome("", "", err)
+	return err
+}
+
+func (t *Trade) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	for _, orderId := range orderIds {
+		if err := t.cancelIntent(symbol, orderId); err != nil {
+			return nil, err
+		}
+	}
+	results, err := t.SpotConnector.CancelByIds(symbol, orderIds)
+	for i, orderId := range orderIds {
+		if i < len(results) {
+			t.cancelOutcome(symbol, orderId, results[i].Err)
+		} else if err != nil {
+			t.cancelOutcome(symbol, orderId, err)
+		}
+	}
+	return results, err
+}
+
+// CancelAllAfter arm the exchange countdown, it is not journaled.
+func (t *Trade) CancelAllAfter(timeout time.Duration) error {
+	return platforms.CancelAllAfter(t.SpotConnector, timeout)
+}
+
+func (t *Trade) outcome(intent Record, orderId string, err error) {
+	record := Record{
+		Kind:     Ack,
+		Venue:    intent.Venue,
+		IntentId: strconv.FormatUint(intent.Seq, 10),
+		OrderId:  orderId,
+		Symbol:   intent.Symbol,
+	}
+	if errors.Is(err, platforms.ErrNetwork) {
+		record.Kind, record.Error = Unknown, err.Error()
+	} else if err != nil {
+		record.Kind, record.Error = Reject, err.Error()
+	}
+	t.append(record)
+}
+
+func (t *Trade) cancelIntent(symbol, orderId string) error {
+	_, err := t.journal.Append(Record{Kind: CancelIntent, Venue: t.Name(), Symbol: symbol, OrderId: orderId})
+	return err
+}
+
+func (t *Trade) cancelOutcome(symbol, orderId string, err error) {
+	record := Record{Kind: Canceled, Venue: t.Name(), Symbol: symbol, OrderId: orderId}
+	if err != nil {
+		record.Kind, record.Error = CancelFailed, err.Error()
+	}
+	t.append(record)
+}
+
+// append an outcome, the request already happened so a storage failure is only logged.
+func (t *Trade) append(record Record) {
+	if _, err := t.journal.Append(record); err != nil {
+		platforms.LoggerOf(t.SpotConnector).Error("record not journaled", slog.String("platform", string(record.Venue)), slog.String("kind", string(record.Kind)), slog.String("order", record.OrderId), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,168 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+type exchange struct {
+	platforms.SpotConnector
+	pending []types.OpenOrderEntry
+	history []types.QueryOrder
+	placed  []types.OrderEntry
+	// fail the next placements with err
+	err error
+}
+
+func (e *exchange) Name() constants.Platform { return "test" }
+
+func (e *exchange) PlaceOrder(order types.OrderEntry) (string, error) {
+	e.placed = append(e.placed, order)
+	if e.err != nil {
+		return "", e.err
+	}
+	if order.Quantity.IsZero() {
+		return "", errors.New("invalid quantity")
+	}
+	return "1", nil
+}
+
+func (e *exchange) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	e.placed = append(e.placed, *list.Entry)
+	return types.OrderListResult{ListId: "L", EntryOrderId: "5"}, nil
+}
+
+func (e *exchange) PendingOrders(string) ([]types.OpenOrderEntry, error) {
+	return e.pending, nil
+}
+
+func (e *exchange) OrderHistory(context.Context, string, int64, int64, []constants.OrderStatus) ([]types.QueryOrder, error) {
+	return e.history, nil
+}
+
+func TestRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	backend, err := NewFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal, err := Open(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := &exchange{}
+	trade := NewTrade(connector, journal)
+	if _, err = trade.PlaceOrderFor(types.OrderEntry{Symbol: "BTCUSDT", Quantity: decimal.NewFromInt(2)}, "rebalance"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = trade.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT"}); err == nil {
+		t.Fatal("expected the zero quantity order to be rejected")
+	}
+	_ = journal.RecordFill("test", types.OrderUpdateEntry{OrderId: "1", Symbol: "BTCUSDT", LastFillQuantity: decimal.NewFromInt(1)})
+	// an intent whose outcome never made it to disk, then a torn write
+	_, _ = journal.Append(Record{Kind: Intent, Venue: "test", Symbol: "BTCUSDT"})
+	_ = backend.Close()
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	_, _ = file.WriteString(`{"seq":99,"kind":"AC`)
+	_ = file.Close()
+
+	backend, err = NewFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	if journal, err = Open(backend); err != nil {
+		t.Fatal(err)
+	}
+	if record, _ := journal.Append(Record{Kind: CancelIntent, Venue: "other"}); record.Seq != 7 {
+		t.Errorf("sequence not continued, got %d", record.Seq)
+	}
+
+	connector.pending = []types.OpenOrderEntry{{OrderId: "1", Symbol: "BTCUSDT"}, {OrderId: "2", Symbol: "BTCUSDT"}}
+	connector.history = []types.QueryOrder{{OrderId: "1", Symbol: "BTCUSDT", Status: constants.PartiallyFilled, Filled: decimal.NewFromFloat(1.5)}}
+	recovery, err := Recover(context.Background(), journal, connector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovery.Open) != 1 || recovery.Open[0].Intent.Reason != "rebalance" || !recovery.Open[0].Filled.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("unexpected open orders %+v", recovery.Open)
+	}
+	if len(recovery.Orphans) != 1 || recovery.Orphans[0].Order.OrderId != "2" {
+		t.Errorf("unexpected orphans %+v", recovery.Orphans)
+	}
+	if len(recovery.Unacknowledged) != 1 || recovery.Unacknowledged[0].Seq != 6 {
+		t.Errorf("unexpected unacknowledged intents %+v", recovery.Unacknowledged)
+	}
+	if len(recovery.MissedFills) != 1 || !recovery.MissedFills[0].Actual.Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("unexpected missed fills %+v", recovery.MissedFills)
+	}
+}
+
+func TestUnknownPlacement(t *testing.T) {
+	backend, err := NewFileBackend(filepath.Join(t.TempDir(), "journal.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	journal, _ := Open(backend)
+	connector := &exchange{err: platforms.NetworkError(errors.New("timeout"))}
+	trade := NewTrade(connector, journal)
+	// the first reached the exchange, the second did not
+	for range 2 {
+		if _, err = trade.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", Quantity: decimal.NewFromInt(1)}); !errors.Is(err, platforms.ErrNetwork) {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	connector.err = nil
+	list := types.OrderListEntry{Symbol: "BTCUSDT", Entry: &types.OrderEntry{Symbol: "BTCUSDT"}}
+	if _, err = trade.PlaceOrderList(list); err != nil || list.Entry.TradeNo != "" {
+		t.Fatalf("order list not placed or the caller's entry changed: %v", err)
+	}
+	// bybit reports cumulative execution only
+	_ = journal.RecordFill("test", types.OrderUpdateEntry{OrderId: "3", Symbol: "BTCUSDT", FilledQuantity: decimal.NewFromInt(1), FilledQuote: decimal.NewFromInt(100)})
+	_ = journal.RecordFill("test", types.OrderUpdateEntry{OrderId: "3", Symbol: "BTCUSDT", FilledQuantity: decimal.NewFromInt(1), FilledQuote: decimal.NewFromInt(100)})
+	_ = journal.RecordFill("test", types.OrderUpdateEntry{OrderId: "3", Symbol: "BTCUSDT", FilledQuantity: decimal.NewFromInt(3), FilledQuote: decimal.NewFromInt(310)})
+
+	var kinds []Kind
+	var fills []Record
+	_ = journal.Replay(func(record Record) error {
+		kinds = append(kinds, record.Kind)
+		if record.Kind == Fill {
+			fills = append(fills, record)
+		}
+		return nil
+	})
+	if want := []Kind{Intent, Unknown, Intent, Unknown, Intent, Ack, Fill, Fill}; !slices.Equal(kinds, want) {
+		t.Errorf("want records %v, got %v", want, kinds)
+	}
+	if len(fills) != 2 || !fills[1].Quantity.Equal(decimal.NewFromInt(2)) || !fills[1].Price.Equal(decimal.NewFromInt(105)) {
+		t.Errorf("unexpected fills %+v", fills)
+	}
+
+	connector.pending = []types.OpenOrderEntry{
+		{OrderId: "3", Symbol: "BTCUSDT", TradeNo: connector.placed[0].TradeNo},
+		{OrderId: "5", Symbol: "BTCUSDT", TradeNo: connector.placed[2].TradeNo},
+	}
+	recovery, err := Recover(context.Background(), journal, connector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovery.Resolved) != 1 || recovery.Resolved[0].OrderId != "3" || !recovery.Resolved[0].Filled.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("unexpected resolved orders %+v", recovery.Resolved)
+	}
+	if len(recovery.Unresolved) != 1 || recovery.Unresolved[0].Order.TradeNo != connector.placed[1].TradeNo {
+		t.Errorf("unexpected unresolved intents %+v", recovery.Unresolved)
+	}
+	if len(recovery.Open) != 2 || len(recovery.Orphans) != 0 {
+		t.Errorf("unexpected open orders %+v, orphans %+v", recovery.Open, recovery.Orphans)
+	}
+}
@@ -0,0 +1,234 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Entry an acknowledged order as known from the journal.
+type Entry struct {
+	Venue   constants.Platform
+	OrderId string
+	Symbol  string
+	// Intent the record the order was sent with, carries the order and its reason
+	Intent Record
+	// Filled sum of the journaled fills
+	Filled   decimal.Decimal
+	Canceled bool
+}
+
+// Orphan an order open on the exchange the journal knows nothing about.
+type Orphan struct {
+	Venue constants.Platform
+	Order types.OpenOrderEntry
+}
+
+// MissedFill an order the exchange filled further than the journal recorded.
+type MissedFill struct {
+	Venue     constants.Platform
+	OrderId   string
+	Symbol    string
+	Journaled decimal.Decimal
+	Actual    decimal.Decimal
+	Status    constants.OrderStatus
+}
+
+// Recovery outcome of comparing the journal with the exchanges.
+type Recovery struct {
+	// Open journaled orders still pending on their exchange
+	Open []Entry
+	// Orphans pending on an exchange without a journal record
+	Orphans []Orphan
+	// Unacknowledged intents without Ack or Reject, the process stopped while they were in flight;
+	// the order may exist and then shows up among the orphans.
+	Unacknowledged []Record
+	MissedFills    []MissedFill
+	// Resolved orders of Unknown placements the exchange reported under their client id,
+	// they take part in Open and MissedFills like acknowledged orders
+	Resolved []Entry
+	// Unresolved intents of Unknown placements no order carries the client id of: the order was
+	// not placed, or the connector does not send TradeNo
+	Unresolved []Record
+}
+
+// Recover replay journal and compare it with PendingOrders and OrderHistory of every symbol
+// journaled for the venues of connectors. Venues that fail are reported in the joined error,
+// the Recovery still holds what the others returned.
+func Recover(ctx context.Context, journal *Journal, connectors ...platforms.SpotConnector) (Recovery, error) {
+	var recovery Recovery
+	intents := make(map[string]Record)
+	var unknown []Record
+	// fills per venue:order, the stream may report an order before its Ack or resolution
+	filled := make(map[string]decimal.Decimal)
+	answered := make(map[string]struct{})
+	entries := make(map[string]*Entry)
+	since := make(map[constants.Platform]int64)
+	symbols := make(map[constants.Platform]map[string]struct{})
+	err := journal.Replay(func(record Record) error {
+		key := string(record.Venue) + ":" + record.OrderId
+		if record.Symbol != "" {
+			if symbols[record.Venue] == nil {
+				symbols[record.Venue] = make(map[string]struct{})
+			}
+			symbols[record.Venue][record.Symbol] = struct{}{}
+		}
+		if first, ok := since[record.Venue]; !ok || record.Timestamp < first {
+			since[record.Venue] = record.Timestamp
+		}
+		switch record.Kind {
+		case Intent:
+			intents[strconv.FormatUint(record.Seq, 10)] = record
+		case Reject:
+			answered[record.IntentId] = struct{}{}
+		case Unknown:
+			answered[record.IntentId] = struct{}{}
+			if intent, ok := intents[record.IntentId]; ok {
+				unknown = append(unknown, intent)
+			}
+		case Ack:
+			answered[record.IntentId] = struct{}{}
+			if record.OrderId == "" {
+				break // an order list without legs yet
+			}
+			entry, ok := entries[key]
+			if !ok {
+				entry = &Entry{Venue: record.Venue, OrderId: record.OrderId, Symbol: record.Symbol}
+				entries[key] = entry
+			}
+			entry.Intent = intents[record.IntentId]
+		case Fill:
+			filled[key] = filled[key].Add(record.Quantity)
+		case Canceled:
+			if entry, ok := entries[key]; ok && record.OrderId != "" {
+				entry.Canceled = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return recovery, err
+	}
+	for key, entry := range entries {
+		entry.Filled = filled[key]
+	}
+	for id, intent := range intents {
+		if _, ok := answered[id]; !ok {
+			recovery.Unacknowledged = append(recovery.Unacknowledged, intent)
+		}
+	}
+	sort.Slice(recovery.Unacknowledged, func(i, j int) bool {
+		return recovery.Unacknowledged[i].Seq < recovery.Unacknowledged[j].Seq
+	})
+
+	var failures []error
+	for _, connector := range connectors {
+		venue := connector.Name()
+		for symbol := range symbols[venue] {
+			var lookups []Record
+			for _, intent := range unknown {
+				if intent.Venue == venue && intent.Symbol == symbol {
+					lookups = append(lookups, intent)
+				}
+			}
+			if err = compare(ctx, connector, symbol, since[venue], entries, filled, lookups, &recovery); err != nil {
+				failures = append(failures, fmt.Errorf("%s %s: %w", venue, symbol, err))
+			}
+		}
+	}
+	return recovery, errors.Join(failures...)
+}
+
+// compare the journaled orders of symbol with the exchange, the intents of unknown
+// placements are looked up by client id first.
+func compare(ctx context.Context, connector platforms.SpotConnector, symbol string, since int64,
+	entries map[string]*Entry, filled map[string]decimal.Decimal, unknown []Record, recovery *Recovery) error {
+	venue := connector.Name()
+	pending, err := connector.PendingOrders(symbol)
+	if err != nil {
+		return err
+	}
+	history, err := connector.OrderHistory(ctx, symbol, since, time.Now().UnixMilli(), nil)
+	if err != nil {
+		return err
+	}
+	byClientId := make(map[string]string, len(pending)+len(history))
+	for _, order := range pending {
+		byClientId[order.TradeNo] = order.OrderId
+	}
+	for _, order := range history {
+		byClientId[order.TradeNo] = order.OrderId
+	}
+	delete(byClientId, "")
+	for _, intent := range unknown {
+		found := false
+		for _, clientId := range clientIds(intent) {
+			orderId, ok := byClientId[clientId]
+			if !ok {
+				continue
+			}
+			found = true
+			key := string(venue) + ":" + orderId
+			entry := &Entry{Venue: venue, OrderId: orderId, Symbol: symbol, Intent: intent, Filled: filled[key]}
+			entries[key] = entry
+			recovery.Resolved = append(recovery.Resolved, *entry)
+		}
+		if !found {
+			recovery.Unresolved = append(recovery.Unresolved, intent)
+		}
+	}
+	open := make(map[string]struct{}, len(pending))
+	for _, order := range pending {
+		open[order.OrderId] = struct{}{}
+		if _, ok := entries[string(venue)+":"+order.OrderId]; !ok {
+			recovery.Orphans = append(recovery.Orphans, Orphan{Venue: venue, Order: order})
+		}
+	}
+	closed := make(map[string]types.QueryOrder, len(history))
+	for _, order := range history {
+		closed[order.OrderId] = order
+	}
+	for _, entry := range entries {
+		if entry.Venue != venue || entry.Symbol != symbol {
+			continue
+		}
+		if _, ok := open[entry.OrderId]; ok {
+			recovery.Open = append(recovery.Open, *entry)
+		}
+		if order, ok := closed[entry.OrderId]; ok && order.Filled.GreaterThan(entry.Filled) {
+			recovery.MissedFills = append(recovery.MissedFills, MissedFill{
+				Venue:     venue,
+				OrderId:   entry.OrderId,
+				Symbol:    symbol,
+				Journaled: entry.Filled,
+				Actual:    order.Filled,
+				Status:    order.Status,
+			})
+		}
+	}
+	return nil
+}
+
+// clientIds the client order ids an intent was sent with, one per leg of an order list.
+func clientIds(intent Record) []string {
+	var ids []string
+	if intent.Order != nil {
+		ids = append(ids, intent.Order.TradeNo)
+	}
+	if list := intent.List; list != nil {
+		for _, leg := range []*types.OrderEntry{list.Entry, list.TakeProfit, list.StopLoss} {
+			if leg != nil {
+				ids = append(ids, leg.TradeNo)
+			}
+		}
+	}
+	return ids
+}
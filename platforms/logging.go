@@ -0,0 +1,134 @@
+package platforms
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// discard slog.Handler dropping every record.
+type discard struct{}
+
+func (discard) Enabled(context.Context, slog.Level) bool  { return false }
+func (discard) Handle(context.Context, slog.Record) error { return nil }
+func (d discard) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discard) WithGroup(string) slog.Handler           { return d }
+
+var silent = slog.New(discard{})
+
+// Discard the silent default logger of connectors, streams and the components built on them.
+func Discard() *slog.Logger {
+	return silent
+}
+
+// Loggable connectors and streams accepting a logger, every connector and stream of this module is one.
+type Loggable interface {
+	SetLogger(logger *slog.Logger)
+	Logger() *slog.Logger
+}
+
+// LoggerOf the logger of connector, the silent default if it is not Loggable.
+func LoggerOf(connector any) *slog.Logger {
+	if loggable, ok := connector.(Loggable); ok {
+		return loggable.Logger()
+	}
+	return silent
+}
+
+// Logging logger embedded by connectors and streams. The zero value is silent.
+type Logging struct {
+	logger atomic.Pointer[slog.Logger]
+}
+
+// SetLogger log through logger, nil restores the silent default.
+func (l *Logging) SetLogger(logger *slog.Logger) {
+	l.logger.Store(logger)
+}
+
+func (l *Logging) Logger() *slog.Logger {
+	if logger := l.logger.Load(); logger != nil {
+		return logger
+	}
+	return silent
+}
+
+// Logged log every request handler sends with platform, method, route, symbol, latency and error code.
+// Keys, signatures, headers and bodies are never logged; failures at Warn, the rest at Debug.
+func (l *Logging) Logged(handler Handler) Handler {
+	return func(invocation *Invocation) error {
+		start := time.Now()
+		err := handler(invocation)
+		logger := l.Logger()
+		level := slog.LevelDebug
+		if err != nil {
+			level = slog.LevelWarn
+		}
+		if !logger.Enabled(context.Background(), level) {
+			return err
+		}
+		attrs := []slog.Attr{
+			slog.String("platform", string(invocation.Platform)),
+			slog.String("method", invocation.Method),
+			slog.String("route", invocation.Route),
+		}
+		if symbol := invocation.Symbol(); symbol != "" {
+			attrs = append(attrs, slog.String("symbol", symbol))
+		}
+		attrs = append(attrs, slog.Duration("latency", time.Since(start)), slog.Int("status", invocation.StatusCode))
+		if err != nil {
+			var exchangeErr *ExchangeError
+			if errors.As(err, &exchangeErr) {
+				attrs = append(attrs, slog.String("code", exchangeErr.Code))
+			}
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		logger.LogAttrs(context.Background(), level, "call", attrs...)
+		return err
+	}
+}
+
+// LogValue keep the key and secret out of logs.
+func (c *Credentials) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("key", redact(c.APIKey)), slog.String("secret", "<redacted>"))
+}
+
+// redact show the first characters of an api key only, enough to tell accounts apart.
+func redact(key string) string {
+	if len(key) <= 4 {
+		return "<redacted>"
+	}
+	return key[:4] + "<redacted>"
+}
+
+// RedactHeader copy of header with api keys, passphrases and signatures replaced.
+func RedactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "key") || strings.Contains(lower, "passphrase") || strings.Contains(lower, "sign") {
+			redacted.Set(name, "<redacted>")
+		}
+	}
+	return redacted
+}
+
+// RedactURL raw with signatures sent in the query string replaced.
+func RedactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	query := parsed.Query()
+	for name := range query {
+		if strings.Contains(strings.ToLower(name), "sign") {
+			query.Set(name, "<redacted>")
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
@@ -0,0 +1,38 @@
+package platforms
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/xavierzho/go-cexs/constants"
+)
+
+func TestLogged(t *testing.T) {
+	var logging Logging
+	handler := logging.Logged(func(invocation *Invocation) error {
+		invocation.StatusCode = 400
+		return &ExchangeError{Exchange: "Binance", Code: "-2013", Message: "Order does not exist.", Err: ErrOrderNotFound}
+	})
+	invocation := &Invocation{Platform: constants.Binance, Method: "DELETE", Route: "/api/v3/order",
+		Params: &ObjectBody{"symbol": "BTCUSDT"}}
+	if err := handler(invocation); err == nil {
+		t.Fatal("error swallowed")
+	}
+
+	var out bytes.Buffer
+	logging.SetLogger(slog.New(slog.NewTextHandler(&out, nil)))
+	_ = handler(invocation)
+	for _, field := range []string{"platform=Binance", "route=/api/v3/order", "symbol=BTCUSDT", "status=400", "code=-2013", "latency="} {
+		if !strings.Contains(out.String(), field) {
+			t.Errorf("%s missing in %q", field, out.String())
+		}
+	}
+
+	out.Reset()
+	slog.New(slog.NewTextHandler(&out, nil)).Info("connector", "credentials", NewCredentials("abcdefgh", "secret", nil))
+	if strings.Contains(out.String(), "abcdefgh") || strings.Contains(out.String(), "=secret") {
+		t.Errorf("credentials leaked: %q", out.String())
+	}
+}
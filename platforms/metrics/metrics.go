@@ -0,0 +1,148 @@
+// Package metrics Prometheus instrumentation of connectors and streams.
+//
+//	collector := metrics.NewCollector("cex")
+//	registry.MustRegister(collector)
+//	collector.Instrument(connector)
+//	stream.Observe(collector.Stream(constants.Binance))
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+)
+
+// Collector prometheus.Collector of the calls and streams it instruments.
+type Collector struct {
+	requests     *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	errors       *prometheus.CounterVec
+	rateLimit    *prometheus.GaugeVec
+	reconnects   *prometheus.CounterVec
+	messages     *prometheus.CounterVec
+	decodeErrors *prometheus.CounterVec
+	lag          *prometheus.HistogramVec
+}
+
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "rest", Name: "requests_total",
+			Help: "REST requests by platform, route and http status, network for requests without response.",
+		}, []string{"platform", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "rest", Name: "request_duration_seconds",
+			Help:    "REST request latency by platform and route.",
+			Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"platform", "route"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "rest", Name: "exchange_errors_total",
+			Help: "Errors reported by the exchange by platform and exchange error code.",
+		}, []string{"platform", "code"}),
+		rateLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "rest", Name: "rate_limit_remaining",
+			Help: "Requests or weight left in the current rate limit window, as last reported by the exchange.",
+		}, []string{"platform", "scope"}),
+		reconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "stream", Name: "reconnects_total",
+			Help: "Websocket connections dialed again.",
+		}, []string{"platform"}),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "stream", Name: "messages_total",
+			Help: "Websocket messages received by platform and topic.",
+		}, []string{"platform", "topic"}),
+		decodeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "stream", Name: "decode_errors_total",
+			Help: "Websocket messages that could not be decoded.",
+		}, []string{"platform"}),
+		lag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "stream", Name: "event_lag_seconds",
+			Help:    "Time from the exchange event to its receipt, by platform and topic.",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"platform", "topic"}),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.requests, c.latency, c.errors, c.rateLimit,
+		c.reconnects, c.messages, c.decodeErrors, c.lag}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+// Instrument measure the calls of connector, false if it does not run a middleware chain.
+func (c *Collector) Instrument(connector any) bool {
+	interceptable, ok := connector.(platforms.Interceptable)
+	if ok {
+		interceptable.Use(c.Middleware())
+	}
+	return ok
+}
+
+// Middleware measure calls, registered after a retrying middleware it measures every attempt.
+func (c *Collector) Middleware() platforms.Middleware {
+	return func(next platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			start := time.Now()
+			err := next(invocation)
+			platform, route := string(invocation.Platform), invocation.Template()
+			c.latency.WithLabelValues(platform, route).Observe(time.Since(start).Seconds())
+			status := "network"
+			if invocation.StatusCode > 0 {
+				status = strconv.Itoa(invocation.StatusCode)
+			}
+			c.requests.WithLabelValues(platform, route, status).Inc()
+			var exchangeErr *platforms.ExchangeError
+			if errors.As(err, &exchangeErr) {
+				c.errors.WithLabelValues(platform, exchangeErr.Code).Inc()
+			}
+			if invocation.Header != nil {
+				c.observeRateLimit(invocation.Platform, route, invocation.Header)
+			}
+			return err
+		}
+	}
+}
+
+// binanceWeightLimit default spot REQUEST_WEIGHT per minute, binance reports the weight used
+const binanceWeightLimit = 6000
+
+// observeRateLimit read the rate limit headers of the platforms reporting them.
+func (c *Collector) observeRateLimit(platform constants.Platform, route string, header http.Header) {
+	gauge := func(scope, value string, convert func(float64) float64) {
+		if value == "" {
+			return
+		}
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			c.rateLimit.WithLabelValues(string(platform), scope).Set(convert(number))
+		}
+	}
+	remaining := func(value float64) float64 { return value }
+	switch platform {
+	case constants.Binance:
+		gauge("REQUEST_WEIGHT_1M", header.Get("X-Mbx-Used-Weight-1m"), func(used float64) float64 {
+			return binanceWeightLimit - used
+		})
+	case constants.ByBit:
+		gauge(route, header.Get("X-Bapi-Limit-Status"), remaining)
+	case constants.Gate:
+		gauge(route, header.Get("X-Gate-Ratelimit-Requests-Remain"), remaining)
+	case constants.Bitmart:
+		gauge(route, header.Get("X-Bm-Ratelimit-Remaining"), remaining)
+	}
+}
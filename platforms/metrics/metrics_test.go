@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+)
+
+func TestMiddleware(t *testing.T) {
+	collector := NewCollector("cex")
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	var chain platforms.Middlewares
+	chain.Use(collector.Middleware())
+	_ = chain.Invoke(&platforms.Invocation{Platform: constants.Gate, Route: "/spot/orders/12345"},
+		func(invocation *platforms.Invocation) error {
+			invocation.StatusCode = http.StatusBadRequest
+			invocation.Header = http.Header{"X-Gate-Ratelimit-Requests-Remain": {"9"}}
+			return &platforms.ExchangeError{Exchange: "Gate", Code: "ORDER_NOT_FOUND", Err: platforms.ErrOrderNotFound}
+		})
+	_ = chain.Invoke(&platforms.Invocation{Platform: constants.Binance, Route: "/api/v3/order"},
+		func(invocation *platforms.Invocation) error {
+			return platforms.NetworkError(errors.New("connection reset"))
+		})
+
+	for _, c := range []struct {
+		metric prometheus.Collector
+		value  float64
+	}{
+		{collector.requests.WithLabelValues("Gate", "/spot/orders/:id", "400"), 1},
+		{collector.requests.WithLabelValues("Binance", "/api/v3/order", "network"), 1},
+		{collector.errors.WithLabelValues("Gate", "ORDER_NOT_FOUND"), 1},
+		{collector.rateLimit.WithLabelValues("Gate", "/spot/orders/:id"), 9},
+	} {
+		if value := testutil.ToFloat64(c.metric); value != c.value {
+			t.Errorf("expected %v, got %v", c.value, value)
+		}
+	}
+	if _, err := registry.Gather(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	collector := NewCollector("cex")
+	observer := collector.Stream(constants.Binance)
+	sent := time.Now().Add(-100 * time.Millisecond)
+	msg := []byte(`{"stream":"btcusdt@depth","data":{"e":"depthUpdate","E":` + strconv.FormatInt(sent.UnixMilli(), 10) + `}}`)
+	observer.OnMessage(msg, time.Now())
+	// the user data stream is named after the listenKey
+	observer.OnMessage([]byte(`{"stream":"pqia91ma19a5s61cv6a81va65sdf19v8a65a1a5s61cv6a81va65sdf19v8a65a1","data":{"e":"executionReport"}}`), time.Now())
+	observer.OnMessage([]byte("pong"), time.Now())
+	observer.OnDecodeError(errors.New("bad message"))
+	observer.OnReconnect()
+
+	if value := testutil.ToFloat64(collector.messages.WithLabelValues("Binance", "depthUpdate")); value != 1 {
+		t.Errorf("expected one depth message, got %v", value)
+	}
+	if value := testutil.ToFloat64(collector.messages.WithLabelValues("Binance", "executionReport")); value != 1 {
+		t.Errorf("expected one execution report, got %v", value)
+	}
+	if value := testutil.ToFloat64(collector.messages.WithLabelValues("Binance", "control")); value != 1 {
+		t.Errorf("expected one control message, got %v", value)
+	}
+	if value := testutil.ToFloat64(collector.decodeErrors.WithLabelValues("Binance")); value != 1 {
+		t.Errorf("expected one decode error, got %v", value)
+	}
+	if value := testutil.ToFloat64(collector.reconnects.WithLabelValues("Binance")); value != 1 {
+		t.Errorf("expected one reconnect, got %v", value)
+	}
+	if count := testutil.CollectAndCount(collector.lag); count != 1 {
+		t.Errorf("expected lag observed for one topic, got %d", count)
+	}
+}
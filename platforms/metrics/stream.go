@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/utils"
+)
+
+// probe topic and event time in unix milliseconds of a message, zero when the message carries none
+type probe func(msg []byte) (topic string, eventTime int64)
+
+// probes where each platform puts the topic and the event time.
+// Symbols are cut from topics so the label set stays small.
+var probes = map[constants.Platform]probe{
+	constants.Binance: func(msg []byte) (string, int64) {
+		// combined streams wrap the event: {"stream":"btcusdt@depth","data":{"e":"depthUpdate","E":...}}.
+		// The stream of the user data stream is the listenKey, a secret, so the event type labels it.
+		if event := utils.Json.Get(msg, "data", "e").ToString(); event != "" {
+			return event, utils.Json.Get(msg, "data", "E").ToInt64()
+		}
+		if stream := utils.Json.Get(msg, "stream").ToString(); strings.Contains(stream, "@") {
+			// partial book depth carries no event type
+			return after(stream, "@"), utils.Json.Get(msg, "data", "E").ToInt64()
+		}
+		return utils.Json.Get(msg, "e").ToString(), utils.Json.Get(msg, "E").ToInt64()
+	},
+	constants.Okx: func(msg []byte) (string, int64) {
+		// okx sends the timestamp as a string
+		ts, _ := strconv.ParseInt(utils.Json.Get(msg, "data", 0, "ts").ToString(), 10, 64)
+		return utils.Json.Get(msg, "arg", "channel").ToString(), ts
+	},
+	constants.ByBit: func(msg []byte) (string, int64) {
+		topic := utils.Json.Get(msg, "topic").ToString()
+		if i := strings.LastIndexByte(topic, '.'); i > 0 && strings.ToUpper(topic[i+1:]) == topic[i+1:] {
+			topic = topic[:i]
+		}
+		return topic, utils.Json.Get(msg, "ts").ToInt64()
+	},
+	constants.Gate: func(msg []byte) (string, int64) {
+		return utils.Json.Get(msg, "channel").ToString(), utils.Json.Get(msg, "time_ms").ToInt64()
+	},
+	constants.Bitmart: func(msg []byte) (string, int64) {
+		return utils.Json.Get(msg, "table").ToString(), utils.Json.Get(msg, "data", 0, "ms_t").ToInt64()
+	},
+	constants.Mexc: func(msg []byte) (string, int64) {
+		// spot@public.increase.depth.v3.api@BTCUSDT
+		topic := utils.Json.Get(msg, "c").ToString()
+		if parts := strings.Split(topic, "@"); len(parts) > 1 {
+			topic = parts[1]
+		}
+		return topic, utils.Json.Get(msg, "t").ToInt64()
+	},
+}
+
+// observer platforms.StreamObserver of one platform's stream.
+type observer struct {
+	collector *Collector
+	platform  string
+	probe     probe
+}
+
+// Stream observer for a stream of platform, pass it to StreamBase.Observe.
+func (c *Collector) Stream(platform constants.Platform) platforms.StreamObserver {
+	return &observer{collector: c, platform: string(platform), probe: probes[platform]}
+}
+
+func (o *observer) OnMessage(msg []byte, received time.Time) {
+	topic, eventTime := "", int64(0)
+	if o.probe != nil && len(msg) > 0 && (msg[0] == '{' || msg[0] == '[') {
+		topic, eventTime = o.probe(msg)
+	}
+	if topic == "" {
+		// acks, heartbeats and anything else without a topic
+		topic = "control"
+	}
+	o.collector.messages.WithLabelValues(o.platform, topic).Inc()
+	if eventTime > 0 {
+		lag := received.Sub(time.UnixMilli(eventTime)).Seconds()
+		o.collector.lag.WithLabelValues(o.platform, topic).Observe(max(lag, 0))
+	}
+}
+
+func (o *observer) OnDecodeError(error) {
+	o.collector.decodeErrors.WithLabelValues(o.platform).Inc()
+}
+
+func (o *observer) OnReconnect() {
+	o.collector.reconnects.WithLabelValues(o.platform).Inc()
+}
+
+// after the part of s after the first sep, s when there is none
+func after(s, sep string) string {
+	if _, rest, ok := strings.Cut(s, sep); ok {
+		return rest
+	}
+	return s
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/xavierzho/go-cexs/platforms"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -20,7 +21,25 @@ func (c *Connector) Sign(params []byte) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
+type ErrorResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// errorKinds error codes with a unified meaning
+var errorKinds = map[int]error{
+	-2011: platforms.ErrOrderNotFound, // Unknown order sent
+	-2013: platforms.ErrOrderNotFound, // Order does not exist
+}
+
 func (c *Connector) Call(method string, route string, params platforms.Serializer, authType constants.AuthType, returnType interface{}) error {
+	return c.Invoke(&platforms.Invocation{Platform: c.Name(), Method: method, Route: route, AuthType: authType,
+		Params: params, Result: returnType}, c.Logged(c.call))
+}
+
+// call the request of one invocation, the middlewares of the connector run around it
+func (c *Connector) call(invocation *platforms.Invocation) error {
+	method, route, params, authType, returnType := invocation.Method, invocation.Route, invocation.Params, invocation.AuthType, invocation.Result
 	// Add necessary parameters
 	var timestamp = time.Now()
 	var url = RestAPI + route
@@ -37,6 +56,7 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 	if err != nil {
 		return err
 	}
+	invocation.RequestBody = bytesBody
 	header := new(http.Header)
 	queryString, err := params.EncodeQuery()
 	if err != nil {
@@ -66,14 +86,24 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 		return err
 	}
 
+	invocation.Request = req
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return err
+		return platforms.NetworkError(err)
 	}
 
+	defer resp.Body.Close()
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
+	invocation.StatusCode, invocation.Header, invocation.Response = resp.StatusCode, resp.Header, respBody
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if json.Unmarshal(respBody, &errResp) != nil || errResp.Code == 0 {
+			return fmt.Errorf("[Mexc] Response %s", resp.Status)
+		}
+		return &platforms.ExchangeError{Exchange: "Mexc", Code: strconv.Itoa(errResp.Code), Message: errResp.Msg, Err: errorKinds[errResp.Code]}
+	}
 	return json.Unmarshal(respBody, returnType)
 }
@@ -26,6 +26,9 @@ const (
 	OpenOrdersEndpoint = "/api/v3/openOrders"
 	AccountEndpoint    = "/api/v3/account"
 	ListenKeyEndpoint  = "/api/v3/userDataStream"
+	MyTradesEndpoint   = "/api/v3/myTrades"
+	AllOrdersEndpoint  = "/api/v3/allOrders"
+	TradeFeeEndpoint   = "/api/v3/tradeFee"
 )
 
 type OrderType string
@@ -1,14 +1,19 @@
 package mexc
 
 import (
+	"fmt"
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
 	"net/http"
 )
 
 type Connector struct {
 	*platforms.Credentials
 	Client *http.Client
+	platforms.Middlewares
+	platforms.Logging
 }
 
 func (c *Connector) Name() constants.Platform {
@@ -26,3 +31,34 @@ func (c *Connector) SymbolPattern(symbol string) string {
 func NewConnector(cred *platforms.Credentials, client *http.Client) platforms.SpotConnector {
 	return &Connector{Credentials: cred, Client: client}
 }
+
+type TradeFeeResp struct {
+	Data struct {
+		MakerCommission decimal.Decimal `json:"makerCommission"`
+		TakerCommission decimal.Decimal `json:"takerCommission"`
+	} `json:"data"`
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (c *Connector) GetFeeRates(symbols []string) (map[string]types.FeeRateEntry, error) {
+	var result = make(map[string]types.FeeRateEntry, len(symbols))
+	for _, symbol := range symbols {
+		var resp TradeFeeResp
+		err := c.Call(http.MethodGet, TradeFeeEndpoint, &platforms.ObjectBody{
+			SymbolFiled: symbol,
+		}, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Code != 0 {
+			return nil, fmt.Errorf("[Mexc] code: %d, msg: %s", resp.Code, resp.Msg)
+		}
+		result[symbol] = types.FeeRateEntry{
+			Symbol: symbol,
+			Maker:  resp.Data.MakerCommission,
+			Taker:  resp.Data.TakerCommission,
+		}
+	}
+	return result, nil
+}
@@ -3,13 +3,17 @@ package mexc
 import (
 	"context"
 	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"net/http"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
 )
 
 func TestMarketAPI(t *testing.T) {
@@ -111,3 +115,157 @@ func TestUserDataStream(t *testing.T) {
 		}
 	}
 }
+
+func TestFillMerger(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan types.OrderUpdateEntry, 8)
+	merger := platforms.NewFillMerger(ctx, updates)
+	onOrder := func(update PlaceUpdate, eventTime int64) {
+		entry := update.convert()
+		entry.Symbol, entry.EventTime = "BTCUSDT", eventTime
+		merger.OnOrder(entry)
+	}
+	onDeal := func(deal OrderUpdate) {
+		merger.OnFill(deal.entry())
+	}
+	next := func() types.OrderUpdateEntry {
+		select {
+		case update := <-updates:
+			return update
+		case <-time.After(2 * platforms.FillMergeWait):
+			t.Fatal("no update")
+			return types.OrderUpdateEntry{}
+		}
+	}
+
+	onOrder(PlaceUpdate{OrderId: "1", Status: 1, Quantity: "3"}, 1)
+	if update := next(); update.Status != constants.Open || update.Symbol != "BTCUSDT" {
+		t.Errorf("unexpected new order update %+v", update)
+	}
+	// deal after its order update
+	onOrder(PlaceUpdate{OrderId: "1", Status: 3, Quantity: "3", CumulativeQuantity: "1"}, 2)
+	onDeal(OrderUpdate{OrderId: "1", Price: "100", Volume: "1", CommissionFee: "0.1", TradeId: "t1"})
+	update := next()
+	if update.Status != constants.PartiallyFilled || !update.FilledQuantity.Equal(decimal.NewFromInt(1)) ||
+		!update.LastFillQuantity.Equal(decimal.NewFromInt(1)) || update.Fee.String() != "0.1" || update.TradeId != "t1" {
+		t.Errorf("unexpected partial fill %+v", update)
+	}
+	// deal before its order update
+	onDeal(OrderUpdate{OrderId: "1", Price: "101", Volume: "2", TradeId: "t2"})
+	onOrder(PlaceUpdate{OrderId: "1", Status: 2, Quantity: "3", CumulativeQuantity: "3"}, 3)
+	update = next()
+	if update.Status != constants.Filled || !update.FilledQuantity.Equal(decimal.NewFromInt(3)) ||
+		!update.LastFillPrice.Equal(decimal.NewFromInt(101)) || update.TradeId != "t2" {
+		t.Errorf("unexpected fill %+v", update)
+	}
+	// a fill whose deal never comes goes out without detail
+	onOrder(PlaceUpdate{OrderId: "2", Status: 3, Quantity: "3", CumulativeQuantity: "1"}, 4)
+	update = next()
+	if update.OrderId != "2" || update.Status != constants.PartiallyFilled || !update.LastFillQuantity.IsZero() {
+		t.Errorf("unexpected unmatched fill %+v", update)
+	}
+}
+
+// mexc order ids are strings such as "C02__413321238354677760043", not numbers.
+func TestOpenOrderId(t *testing.T) {
+	var order OpenOrder
+	if err := utils.Json.UnmarshalFromString(`{"symbol":"BTCUSDT","orderId":"C02__413321238354677760043","origQty":"2","executedQty":"0.5","status":"PARTIALLY_FILLED"}`, &order); err != nil {
+		t.Fatal(err)
+	}
+	if result := order.convert(); result.OrderId != "C02__413321238354677760043" || !result.Filled.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("unexpected order %+v", result)
+	}
+}
+
+// myTradesServer answers myTrades from trades like mexc: from the trade fromId on, or by time range.
+func myTradesServer(trades []MyTrade) platforms.Middleware {
+	return func(platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			param := func(key string) int64 {
+				value, _ := strconv.ParseInt(invocation.Param(key), 10, 64)
+				return value
+			}
+			var page []MyTrade
+			found := false
+			for _, trade := range trades {
+				if fromId := invocation.Param("fromId"); fromId != "" {
+					if found = found || trade.ID == fromId; !found {
+						continue
+					}
+				} else if trade.Time < param("startTime") || trade.Time > param("endTime") {
+					continue
+				}
+				if len(page) < myTradesLimit {
+					page = append(page, trade)
+				}
+			}
+			*invocation.Result.(*[]MyTrade) = page
+			return nil
+		}
+	}
+}
+
+func TestGetMyTradesPaging(t *testing.T) {
+	// more trades in one millisecond than a page holds, then one after until
+	var trades []MyTrade
+	for id := 1; id <= 250; id++ {
+		trades = append(trades, MyTrade{ID: fmt.Sprintf("t%d", id), Time: 5000, Price: "1", Qty: "1"})
+	}
+	trades = append(trades, MyTrade{ID: "t251", Time: 9000})
+	cex := NewConnector(&platforms.Credentials{}, nil)
+	cex.(platforms.Interceptable).Use(myTradesServer(trades))
+	fills, err := cex.GetMyTrades(context.Background(), "BTCUSDT", 0, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 250 || fills[0].TradeId != "t1" || fills[249].TradeId != "t250" {
+		t.Errorf("want trades t1 to t250, got %d", len(fills))
+	}
+}
+
+// allOrdersServer answers allOrders from orders like mexc: from the order orderId on, or by time range.
+func allOrdersServer(orders []OpenOrder) platforms.Middleware {
+	return func(platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			param := func(key string) int64 {
+				value, _ := strconv.ParseInt(invocation.Param(key), 10, 64)
+				return value
+			}
+			var page []OpenOrder
+			found := false
+			for _, order := range orders {
+				if orderId := invocation.Param("orderId"); orderId != "" {
+					if found = found || order.OrderID == orderId; !found {
+						continue
+					}
+				} else if order.Time < param("startTime") || order.Time > param("endTime") {
+					continue
+				}
+				if len(page) < allOrdersLimit {
+					page = append(page, order)
+				}
+			}
+			*invocation.Result.(*[]OpenOrder) = page
+			return nil
+		}
+	}
+}
+
+func TestOrderHistoryPaging(t *testing.T) {
+	// more orders in one millisecond than a page holds, then one after until
+	var orders []OpenOrder
+	for id := 1; id <= 2500; id++ {
+		orders = append(orders, OpenOrder{OrderID: fmt.Sprintf("C02__%d", id), Time: 5000, Status: "FILLED"})
+	}
+	orders = append(orders, OpenOrder{OrderID: "C02__2501", Time: 9000, Status: "FILLED"})
+	cex := NewConnector(&platforms.Credentials{}, nil)
+	cex.(platforms.Interceptable).Use(allOrdersServer(orders))
+	history, err := cex.OrderHistory(context.Background(), "BTCUSDT", 0, 8000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2500 || history[0].OrderId != "C02__1" || history[2499].OrderId != "C02__2500" {
+		t.Errorf("want orders C02__1 to C02__2500, got %d", len(history))
+	}
+}
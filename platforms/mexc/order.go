@@ -1,11 +1,12 @@
 package mexc
 
 import (
+	"context"
+	"errors"
 	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
-	"math"
 	"net/http"
 	"slices"
 	"strconv"
@@ -45,60 +46,95 @@ func (c *Connector) PlaceOrder(params types.OrderEntry) (string, error) {
 	var resp = new(Order)
 
 	orderType := c.MatchOrderType(params.Type)
-	err := c.Call(http.MethodPost, OrderEndpoint, &platforms.ObjectBody{
+	var body = &platforms.ObjectBody{
 		SymbolFiled: params.Symbol,
 		"side":      strings.ToUpper(params.Side),
 		"type":      orderType,
-	}, constants.Signed, resp)
+	}
+	if params.TradeNo != "" {
+		body.Set("newClientOrderId", params.TradeNo)
+	}
+	err := c.Call(http.MethodPost, OrderEndpoint, body, constants.Signed, resp)
 	if err != nil {
 		return "", err
 	}
 	return strconv.FormatInt(int64(resp.OrderID), 10), nil
 }
 
-func (c *Connector) BatchOrder(params []types.OrderEntry) ([]string, error) {
+// batchOrderLimit maximum orders per batch request, all of the same symbol
+const batchOrderLimit = 20
+
+// BatchOrderResp item of batch orders response, code and msg are set when the order failed
+type BatchOrderResp struct {
+	Symbol           string `json:"symbol"`
+	OrderId          string `json:"orderId"`
+	NewClientOrderId string `json:"newClientOrderId"`
+	Code             int    `json:"code"`
+	Msg              string `json:"msg"`
+}
+
+// PlaceOrderList mexc spot has no order lists, see platforms.OrderListEmulator.
+func (c *Connector) PlaceOrderList(types.OrderListEntry) (types.OrderListResult, error) {
+	return types.OrderListResult{}, platforms.ErrNotSupported
+}
 
+// BatchOrder https://mexcdevelop.github.io/apidocs/spot_v3_en/#batch-orders
+func (c *Connector) BatchOrder(params []types.OrderEntry) ([]types.BatchOrderResult, error) {
 	orders := make(platforms.ArrayBody, len(params))
+	results := make([]types.BatchOrderResult, len(params))
 	for i, arg := range params {
+		results[i].TradeNo = arg.TradeNo
 		orders[i] = map[string]interface{}{
 			"quantity":         arg.Quantity.StringFixed(1),
 			"price":            arg.Price.StringFixed(11),
-			"side":             strings.ToLower(arg.Side),
-			SymbolFiled:        arg.Symbol,
+			"side":             strings.ToUpper(arg.Side),
+			SymbolFiled:        c.SymbolPattern(arg.Symbol),
 			"type":             c.MatchOrderType(arg.Type).String(),
 			"newClientOrderId": arg.TradeNo,
 		}
 	}
-	maxSize := 30
-	numBatches := int(math.Ceil(float64(len(orders)) / float64(maxSize)))
-	results := make([]string, len(orders))
 	var mu sync.Mutex
 	var wg sync.WaitGroup
-	for i := 0; i < numBatches; i++ {
+	var errs []error
+	for _, chunk := range platforms.ChunkOrders(params, batchOrderLimit, true) {
 		wg.Add(1)
-		batchStart := i * maxSize
-		batchEnd := int(math.Min(float64(batchStart+maxSize), float64(len(orders))))
-		batchOrders := orders[batchStart:batchEnd]
-		go func(i int, batchOrders []map[string]interface{}) {
+		go func(chunk []int) {
 			defer wg.Done()
-			var resp []Order
+			var batchOrders = make([]map[string]interface{}, len(chunk))
+			for j, index := range chunk {
+				batchOrders[j] = orders[index]
+			}
+			var resp []BatchOrderResp
 			err := c.Call(http.MethodPost, BatchOrderEndpoint, &platforms.ObjectBody{
 				"batchOrders": batchOrders,
 			}, constants.Signed, &resp)
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
+				errs = append(errs, err)
+				for _, index := range chunk {
+					results[index].Message = err.Error()
+				}
 				return
 			}
-			mu.Lock()
-			defer mu.Unlock()
-			if len(resp) > 0 {
-				for j, order := range resp {
-					results[batchStart+j] = strconv.FormatInt(int64(order.OrderID), 10)
+			// response is in request order
+			for j, order := range resp {
+				if j >= len(chunk) {
+					break
 				}
+				index := chunk[j]
+				if order.Code != 0 {
+					results[index].Code = strconv.Itoa(order.Code)
+					results[index].Message = order.Msg
+					continue
+				}
+				results[index].OrderId = order.OrderId
 			}
-		}(i, batchOrders)
+		}(chunk)
 	}
+	wg.Wait()
 
-	return results, nil
+	return results, errors.Join(errs...)
 }
 func (c *Connector) queryOrder(symbol string, orderId string) (types.QueryOrder, error) {
 	var resp types.QueryOrder
@@ -122,39 +158,38 @@ func (c *Connector) QueryOrder(symbol string, orderId string) (types.QueryOrder,
 	return c.queryOrder(symbol, orderId)
 }
 func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
+	var resp map[string]any
 	err := c.Call(http.MethodDelete, OrderEndpoint, &platforms.ObjectBody{
 		SymbolFiled: symbol,
-		orderId:     orderId,
-	}, constants.Signed, nil)
+		"orderId":   orderId,
+	}, constants.Signed, &resp)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
-
 func (c *Connector) CancelAll(symbol string) error {
+	var resp []map[string]any
 	return c.Call(http.MethodDelete, OpenOrdersEndpoint, &platforms.ObjectBody{
 		SymbolFiled: symbol,
-	}, constants.Signed, nil)
+	}, constants.Signed, &resp)
+}
+
+// CancelAllSymbols mexc requires a symbol to list or cancel open orders.
+func (c *Connector) CancelAllSymbols() error {
+	return platforms.ErrNotSupported
 }
 
-func (c *Connector) CancelByIds(symbol string, orderIds []string) (map[string]bool, error) {
+func (c *Connector) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
 	var wg sync.WaitGroup
-	var mux sync.Mutex
-	var result = make(map[string]bool)
-	for _, orderId := range orderIds {
+	var result = make([]types.CancelResult, len(orderIds))
+	for i, orderId := range orderIds {
+		result[i].OrderId = orderId
 		wg.Add(1)
-		go func(orderId string) {
+		go func(i int, orderId string) {
 			defer wg.Done()
-
-			ok, err := c.Cancel(symbol, orderId)
-			if err != nil {
-				return
-			}
-			mux.Lock()
-			result[orderId] = ok
-			mux.Unlock()
-		}(orderId)
+			_, result[i].Err = c.Cancel(symbol, orderId)
+		}(i, orderId)
 	}
 	wg.Wait()
 	return result, nil
@@ -166,7 +201,7 @@ type OpenOrder struct {
 	Side                string `json:"side"`
 	OrderListID         int    `json:"orderListId"`
 	ExecutedQty         string `json:"executedQty"`
-	OrderID             int    `json:"orderId"`
+	OrderID             string `json:"orderId"`
 	OrigQty             string `json:"origQty"`
 	ClientOrderID       string `json:"clientOrderId"`
 	UpdateTime          int64  `json:"updateTime"`
@@ -198,7 +233,7 @@ func (c *Connector) PendingOrders(symbol string) ([]types.OpenOrderEntry, error)
 			Type:     OrderType(m.Type).Convert(),
 			Status:   OrderStatus(m.Status).Convert(),
 			Side:     m.Side,
-			OrderId:  strconv.Itoa(m.OrderID),
+			OrderId:  m.OrderID,
 			TradeNo:  m.ClientOrderID,
 			Price:    price,
 			Quantity: amount,
@@ -207,6 +242,71 @@ func (c *Connector) PendingOrders(symbol string) ([]types.OpenOrderEntry, error)
 	return result, nil
 }
 
+func (m OpenOrder) convert() types.QueryOrder {
+	price, _ := decimal.NewFromString(m.Price)
+	amount, _ := decimal.NewFromString(m.OrigQty)
+	filled, _ := decimal.NewFromString(m.ExecutedQty)
+	return types.QueryOrder{
+		Symbol:     m.Symbol,
+		Type:       OrderType(m.Type).Convert(),
+		Status:     OrderStatus(m.Status).Convert(),
+		Side:       strings.ToUpper(m.Side),
+		Price:      price,
+		Quantity:   amount,
+		OrderId:    m.OrderID,
+		TradeNo:    m.ClientOrderID,
+		CreateTime: m.Time,
+		UpdateTime: m.UpdateTime,
+		Filled:     filled,
+	}
+}
+
+const allOrdersLimit = 1000
+
+func (c *Connector) OrderHistory(ctx context.Context, symbol string, since, until int64, statuses []constants.OrderStatus) ([]types.QueryOrder, error) {
+	var result []types.QueryOrder
+	// mexc order ids are strings, orderId returns the order itself first
+	var orderId string
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var params = &platforms.ObjectBody{
+			SymbolFiled: symbol,
+			"limit":     allOrdersLimit,
+		}
+		if orderId != "" {
+			params.Set("orderId", orderId)
+		} else {
+			params.Set("startTime", since)
+			params.Set("endTime", until)
+		}
+		var resp []OpenOrder
+		err := c.Call(http.MethodGet, AllOrdersEndpoint, params, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range resp {
+			if m.OrderID == orderId {
+				continue
+			}
+			if m.Time > until {
+				return result, nil
+			}
+			order := m.convert()
+			if len(statuses) > 0 && !slices.Contains(statuses, order.Status) {
+				continue
+			}
+			result = append(result, order)
+		}
+		if len(resp) < allOrdersLimit || resp[len(resp)-1].OrderID == orderId {
+			break
+		}
+		orderId = resp[len(resp)-1].OrderID
+	}
+	return result, nil
+}
+
 type Balance struct {
 	Balances []struct {
 		Asset  string `json:"asset"`
@@ -241,3 +341,86 @@ func (c *Connector) Balance(symbols []string) (map[string]types.BalanceEntry, er
 
 	return result, nil
 }
+
+type MyTrade struct {
+	Symbol          string `json:"symbol"`
+	ID              string `json:"id"`
+	OrderID         string `json:"orderId"`
+	OrderListID     int    `json:"orderListId"`
+	Price           string `json:"price"`
+	Qty             string `json:"qty"`
+	QuoteQty        string `json:"quoteQty"`
+	Commission      string `json:"commission"`
+	CommissionAsset string `json:"commissionAsset"`
+	Time            int64  `json:"time"`
+	IsBuyer         bool   `json:"isBuyer"`
+	IsMaker         bool   `json:"isMaker"`
+	IsBestMatch     bool   `json:"isBestMatch"`
+	IsSelfTrade     bool   `json:"isSelfTrade"`
+	ClientOrderID   string `json:"clientOrderId"`
+}
+
+const myTradesLimit = 100
+
+func (c *Connector) GetMyTrades(ctx context.Context, symbol string, since, until int64) ([]types.FillEntry, error) {
+	var result []types.FillEntry
+	// mexc trade ids are strings, fromId returns the trade itself first
+	var fromId string
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var params = &platforms.ObjectBody{
+			SymbolFiled: symbol,
+			"limit":     myTradesLimit,
+		}
+		if fromId != "" {
+			params.Set("fromId", fromId)
+		} else {
+			params.Set("startTime", since)
+			params.Set("endTime", until)
+		}
+		var resp []MyTrade
+		err := c.Call(http.MethodGet, MyTradesEndpoint, params, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, trade := range resp {
+			if trade.ID == fromId {
+				continue
+			}
+			if trade.Time > until {
+				return result, nil
+			}
+			result = append(result, trade.fill())
+		}
+		if len(resp) < myTradesLimit || resp[len(resp)-1].ID == fromId {
+			break
+		}
+		fromId = resp[len(resp)-1].ID
+	}
+	return result, nil
+}
+
+func (trade MyTrade) fill() types.FillEntry {
+	price, _ := decimal.NewFromString(trade.Price)
+	qty, _ := decimal.NewFromString(trade.Qty)
+	fee, _ := decimal.NewFromString(trade.Commission)
+	side := "SELL"
+	if trade.IsBuyer {
+		side = "BUY"
+	}
+	return types.FillEntry{
+		Symbol:      trade.Symbol,
+		TradeId:     trade.ID,
+		OrderId:     trade.OrderID,
+		TradeNo:     trade.ClientOrderID,
+		Side:        side,
+		Price:       price,
+		Quantity:    qty,
+		Fee:         fee,
+		FeeCurrency: trade.CommissionAsset,
+		IsMaker:     trade.IsMaker,
+		Timestamp:   trade.Time,
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
-	"github.com/xavierzho/go-cexs/utils"
 	"regexp"
 	"strings"
 )
@@ -65,7 +64,7 @@ func (stream *MarketStream) DepthStream(ctx context.Context, symbol string, chan
 					continue
 				}
 				var resp StreamResp[DepthUpdate]
-				_ = utils.Json.Unmarshal(msg, &resp)
+				_ = stream.Decode(msg, &resp)
 				var asks = make([][]string, len(resp.Data.Asks))
 				var bids = make([][]string, len(resp.Data.Bids))
 				for i, bid := range resp.Data.Bids {
@@ -74,9 +73,11 @@ func (stream *MarketStream) DepthStream(ctx context.Context, symbol string, chan
 				for i, ask := range resp.Data.Asks {
 					asks[i] = []string{ask.Price, ask.Volume}
 				}
+				// the limit depth channel pushes the top 20 levels whole every time
 				channel <- types.DepthEntry{
-					Asks: asks,
-					Bids: bids,
+					Asks:     asks,
+					Bids:     bids,
+					Snapshot: true,
 				}
 			}
 		}
@@ -166,7 +167,7 @@ func (stream *MarketStream) CandleStream(ctx context.Context, symbol, interval s
 					continue
 				}
 				var resp CandleUpdate
-				_ = utils.Json.Unmarshal(msg, &resp)
+				_ = stream.Decode(msg, &resp)
 				var k = resp.Kline
 				var list = []any{k.Start, k.Open, k.High, k.Low, k.Close, k.Volume, k.Amount}
 				var result = make(types.CandleEntry, len(list))
@@ -6,21 +6,45 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
 	"github.com/xavierzho/go-cexs/utils"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
 const listenKeyEndpoint = RestAPI + ListenKeyEndpoint
 
+// accountChannel balance and account streams are split from it by change type
+const accountChannel = "spot@private.account.v3.api"
+
 type UserDataStream struct {
 	*platforms.Credentials
-	base      *platforms.StreamBase
-	listenKey string
+	base          *platforms.StreamBase
+	dispatcher    *platforms.Dispatcher
+	listenKey     string
+	mux           sync.Mutex
+	subscriptions []string // channels, replayed after reconnecting
+}
+
+// SetLogger log through logger, nil restores the silent default.
+func (stream *UserDataStream) SetLogger(logger *slog.Logger) {
+	stream.base.SetLogger(logger)
+}
+
+func (stream *UserDataStream) Logger() *slog.Logger {
+	return stream.base.Logger()
+}
+
+// Observe report the traffic of the stream to observer.
+func (stream *UserDataStream) Observe(observer platforms.StreamObserver) {
+	stream.base.Observe(observer)
 }
 
 func (stream *UserDataStream) getListenKey() error {
@@ -96,24 +120,35 @@ func (stream *UserDataStream) Reconnect() error {
 	var attempt int
 	for attempt = 0; attempt < 3; attempt++ {
 		stream.closeListenKey(stream.listenKey)
-		fmt.Printf("Reconnection attempt %d of %d...\n", attempt+1, 3)
+		stream.base.Logger().Info("reconnecting", slog.String("platform", string(constants.Mexc)), slog.Int("attempt", attempt+1), slog.Int("attempts", 3))
 
 		// 获取新的 listenKey 并尝试连接
 		if err := stream.getListenKey(); err != nil {
-			fmt.Printf("Failed to get listenKey: %v\n", err)
+			stream.base.Logger().Warn("listen key failed", slog.String("platform", string(constants.Mexc)), slog.String("error", err.Error()))
 			//time.Sleep(stream.reconnectInterval)
 			continue
 		}
 
 		// 尝试建立 WebSocket 连接
-		err := stream.base.Connect(StreamAPI + "?streams=" + stream.listenKey)
+		err := stream.base.Connect(StreamAPI + "?listenKey=" + stream.listenKey)
 		if err != nil {
-			fmt.Printf("Failed to reconnect WebSocket: %v\n", err)
+			stream.base.Logger().Warn("reconnect failed", slog.String("platform", string(constants.Mexc)), slog.String("error", err.Error()))
 			//time.Sleep(stream.reconnectInterval)
 			continue
 		}
+		// the acks are left to the reader
+		stream.mux.Lock()
+		for _, channel := range stream.subscriptions {
+			if err = stream.base.WriteJSON(subscribePayload(stream.dispatcher.NextId(), channel)); err != nil {
+				break
+			}
+		}
+		stream.mux.Unlock()
+		if err != nil {
+			continue
+		}
 
-		fmt.Println("Reconnected successfully")
+		stream.base.Logger().Info("reconnected", slog.String("platform", string(constants.Mexc)))
 
 		// 重新开始接收消息
 		return nil
@@ -122,6 +157,45 @@ func (stream *UserDataStream) Reconnect() error {
 	return fmt.Errorf("failed to reconnect after %d attempts", 3)
 }
 
+func subscribePayload(id, channel string) map[string]any {
+	requestId, _ := strconv.ParseInt(id, 10, 64)
+	return map[string]any{
+		"id":     requestId,
+		"method": SubscribeOp,
+		"params": []string{channel},
+	}
+}
+
+// route channel of a push.
+func route(msg []byte) string {
+	return utils.Json.Get(msg, "c").ToString()
+}
+
+// replyId mexc echoes the id of a request in its reply, which carries no channel.
+func replyId(msg []byte) (string, bool) {
+	id := utils.Json.Get(msg, "id").ToString()
+	return id, id != "" && route(msg) == ""
+}
+
+// subscribe channel once for all the streams reading it, handle receives its pushes until ctx is done.
+func (stream *UserDataStream) subscribe(ctx context.Context, channel string, handle func(msg []byte)) error {
+	return stream.dispatcher.Subscribe(ctx, channel, func() error {
+		id := stream.dispatcher.NextId()
+		reply, err := stream.dispatcher.Request(id, subscribePayload(id, channel))
+		if err != nil {
+			return err
+		}
+		// a successful subscription echoes the channel
+		if msg := utils.Json.Get(reply, "msg").ToString(); msg != channel {
+			return fmt.Errorf("[Mexc stream] subscribe %s failed: %s", channel, msg)
+		}
+		stream.mux.Lock()
+		stream.subscriptions = append(stream.subscriptions, channel)
+		stream.mux.Unlock()
+		return nil
+	}, handle)
+}
+
 type PlaceUpdate struct {
 	RemainAmount       string  `json:"A,omitempty"`
 	CreateTime         int64   `json:"O,omitempty"`
@@ -147,38 +221,64 @@ func (d PlaceUpdate) GetSymbol() string {
 	return ""
 }
 
+// convert the fill detail comes with the deal of the fill, see OrderUpdate.entry.
+func (d PlaceUpdate) convert() types.OrderUpdateEntry {
+	var status constants.OrderStatus = constants.Error
+	switch d.Status {
+	case 1:
+		status = constants.Open
+	case 2:
+		status = constants.Filled
+	case 3:
+		status = constants.PartiallyFilled
+	case 4:
+		status = constants.Canceled
+	case 5:
+		status = constants.PartiallyCanceled
+	}
+	price, _ := decimal.NewFromString(d.Price)
+	quantity, _ := decimal.NewFromString(d.Quantity)
+	filled, _ := decimal.NewFromString(d.CumulativeQuantity)
+	filledQuote, _ := decimal.NewFromString(d.CumulativeAmount)
+	return types.OrderUpdateEntry{
+		OrderId:         d.OrderId,
+		ClientOrderId:   d.TradeNo,
+		Status:          status,
+		Side:            streamSide(int(d.Side)),
+		Type:            streamOrderType(d.OrderType),
+		Price:           price,
+		Quantity:        quantity,
+		FilledQuantity:  filled,
+		FilledQuote:     filledQuote,
+		FeeAsset:        d.FeeAsset,
+		IsMaker:         d.IsMaker == 1,
+		TransactionTime: d.CreateTime,
+	}
+}
+
+// OrderStream joins spot@private.orders, carrying status and cumulative quantity,
+// with spot@private.deals, carrying price, quantity and fee of every fill.
 func (stream *UserDataStream) OrderStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
-	err := stream.base.SendMessage(map[string]any{
-		"method": SubscribeOp,
-		"params": []string{"spot@private.deals.v3.api"},
+	merger := platforms.NewFillMerger(ctx, channel)
+	err := stream.subscribe(ctx, "spot@private.orders.v3.api", func(msg []byte) {
+		var resp StreamResp[PlaceUpdate]
+		if err := stream.base.Decode(msg, &resp); err != nil {
+			return
+		}
+		entry := resp.Data.convert()
+		entry.Symbol, entry.EventTime = resp.Symbol, resp.Timestamp
+		merger.OnOrder(entry)
 	})
 	if err != nil {
 		return err
 	}
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-			default:
-				msg, err := stream.base.ReadMessage()
-				if err != nil {
-					fmt.Println("order error:", err)
-					continue
-				}
-				fmt.Printf("%s\n", msg)
-				var resp StreamResp[OrderUpdate]
-
-				_ = utils.Json.Unmarshal(msg, &resp)
-				fmt.Printf("order %+v\n", resp)
-				channel <- types.OrderUpdateEntry{
-					OrderId:       resp.Data.OrderId,
-					ClientOrderId: resp.Data.TradeNo,
-					Status:        constants.Filled,
-				}
-			}
+	return stream.subscribe(ctx, "spot@private.deals.v3.api", func(msg []byte) {
+		var resp StreamResp[OrderUpdate]
+		if err := stream.base.Decode(msg, &resp); err != nil {
+			return
 		}
-	}()
-	return nil
+		merger.OnFill(resp.Data.entry())
+	})
 }
 
 type BalanceUpdate struct {
@@ -195,40 +295,36 @@ func (BalanceUpdate) GetSymbol() string {
 	return ""
 }
 
-func (stream *UserDataStream) BalanceStream(ctx context.Context, channel chan<- types.BalanceUpdateEntry) error {
-	err := stream.base.SendMessage(map[string]any{
-		"method": SubscribeOp,
-		"params": []string{"spot@private.account.v3.api"},
-	})
-	if err != nil {
-		return err
-	}
+// values delta is the change of free plus locked balance
+func (b BalanceUpdate) values() (free, locked, delta decimal.Decimal) {
+	free, _ = decimal.NewFromString(b.Free)
+	locked, _ = decimal.NewFromString(b.Lock)
+	freeChange, _ := decimal.NewFromString(b.FreeChange)
+	lockChange, _ := decimal.NewFromString(b.LockChange)
+	return free, locked, freeChange.Add(lockChange)
+}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
+func (stream *UserDataStream) BalanceStream(ctx context.Context, channel chan<- types.BalanceUpdateEntry) error {
+	return stream.subscribe(ctx, accountChannel, func(msg []byte) {
+		var resp StreamResp[BalanceUpdate]
+		if err := stream.base.Decode(msg, &resp); err != nil {
+			return
+		}
+		switch resp.Data.Type {
+		case InternalTransfer, ContractTransfer, Deposit, Withdraw, WithdrawFee, DepositFee:
+			free, locked, delta := resp.Data.values()
+			if !platforms.Deliver(ctx, channel, types.BalanceUpdateEntry{
+				Asset:     resp.Data.Asset,
+				Free:      free,
+				Locked:    locked,
+				Delta:     delta,
+				Reason:    string(resp.Data.Type),
+				Timestamp: resp.Data.Timestamp,
+			}) {
 				return
-			default:
-				msg, err := stream.base.ReadMessage()
-				if err != nil {
-					continue
-				}
-				var resp StreamResp[BalanceUpdate]
-
-				_ = utils.Json.Unmarshal(msg, &resp)
-				switch resp.Data.Type {
-				case InternalTransfer, ContractTransfer, Deposit, Withdraw, WithdrawFee, DepositFee:
-					//fmt.Printf("%+v\n", resp.Data)
-					channel <- types.BalanceUpdateEntry{}
-				default:
-					continue
-				}
-
 			}
 		}
-	}()
-	return nil
+	})
 }
 
 type OrderUpdate struct {
@@ -250,90 +346,80 @@ func (OrderUpdate) GetSymbol() string {
 	return ""
 }
 
-func (stream *UserDataStream) AccountStream(ctx context.Context, channel chan<- types.AccountUpdateEntry) error {
-	err := stream.base.SendMessage(map[string]any{
-		"method": SubscribeOp,
-		"params": []string{"spot@private.account.v3.api"},
-	})
-	if err != nil {
-		return err
+// entry the fill detail of a deal, merged into the update of its order.
+func (d OrderUpdate) entry() types.OrderUpdateEntry {
+	price, _ := decimal.NewFromString(d.Price)
+	volume, _ := decimal.NewFromString(d.Volume)
+	fee, _ := decimal.NewFromString(d.CommissionFee)
+	return types.OrderUpdateEntry{
+		OrderId:          d.OrderId,
+		LastFillPrice:    price,
+		LastFillQuantity: volume,
+		Fee:              fee,
+		FeeAsset:         d.CommissionAsset,
+		IsMaker:          d.IsMaker == 1,
+		TradeId:          d.TradeId,
+		TransactionTime:  d.TradeTime,
 	}
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				msg, err := stream.base.ReadMessage()
-				if err != nil {
-					continue
-				}
-
-				var resp StreamResp[BalanceUpdate]
-				_ = utils.Json.Unmarshal(msg, &resp)
-				switch resp.Data.Type {
-				case Entrust, EntrustCancel, EntrustPlace, EntrustUnfrozen, Airdrop, EtfIndex, TradeFee:
-					channel <- types.AccountUpdateEntry{}
-				default:
-					continue
-				}
-			}
-		}
-	}()
-	return nil
 }
 
-func (stream *UserDataStream) PlaceStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
-	err := stream.base.SendMessage(map[string]any{
-		"method": SubscribeOp,
-		"params": []string{
-			"spot@private.orders.v3.api",
-		},
-	})
-	if err != nil {
-		return err
+// streamSide private streams encode side as 1= buy 2= sell
+func streamSide(side int) string {
+	if side == 1 {
+		return "BUY"
 	}
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
+	return "SELL"
+}
+
+// streamOrderType LIMIT_ORDER(1),POST_ONLY(2),IMMEDIATE_OR_CANCEL(3),FILL_OR_KILL(4),MARKET_ORDER(5),STOP_LIMIT(100)
+func streamOrderType(orderType int) constants.OrderType {
+	switch orderType {
+	case 2:
+		return constants.LimitMaker
+	case 5:
+		return constants.Market
+	case 100:
+		return constants.StopLossLimit
+	default:
+		return constants.Limit
+	}
+}
+
+func (stream *UserDataStream) AccountStream(ctx context.Context, channel chan<- types.AccountUpdateEntry) error {
+	return stream.subscribe(ctx, accountChannel, func(msg []byte) {
+		var resp StreamResp[BalanceUpdate]
+		if err := stream.base.Decode(msg, &resp); err != nil {
+			return
+		}
+		switch resp.Data.Type {
+		case Entrust, EntrustCancel, EntrustPlace, EntrustUnfrozen, Airdrop, EtfIndex, TradeFee:
+			free, locked, delta := resp.Data.values()
+			if !platforms.Deliver(ctx, channel, types.AccountUpdateEntry{
+				Asset:     resp.Data.Asset,
+				Free:      free,
+				Locked:    locked,
+				Delta:     delta,
+				Reason:    string(resp.Data.Type),
+				Timestamp: resp.Data.Timestamp,
+			}) {
 				return
-			default:
-				msg, err := stream.base.ReadMessage()
-				if err != nil {
-					fmt.Println("place error: ", err)
-					continue
-				}
-				fmt.Printf("%s\n", msg)
-				var resp StreamResp[PlaceUpdate]
-				_ = utils.Json.Unmarshal(msg, &resp)
-				fmt.Printf("place %+v\n", resp)
-				var status constants.OrderStatus = constants.Error
-				switch resp.Data.Status {
-				case 1:
-					status = constants.Open
-				case 2:
-					status = constants.Filled
-				case 3:
-					status = constants.PartiallyFilled
-				case 4:
-					status = constants.Canceled
-				case 5:
-					status = constants.PartiallyCanceled
-				}
-				channel <- types.OrderUpdateEntry{
-					OrderId:       resp.Data.OrderId,
-					ClientOrderId: resp.Data.TradeNo,
-					Status:        status,
-				}
 			}
 		}
-	}()
-	return nil
+	})
 }
+
+// PlaceStream the same as OrderStream, which reads the orders channel as well now.
+func (stream *UserDataStream) PlaceStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
+	return stream.OrderStream(ctx, channel)
+}
+
 func NewUserStream(cred *platforms.Credentials) platforms.UserDataStreamer {
-	return &UserDataStream{
+	stream := &UserDataStream{
 		base:        platforms.NewStream(),
 		Credentials: cred,
 	}
+	stream.dispatcher = platforms.NewDispatcher(stream.base, route)
+	stream.dispatcher.ReplyId = replyId
+	stream.dispatcher.Recover = stream.Reconnect
+	return stream
 }
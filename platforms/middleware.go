@@ -0,0 +1,96 @@
+package platforms
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/xavierzho/go-cexs/constants"
+)
+
+// Invocation one Caller.Call on its way through the middleware chain.
+type Invocation struct {
+	Platform constants.Platform
+	Method   string
+	Route    string
+	AuthType constants.AuthType
+	// Params request parameters, serialized again on every attempt so a middleware may retry
+	Params Serializer
+	// Result value the response is decoded into
+	Result any
+	// Request, RequestBody the http request as sent, signed, and its body; set once it is built
+	Request     *http.Request
+	RequestBody []byte
+	// StatusCode, Header, Response http status, headers and raw body, set once the exchange answered
+	StatusCode int
+	Header     http.Header
+	Response   []byte
+}
+
+// symbolKeys parameter names the connectors send the symbol under
+var symbolKeys = []string{"symbol", "instId", "currency_pair", "trading_pair"}
+
+// Symbol the symbol among the parameters, empty if there is none.
+func (invocation *Invocation) Symbol() string {
+	return invocation.Param(symbolKeys...)
+}
+
+// Param the first of keys present among the parameters, formatted as a string.
+func (invocation *Invocation) Param(keys ...string) string {
+	if invocation.Params == nil {
+		return ""
+	}
+	for _, key := range keys {
+		if value, ok := invocation.Params.Exists(key); ok && value != nil {
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+// Template the route with ids in the path, e.g. gate's /spot/orders/{order_id}, replaced by :id.
+// Span names and metric labels use it to stay few.
+func (invocation *Invocation) Template() string {
+	segments := strings.Split(invocation.Route, "/")
+	for i, segment := range segments {
+		if segment != "" && strings.Trim(segment, "0123456789") == "" {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Handler performs an invocation, the returned error is the decoded exchange error if any.
+type Handler func(invocation *Invocation) error
+
+// Middleware wraps the next Handler, e.g. to log, measure, limit, retry, record or fail invocations.
+type Middleware func(next Handler) Handler
+
+// Interceptable connectors whose calls run through a middleware chain, every connector of this module is one.
+type Interceptable interface {
+	Use(middlewares ...Middleware)
+}
+
+// Middlewares chain embedded by the connectors. The zero value is an empty chain.
+type Middlewares struct {
+	mux  sync.RWMutex
+	list []Middleware
+}
+
+// Use append middlewares to the chain, the first registered runs outermost.
+func (m *Middlewares) Use(middlewares ...Middleware) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.list = append(m.list, middlewares...)
+}
+
+// Invoke run invocation through the chain into handler.
+func (m *Middlewares) Invoke(invocation *Invocation, handler Handler) error {
+	m.mux.RLock()
+	for i := len(m.list) - 1; i >= 0; i-- {
+		handler = m.list[i](handler)
+	}
+	m.mux.RUnlock()
+	return handler(invocation)
+}
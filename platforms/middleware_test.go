@@ -0,0 +1,56 @@
+package platforms
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMiddlewares(t *testing.T) {
+	var chain Middlewares
+	var trace []string
+	named := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(invocation *Invocation) error {
+				trace = append(trace, name+" "+invocation.Route)
+				return next(invocation)
+			}
+		}
+	}
+	retry := func(next Handler) Handler {
+		return func(invocation *Invocation) error {
+			err := next(invocation)
+			if errors.Is(err, ErrNetwork) {
+				err = next(invocation)
+			}
+			return err
+		}
+	}
+	chain.Use(named("outer"), retry, named("inner"))
+
+	attempts := 0
+	err := chain.Invoke(&Invocation{Route: "/order"}, func(invocation *Invocation) error {
+		attempts++
+		if attempts == 1 {
+			return NetworkError(errors.New("connection reset"))
+		}
+		invocation.StatusCode, invocation.Response = 200, []byte(`{}`)
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("retry failed after %d attempts: %v", attempts, err)
+	}
+	if want := []string{"outer /order", "inner /order", "inner /order"}; !reflect.DeepEqual(trace, want) {
+		t.Errorf("unexpected order %v", trace)
+	}
+
+	chain.Use(func(Handler) Handler {
+		return func(*Invocation) error { return ErrNotSupported }
+	})
+	if err = chain.Invoke(&Invocation{}, func(*Invocation) error {
+		t.Error("short-circuited call reached the handler")
+		return nil
+	}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -20,7 +20,27 @@ func (c *Connector) Sign(params []byte) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
-func (c *Connector) Call(method string, route string, params platforms.Serializer, _ constants.AuthType, returnType interface{}) error {
+// orderErrorKinds sCode of order operations with a unified meaning
+var orderErrorKinds = map[string]error{
+	"51400": platforms.ErrOrderClosed,   // Cancellation failed as the order has been filled, canceled or does not exist
+	"51401": platforms.ErrOrderClosed,   // Cancellation failed as the order is already canceled
+	"51402": platforms.ErrOrderFilled,   // Cancellation failed as the order is already completed
+	"51603": platforms.ErrOrderNotFound, // Order does not exist
+}
+
+// orderError per order failure reported through sCode and sMsg
+func orderError(code, msg string) error {
+	return &platforms.ExchangeError{Exchange: "Okx", Code: code, Message: msg, Err: orderErrorKinds[code]}
+}
+
+func (c *Connector) Call(method string, route string, params platforms.Serializer, authType constants.AuthType, returnType interface{}) error {
+	return c.Invoke(&platforms.Invocation{Platform: c.Name(), Method: method, Route: route, AuthType: authType,
+		Params: params, Result: returnType}, c.Logged(c.call))
+}
+
+// call the request of one invocation, the middlewares of the connector run around it
+func (c *Connector) call(invocation *platforms.Invocation) error {
+	method, route, params, returnType := invocation.Method, invocation.Route, invocation.Params, invocation.Result
 	// Add necessary parameters
 	var body io.Reader
 	var err error
@@ -57,23 +77,28 @@ func (c *Connector) Call(method string, route string, params platforms.Serialize
 		}
 
 		prevSign += fmt.Sprintf("%s", bodyBytes.Bytes())
+		// the signing read drained the serialized body
+		body = bytes.NewReader(bodyBytes.Bytes())
+		invocation.RequestBody = bodyBytes.Bytes()
 	}
 	headers.Set("OK-ACCESS-SIGN", c.Sign([]byte(prevSign)))
-	fmt.Println(url)
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return err
 	}
 	req.Header = headers
+	invocation.Request = req
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return err
+		return platforms.NetworkError(err)
 	}
 
+	defer resp.Body.Close()
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
+	invocation.StatusCode, invocation.Header, invocation.Response = resp.StatusCode, resp.Header, respBody
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("[Okx] bad status code: %d", resp.StatusCode)
 	}
@@ -23,6 +23,10 @@ const (
 	OrderPendingEndpoint        = "/api/v5/trade/orders-pending"
 	OrderCancelAllAfterEndpoint = "/api/v5/trade/cancel-all-after"
 	AccountBalanceEndpoint      = "/api/v5/account/balance"
+	FillsHistoryEndpoint        = "/api/v5/trade/fills-history"
+	OrderHistoryEndpoint        = "/api/v5/trade/orders-history"
+	TradeFeeEndpoint            = "/api/v5/account/trade-fee"
+	OrderAlgoEndpoint           = "/api/v5/trade/order-algo"
 )
 
 type TradeMode string
@@ -64,6 +68,13 @@ func (o OrderType) Convert() constants.OrderType {
 	}
 }
 
+type ExecType string
+
+const (
+	ExecTypeTaker ExecType = "T"
+	ExecTypeMaker ExecType = "M"
+)
+
 type OrderStatus string
 
 const (
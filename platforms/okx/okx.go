@@ -2,14 +2,19 @@ package okx
 
 import (
 	"fmt"
+	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
 	"net/http"
+	"strings"
 )
 
 type Connector struct {
 	*platforms.Credentials
 	Client *http.Client
+	platforms.Middlewares
+	platforms.Logging
 }
 
 func (c *Connector) Name() constants.Platform {
@@ -34,3 +39,49 @@ type RestReturn[T fmt.Stringer] struct {
 	OutTime string `json:"outTime"`
 	Data    []T    `json:"data"`
 }
+
+type TradeFee struct {
+	Category  string `json:"category"`
+	InstType  string `json:"instType"`
+	Level     string `json:"level"`
+	Maker     string `json:"maker"`     // USDT pairs; a negative number represents commission.
+	Taker     string `json:"taker"`     // USDT pairs; a negative number represents commission.
+	MakerUSDC string `json:"makerUSDC"` // USDC & crypto pairs
+	TakerUSDC string `json:"takerUSDC"` // USDC & crypto pairs
+	Timestamp string `json:"ts"`
+}
+
+func (f TradeFee) String() string {
+	return f.Level
+}
+
+func (c *Connector) GetFeeRates(symbols []string) (map[string]types.FeeRateEntry, error) {
+	var result = make(map[string]types.FeeRateEntry, len(symbols))
+	for _, symbol := range symbols {
+		var resp RestReturn[TradeFee]
+		err := c.Call(http.MethodGet, TradeFeeEndpoint, &platforms.ObjectBody{
+			"instType": "SPOT",
+			"instId":   symbol,
+		}, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Code != "0" || len(resp.Data) == 0 {
+			return nil, fmt.Errorf("[Okx] code: %s, msg: %s", resp.Code, resp.Msg)
+		}
+		fee := resp.Data[0]
+		maker, taker := fee.Maker, fee.Taker
+		if !strings.HasSuffix(c.SymbolPattern(symbol), "-USDT") && fee.MakerUSDC != "" {
+			maker, taker = fee.MakerUSDC, fee.TakerUSDC
+		}
+		makerRate, _ := decimal.NewFromString(maker)
+		takerRate, _ := decimal.NewFromString(taker)
+		// okx reports commission as a negative number and rebates as positive
+		result[symbol] = types.FeeRateEntry{
+			Symbol: symbol,
+			Maker:  makerRate.Neg(),
+			Taker:  takerRate.Neg(),
+		}
+	}
+	return result, nil
+}
@@ -9,7 +9,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
 )
 
 func TestMarketStream(t *testing.T) {
@@ -63,3 +66,12 @@ func TestGetCandles(t *testing.T) {
 	}
 	fmt.Println(candles)
 }
+
+// QueryOrder reports the accumulated fill quantity as Filled, it was left empty before.
+func TestQueryOrderConvert(t *testing.T) {
+	var info OrderInfo
+	_ = utils.Json.UnmarshalFromString(`{"instId":"BTC-USDT","ordId":"1","sz":"2","accFillSz":"0.5","state":"partially_filled","cTime":"1700000000000","uTime":"1700000001000"}`, &info)
+	if order := info.convert(); !order.Filled.Equal(decimal.NewFromFloat(0.5)) || order.Status != constants.PartiallyFilled {
+		t.Errorf("unexpected order %+v", order)
+	}
+}
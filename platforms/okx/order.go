@@ -1,18 +1,20 @@
 package okx
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
-	"log"
-	"math"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type OrderReturn struct {
@@ -39,10 +41,14 @@ func (c *Connector) MatchOrderType(orderType constants.OrderType) types.OrderTyp
 }
 func (c *Connector) PlaceOrder(params types.OrderEntry) (string, error) {
 	var resp RestReturn[OrderReturn]
+	clientId := params.TradeNo
+	if clientId == "" {
+		clientId = platforms.NewClientId()
+	}
 	err := c.Call(http.MethodPost, OrderEndpoint, &platforms.ObjectBody{
 		"instId":  c.SymbolPattern(params.Symbol),
 		"tdMode":  CashMode,
-		"clOrdId": uuid.New().String(),
+		"clOrdId": clientId,
 		"side":    strings.ToLower(params.Side),
 		"ordType": c.MatchOrderType(params.Type),
 		"px":      params.Price.StringFixed(12),
@@ -54,52 +60,72 @@ func (c *Connector) PlaceOrder(params types.OrderEntry) (string, error) {
 	return resp.Data[0].OrderId, nil
 }
 
-func (c *Connector) BatchOrder(params []types.OrderEntry) ([]string, error) {
+// batchOrderLimit maximum orders per batch request
+const batchOrderLimit = 20
+
+// BatchOrder https://www.okx.com/docs-v5/en/#order-book-trading-trade-post-place-multiple-orders
+func (c *Connector) BatchOrder(params []types.OrderEntry) ([]types.BatchOrderResult, error) {
 	var orders = make(platforms.ArrayBody, len(params))
+	results := make([]types.BatchOrderResult, len(params))
 	for i, order := range params {
+		clientId := order.TradeNo
+		if clientId == "" {
+			// clOrdId is alphanumeric, up to 32 characters
+			clientId = platforms.NewClientId()
+		}
+		results[i].TradeNo = clientId
 		orders[i] = map[string]any{
 			"instId":  c.SymbolPattern(order.Symbol),
 			"tdMode":  CashMode,
-			"clOrdId": uuid.New().String(),
+			"clOrdId": clientId,
 			"side":    strings.ToLower(order.Side),
 			"ordType": c.MatchOrderType(order.Type),
 			"px":      order.Price.StringFixed(12),
 			"sz":      order.Quantity.StringFixed(2),
 		}
 	}
-	results := make([]string, len(orders))
-	const maxOrders = 20
-	numBatches := int(math.Ceil(float64(len(orders)) / float64(maxOrders)))
 	var mu sync.Mutex
 	var wg sync.WaitGroup
-
-	for i := 0; i < numBatches; i++ {
+	var errs []error
+	for i, chunk := range platforms.ChunkOrders(params, batchOrderLimit, false) {
 		wg.Add(1)
-		batchStart := i * maxOrders
-		batchEnd := int(math.Min(float64(batchStart+maxOrders), float64(len(orders))))
-		batchOrders := orders[batchStart:batchEnd]
-		go func(i int, batchOrders []map[string]interface{}) {
+		go func(i int, chunk []int) {
 			defer wg.Done()
-			var resp RestReturn[OrderReturn]
-			err := c.Call(http.MethodPost, OrderBatchEndpoint, &orders, constants.None, &resp)
-			if err != nil {
-				return
+			var batchOrders = make(platforms.ArrayBody, len(chunk))
+			for j, index := range chunk {
+				batchOrders[j] = orders[index]
 			}
+			var resp RestReturn[OrderReturn]
+			err := c.Call(http.MethodPost, OrderBatchEndpoint, &batchOrders, constants.Signed, &resp)
 			mu.Lock()
 			defer mu.Unlock()
+			// code 1 and 2 mean all or part of the orders failed, the reason is in sCode of each order
+			if err == nil && len(resp.Data) != len(chunk) {
+				err = fmt.Errorf("[Okx] code: %s, msg: %s", resp.Code, resp.Msg)
+			}
 			if err != nil {
-				log.Printf("Error in batch %d: %v\n", i, err)
-			} else {
-				// 将批次的订单 ID 填充到结果中，保证原始顺序
-				for j, order := range resp.Data {
-					results[batchStart+j] = order.OrderId
+				c.Logger().Warn("batch order failed", slog.String("platform", string(c.Name())), slog.Int("batch", i), slog.Int("orders", len(chunk)), slog.String("error", err.Error()))
+				errs = append(errs, err)
+				for _, index := range chunk {
+					results[index].Message = err.Error()
+				}
+				return
+			}
+			// data is in request order
+			for j, order := range resp.Data {
+				index := chunk[j]
+				if order.SCode != "0" {
+					results[index].Code = order.SCode
+					results[index].Message = order.SMsg
+					continue
 				}
+				results[index].OrderId = order.OrderId
 			}
-		}(i, batchOrders)
+		}(i, chunk)
 	}
 	wg.Wait()
 
-	return results, nil
+	return results, errors.Join(errs...)
 }
 
 type OrderInfo struct {
@@ -156,6 +182,9 @@ type OrderInfo struct {
 	RebateCcy          string        `json:"rebateCcy"`
 	TradeID            string        `json:"tradeId"` // Last traded ID
 	FeeCcy             string        `json:"feeCcy"`
+	FillFee            string        `json:"fillFee,omitempty"`    // Last fill fee, only pushed by orders channel
+	FillFeeCcy         string        `json:"fillFeeCcy,omitempty"` // Last fill fee currency, only pushed by orders channel
+	ExecType           string        `json:"execType,omitempty"`   // Liquidity taker or maker of the last fill, only pushed by orders channel
 }
 
 func (OrderInfo) String() string {
@@ -185,10 +214,15 @@ func (c *Connector) QueryOrder(symbol string, orderId string) (types.QueryOrder,
 	if err != nil {
 		return types.QueryOrder{}, err
 	}
+	return order.convert(), nil
+}
+
+func (order OrderInfo) convert() types.QueryOrder {
 	ct, _ := strconv.ParseInt(order.CreateTime, 10, 64)
 	ut, _ := strconv.ParseInt(order.UpdateTime, 10, 64)
 	price, _ := decimal.NewFromString(order.Price)
 	qty, _ := decimal.NewFromString(order.Qty)
+	filled, _ := decimal.NewFromString(order.AccFilledQty)
 	return types.QueryOrder{
 		Symbol:     order.Symbol,
 		Type:       OrderType(order.OrderType).Convert(),
@@ -200,7 +234,47 @@ func (c *Connector) QueryOrder(symbol string, orderId string) (types.QueryOrder,
 		UpdateTime: ut,
 		Price:      price,
 		Quantity:   qty,
-	}, nil
+		Filled:     filled,
+	}
+}
+
+const orderHistoryLimit = 100
+
+func (c *Connector) OrderHistory(ctx context.Context, symbol string, since, until int64, statuses []constants.OrderStatus) ([]types.QueryOrder, error) {
+	var result []types.QueryOrder
+	var req = &platforms.ObjectBody{
+		"instType": "SPOT",
+		"instId":   symbol,
+		"begin":    strconv.FormatInt(since, 10),
+		"end":      strconv.FormatInt(until, 10),
+		"limit":    orderHistoryLimit,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var resp RestReturn[OrderInfo]
+		err := c.Call(http.MethodGet, OrderHistoryEndpoint, req, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Code != "0" {
+			return nil, fmt.Errorf("[Okx] code: %s, msg: %s", resp.Code, resp.Msg)
+		}
+		for _, info := range resp.Data {
+			order := info.convert()
+			if len(statuses) > 0 && !slices.Contains(statuses, order.Status) {
+				continue
+			}
+			result = append(result, order)
+		}
+		if len(resp.Data) < orderHistoryLimit {
+			break
+		}
+		// records are returned newest first, page towards older orders
+		req.Set("after", resp.Data[len(resp.Data)-1].OrderId)
+	}
+	return result, nil
 }
 func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
 	var resp RestReturn[OrderReturn]
@@ -212,8 +286,11 @@ func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if len(resp.Data) == 0 {
+		return false, &platforms.ExchangeError{Exchange: "Okx", Code: resp.Code, Message: resp.Msg}
+	}
 	if resp.Data[0].SCode != "0" {
-		return false, fmt.Errorf("not support")
+		return false, orderError(resp.Data[0].SCode, resp.Data[0].SMsg)
 	}
 	return true, nil
 }
@@ -227,61 +304,139 @@ type CancelAll struct {
 func (CancelAll) String() string {
 	return ""
 }
-func (c *Connector) CancelAll(_ string) error {
+
+// CancelAllAfter cancel every pending order once timeout expires, 0 disarms the countdown.
+// okx accepts 10 to 120 seconds.
+// https://www.okx.com/docs-v5/en/#order-book-trading-trade-post-cancel-all-after
+func (c *Connector) CancelAllAfter(timeout time.Duration) error {
 	var resp RestReturn[CancelAll]
+	err := c.Call(http.MethodPost, OrderCancelAllAfterEndpoint, &platforms.ObjectBody{
+		"timeOut": strconv.FormatInt(int64(timeout/time.Second), 10),
+	}, constants.Signed, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Code != "0" {
+		return orderError(resp.Code, resp.Msg)
+	}
+	return nil
+}
 
-	return c.Call(http.MethodPost, OrderCancelAllAfterEndpoint, &platforms.ObjectBody{"timeOut": 0}, constants.None, &resp)
+// CancelAll okx has no cancel all endpoint, pending orders are canceled in batches.
+func (c *Connector) CancelAll(symbol string) error {
+	return c.cancelPending(symbol)
 }
 
-func (c *Connector) CancelByIds(symbol string, orderIds []string) (map[string]bool, error) {
-	var resp RestReturn[OrderReturn]
-	var orders = make(platforms.ArrayBody, len(orderIds))
-	var result = make(map[string]bool)
+// CancelAllSymbols cancel the pending orders of every spot symbol.
+func (c *Connector) CancelAllSymbols() error {
+	return c.cancelPending("")
+}
+
+func (c *Connector) cancelPending(symbol string) error {
+	orders, err := c.pendingOrders(symbol)
+	if err != nil {
+		return err
+	}
+	var items = make(platforms.ArrayBody, len(orders))
+	for i, order := range orders {
+		items[i] = map[string]any{
+			"instId": order.Symbol,
+			"ordId":  order.OrderId,
+		}
+	}
+	var errs []error
+	for _, result := range c.cancelBatch(items) {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Connector) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	var items = make(platforms.ArrayBody, len(orderIds))
 	for i, id := range orderIds {
-		orders[i] = map[string]any{
-			"instId": symbol,
+		items[i] = map[string]any{
+			"instId": c.SymbolPattern(symbol),
 			"ordId":  id,
 		}
 	}
-	err := c.Call(http.MethodPost, OrderCancelBatchEndpoint, &orders, constants.None, &resp)
-	if err != nil {
-		return nil, err
-	}
-	for _, d := range resp.Data {
-		result[d.OrderId] = d.SCode == "0"
+	return c.cancelBatch(items), nil
+}
+
+// cancelBatch https://www.okx.com/docs-v5/en/#order-book-trading-trade-post-cancel-multiple-orders
+func (c *Connector) cancelBatch(items platforms.ArrayBody) []types.CancelResult {
+	var results = make([]types.CancelResult, len(items))
+	for start := 0; start < len(items); start += batchOrderLimit {
+		end := min(start+batchOrderLimit, len(items))
+		batch := items[start:end]
+		var resp RestReturn[OrderReturn]
+		err := c.Call(http.MethodPost, OrderCancelBatchEndpoint, &batch, constants.None, &resp)
+		if err == nil && len(resp.Data) != len(batch) {
+			err = &platforms.ExchangeError{Exchange: "Okx", Code: resp.Code, Message: resp.Msg}
+		}
+		for j := range batch {
+			index := start + j
+			results[index].OrderId = batch[j]["ordId"].(string)
+			if err != nil {
+				results[index].Err = err
+				continue
+			}
+			// data is in request order
+			if resp.Data[j].SCode != "0" {
+				results[index].Err = orderError(resp.Data[j].SCode, resp.Data[j].SMsg)
+			}
+		}
 	}
-	return result, nil
+	return results
 }
-func (c *Connector) PendingOrders(symbol string) ([]types.OpenOrderEntry, error) {
-	var results []types.OpenOrderEntry
-	var req = &platforms.ObjectBody{"instType": "SPOT", "instId": symbol}
+
+// pendingOrders page through orders-pending, every spot symbol when symbol is empty.
+func (c *Connector) pendingOrders(symbol string) ([]OrderInfo, error) {
+	var results []OrderInfo
+	var req = &platforms.ObjectBody{"instType": "SPOT"}
+	if symbol != "" {
+		req.Set("instId", symbol)
+	}
 	for {
 		var resp RestReturn[OrderInfo]
-		err := c.Call(http.MethodPost, OrderPendingEndpoint, req, constants.None, &resp)
+		err := c.Call(http.MethodGet, OrderPendingEndpoint, req, constants.None, &resp)
 		if err != nil {
 			return nil, err
 		}
+		if resp.Code != "0" {
+			return nil, &platforms.ExchangeError{Exchange: "Okx", Code: resp.Code, Message: resp.Msg}
+		}
+		results = append(results, resp.Data...)
 		lens := len(resp.Data)
 		if lens < 100 {
 			break
 		}
-		for _, order := range resp.Data {
-			price, _ := decimal.NewFromString(order.Price)
-			qty, _ := decimal.NewFromString(order.Qty)
-			results = append(results, types.OpenOrderEntry{
-				Symbol:   order.Symbol,
-				Type:     OrderType(order.OrderType).Convert(),
-				Side:     strings.ToUpper(order.Side),
-				TradeNo:  order.ClientOrderId,
-				OrderId:  order.OrderId,
-				Price:    price,
-				Quantity: qty,
-				Status:   OrderStatus(order.State).Convert(),
-			})
-		}
-		(*req)["after"] = resp.Data[lens-1].OrderId
+		req.Set("after", resp.Data[lens-1].OrderId)
 	}
+	return results, nil
+}
 
+func (c *Connector) PendingOrders(symbol string) ([]types.OpenOrderEntry, error) {
+	orders, err := c.pendingOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	var results []types.OpenOrderEntry
+	for _, order := range orders {
+		price, _ := decimal.NewFromString(order.Price)
+		qty, _ := decimal.NewFromString(order.Qty)
+		results = append(results, types.OpenOrderEntry{
+			Symbol:   order.Symbol,
+			Type:     OrderType(order.OrderType).Convert(),
+			Side:     strings.ToUpper(order.Side),
+			TradeNo:  order.ClientOrderId,
+			OrderId:  order.OrderId,
+			Price:    price,
+			Quantity: qty,
+			Status:   OrderStatus(order.State).Convert(),
+		})
+	}
 	return results, nil
 }
 
@@ -366,3 +521,78 @@ func (c *Connector) Balance(symbols []string) (map[string]types.BalanceEntry, er
 	}
 	return result, nil
 }
+
+type Fill struct {
+	InstType string `json:"instType"`
+	Symbol   string `json:"instId"`
+	TradeID  string `json:"tradeId"`
+	OrderId  string `json:"ordId"`
+	ClOrdId  string `json:"clOrdId"`
+	BillId   string `json:"billId"`
+	Tag      string `json:"tag"`
+	FillPx   string `json:"fillPx"`
+	FillSz   string `json:"fillSz"`
+	Side     string `json:"side"`
+	PosSide  string `json:"posSide"`
+	ExecType string `json:"execType"`
+	FeeCcy   string `json:"feeCcy"`
+	Fee      string `json:"fee"` // negative number represents the user transaction fee charged by the platform.
+	Ts       string `json:"ts"`
+	FillTime string `json:"fillTime"`
+}
+
+func (f Fill) String() string {
+	return f.BillId
+}
+
+const fillsHistoryLimit = 100
+
+func (c *Connector) GetMyTrades(ctx context.Context, symbol string, since, until int64) ([]types.FillEntry, error) {
+	var result []types.FillEntry
+	var req = &platforms.ObjectBody{
+		"instType": "SPOT",
+		"instId":   symbol,
+		"begin":    strconv.FormatInt(since, 10),
+		"end":      strconv.FormatInt(until, 10),
+		"limit":    fillsHistoryLimit,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var resp RestReturn[Fill]
+		err := c.Call(http.MethodGet, FillsHistoryEndpoint, req, constants.Signed, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Code != "0" {
+			return nil, fmt.Errorf("[Okx] code: %s, msg: %s", resp.Code, resp.Msg)
+		}
+		for _, fill := range resp.Data {
+			price, _ := decimal.NewFromString(fill.FillPx)
+			qty, _ := decimal.NewFromString(fill.FillSz)
+			fee, _ := decimal.NewFromString(fill.Fee)
+			ts, _ := strconv.ParseInt(fill.Ts, 10, 64)
+			symbol, _ := constants.StandardizeSymbol(fill.Symbol)
+			result = append(result, types.FillEntry{
+				Symbol:      symbol,
+				TradeId:     fill.TradeID,
+				OrderId:     fill.OrderId,
+				TradeNo:     fill.ClOrdId,
+				Side:        strings.ToUpper(fill.Side),
+				Price:       price,
+				Quantity:    qty,
+				Fee:         fee.Neg(),
+				FeeCurrency: fill.FeeCcy,
+				IsMaker:     ExecType(fill.ExecType) == ExecTypeMaker,
+				Timestamp:   ts,
+			})
+		}
+		if len(resp.Data) < fillsHistoryLimit {
+			break
+		}
+		// records are returned newest first, page towards older bills
+		req.Set("after", resp.Data[len(resp.Data)-1].BillId)
+	}
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package okx
+
+import (
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"net/http"
+	"strings"
+)
+
+type AlgoReturn struct {
+	AlgoId      string `json:"algoId"`
+	AlgoClOrdId string `json:"algoClOrdId"`
+	SCode       string `json:"sCode"`
+	SMsg        string `json:"sMsg"`
+}
+
+func (AlgoReturn) String() string {
+	return ""
+}
+
+// orderPrice "-1" executes the triggered leg at market
+func orderPrice(order *types.OrderEntry) string {
+	if order.Price.IsZero() {
+		return "-1"
+	}
+	return order.Price.String()
+}
+
+// setExits take profit and stop loss fields shared by oco algo orders and attached algo orders.
+// Take profit triggers at StopPrice when set, otherwise at its limit price.
+func setExits(params map[string]any, list types.OrderListEntry) {
+	if tp := list.TakeProfit; tp != nil {
+		trigger := tp.StopPrice
+		if trigger.IsZero() {
+			trigger = tp.Price
+		}
+		params["tpTriggerPx"] = trigger.String()
+		params["tpOrdPx"] = orderPrice(tp)
+	}
+	if sl := list.StopLoss; sl != nil {
+		params["slTriggerPx"] = sl.StopPrice.String()
+		params["slOrdPx"] = orderPrice(sl)
+	}
+}
+
+// PlaceOrderList OCO is an oco algo order, OTO and OTOCO attach take profit and stop loss to the entry.
+// okx creates the exit orders only on trigger, so their ids are left empty.
+// https://www.okx.com/docs-v5/en/#order-book-trading-algo-trading-post-place-algo-order
+func (c *Connector) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	if err := platforms.ValidateOrderList(list); err != nil {
+		return types.OrderListResult{}, err
+	}
+	if list.Type == types.OCO {
+		params := platforms.ObjectBody{
+			"instId":  c.SymbolPattern(list.Symbol),
+			"tdMode":  CashMode,
+			"side":    strings.ToLower(list.TakeProfit.Side),
+			"ordType": "oco",
+			"sz":      list.TakeProfit.Quantity.String(),
+		}
+		setExits(params, list)
+		var resp RestReturn[AlgoReturn]
+		err := c.Call(http.MethodPost, OrderAlgoEndpoint, &params, constants.Signed, &resp)
+		if err != nil {
+			return types.OrderListResult{}, err
+		}
+		if len(resp.Data) == 0 {
+			return types.OrderListResult{}, orderError(resp.Code, resp.Msg)
+		}
+		if resp.Data[0].SCode != "0" {
+			return types.OrderListResult{}, orderError(resp.Data[0].SCode, resp.Data[0].SMsg)
+		}
+		return types.OrderListResult{ListId: resp.Data[0].AlgoId}, nil
+	}
+	clientId := list.Entry.TradeNo
+	if clientId == "" {
+		clientId = platforms.NewClientId()
+	}
+	var attached = make(map[string]any)
+	setExits(attached, list)
+	var resp RestReturn[OrderReturn]
+	err := c.Call(http.MethodPost, OrderEndpoint, &platforms.ObjectBody{
+		"instId":         c.SymbolPattern(list.Symbol),
+		"tdMode":         CashMode,
+		"clOrdId":        clientId,
+		"side":           strings.ToLower(list.Entry.Side),
+		"ordType":        c.MatchOrderType(list.Entry.Type),
+		"px":             list.Entry.Price.String(),
+		"sz":             list.Entry.Quantity.String(),
+		"attachAlgoOrds": []map[string]any{attached},
+	}, constants.Signed, &resp)
+	if err != nil {
+		return types.OrderListResult{}, err
+	}
+	if len(resp.Data) == 0 {
+		return types.OrderListResult{}, orderError(resp.Code, resp.Msg)
+	}
+	if resp.Data[0].SCode != "0" {
+		return types.OrderListResult{}, orderError(resp.Data[0].SCode, resp.Data[0].SMsg)
+	}
+	return types.OrderListResult{ListId: resp.Data[0].OrderId, EntryOrderId: resp.Data[0].OrderId}, nil
+}
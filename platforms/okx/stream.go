@@ -6,7 +6,6 @@ import (
 	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
-	"github.com/xavierzho/go-cexs/utils"
 )
 
 type MarketStream struct {
@@ -64,11 +63,12 @@ func (stream *MarketStream) DepthStream(ctx context.Context, symbol string, chan
 				}
 				var event StreamEvent[DepthEvent]
 
-				_ = utils.Json.Unmarshal(msg, &event)
+				_ = stream.Decode(msg, &event)
 				for _, e := range event.Data {
 					channel <- types.DepthEntry{
-						Asks: e.Asks,
-						Bids: e.Bids,
+						Asks:     e.Asks,
+						Bids:     e.Bids,
+						Snapshot: event.Action == "snapshot",
 					}
 				}
 			}
@@ -107,7 +107,7 @@ func (stream *MarketStream) CandleStream(ctx context.Context, symbol, interval s
 					continue
 				}
 				var event StreamEvent[CandleEvent]
-				_ = utils.Json.Unmarshal(msg, &event)
+				_ = stream.Decode(msg, &event)
 				for _, k := range event.Data {
 					list := append(k[:8])
 					var result = make(types.CandleEntry, len(list))
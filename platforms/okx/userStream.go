@@ -6,20 +6,39 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
 	"github.com/xavierzho/go-cexs/platforms"
 	"github.com/xavierzho/go-cexs/types"
 	"github.com/xavierzho/go-cexs/utils"
+	"log/slog"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 type UserDataStream struct {
 	*platforms.Credentials
 	*platforms.StreamBase
+	dispatcher    *platforms.Dispatcher
+	mux           sync.Mutex
+	subscriptions []map[string]any // channel args, replayed after re-login
+	heartbeat     sync.Once
 }
 
+const (
+	// heartbeatInterval okx drops the connection when nothing is sent for 30 seconds
+	heartbeatInterval = 20 * time.Second
+	reconnectAttempts = 3
+)
+
 func (stream *UserDataStream) Sign(timestamp int64) string {
-	mac := hmac.New(sha256.New, []byte(stream.APISecret))
+	return wsSign(stream.Credentials, timestamp)
+}
+
+func wsSign(cred *platforms.Credentials, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(cred.APISecret))
 	mac.Write([]byte(fmt.Sprintf("%dGET/users/self/verify", timestamp)))
 	return hex.EncodeToString(mac.Sum(nil))
 }
@@ -32,30 +51,41 @@ type LoginReturn struct {
 }
 
 func (stream *UserDataStream) Login() error {
+	err := stream.login()
+	if err != nil {
+		return err
+	}
+	stream.heartbeat.Do(func() {
+		go stream.keepAlive()
+	})
+	return nil
+}
+
+func (stream *UserDataStream) login() error {
 	err := stream.Connect(StreamAPI + PrivateChannel)
 	if err != nil {
 		return err
 	}
+	return wsLogin(stream.StreamBase, stream.Credentials)
+}
+
+// wsLogin authenticate a private connection, shared by user data streams and websocket order entry.
+func wsLogin(stream *platforms.StreamBase, cred *platforms.Credentials) error {
 	timestamp := time.Now().Unix()
-	err = stream.SendMessage(map[string]any{
+	data, err := stream.Request(map[string]any{
 		"op": "login",
 		"args": []map[string]any{
 			{
-				"apiKey":     stream.APIKey,
-				"passphrase": *stream.Option,
+				"apiKey":     cred.APIKey,
+				"passphrase": *cred.Option,
 				"timestamp":  strconv.Itoa(int(timestamp)),
-				"sign":       stream.Sign(timestamp),
+				"sign":       wsSign(cred, timestamp),
 			},
 		},
 	})
 	if err != nil {
 		return err
 	}
-
-	data, err := stream.ReadMessage()
-	if err != nil {
-		return err
-	}
 	var res LoginReturn
 	_ = utils.Json.Unmarshal(data, &res)
 	if res.Event == "error" {
@@ -64,24 +94,253 @@ func (stream *UserDataStream) Login() error {
 	return nil
 }
 
+// keepAlive send "ping" text frame, okx answers with "pong".
+// https://www.okx.com/docs-v5/en/#overview-websocket-connect
+func (stream *UserDataStream) keepAlive() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Done():
+			return
+		case <-ticker.C:
+			if err := stream.WriteText("ping"); err != nil {
+				stream.Logger().Warn("ping failed", slog.String("platform", string(constants.Okx)), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Reconnect dial and login again, then replay every subscription. The acks are left to the reader.
+func (stream *UserDataStream) Reconnect() error {
+	stream.mux.Lock()
+	defer stream.mux.Unlock()
+	var err error
+	for attempt := 0; attempt < reconnectAttempts; attempt++ {
+		stream.Logger().Info("reconnecting", slog.String("platform", string(constants.Okx)), slog.Int("attempt", attempt+1), slog.Int("attempts", reconnectAttempts))
+		if err = stream.login(); err != nil {
+			time.Sleep(time.Duration(attempt+1) * time.Second)
+			continue
+		}
+		for _, args := range stream.subscriptions {
+			if err = stream.WriteJSON(subscribePayload(stream.dispatcher.NextId(), args)); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("[Okx stream] failed to reconnect after %d attempts: %w", reconnectAttempts, err)
+}
+
+func subscribePayload(id string, args map[string]any) map[string]any {
+	return map[string]any{
+		"id":   id,
+		"op":   "subscribe",
+		"args": []map[string]any{args},
+	}
+}
+
+// route channel of a push, acks and errors carry an event instead.
+func route(msg []byte) string {
+	if utils.Json.Get(msg, "event").ToString() != "" {
+		return ""
+	}
+	return utils.Json.Get(msg, "arg", "channel").ToString()
+}
+
+// replyId okx echoes the id of a subscribe request in its ack or error.
+func replyId(msg []byte) (string, bool) {
+	id := utils.Json.Get(msg, "id").ToString()
+	return id, id != "" && utils.Json.Get(msg, "event").ToString() != ""
+}
+
+// subscribe channel args once for all the streams reading it, handle receives its pushes until ctx is done.
+func (stream *UserDataStream) subscribe(ctx context.Context, args map[string]any, handle func(msg []byte)) error {
+	return stream.dispatcher.Subscribe(ctx, args["channel"].(string), func() error {
+		id := stream.dispatcher.NextId()
+		reply, err := stream.dispatcher.Request(id, subscribePayload(id, args))
+		if err != nil {
+			return err
+		}
+		var ack LoginReturn
+		_ = utils.Json.Unmarshal(reply, &ack)
+		if ack.Event == "error" {
+			return fmt.Errorf("[Okx stream] code: %s, msg: %s", ack.Code, ack.Msg)
+		}
+		stream.mux.Lock()
+		stream.subscriptions = append(stream.subscriptions, args)
+		stream.mux.Unlock()
+		return nil
+	}, handle)
+}
+
+func (order OrderInfo) updateEntry() types.OrderUpdateEntry {
+	symbol, _ := constants.StandardizeSymbol(order.Symbol)
+	price, _ := decimal.NewFromString(order.Price)
+	qty, _ := decimal.NewFromString(order.Qty)
+	filled, _ := decimal.NewFromString(order.AccFilledQty)
+	avgPrice, _ := decimal.NewFromString(order.AvgPrice)
+	lastPrice, _ := decimal.NewFromString(order.LatestFillPrice)
+	lastQty, _ := decimal.NewFromString(order.LatestFillQty)
+	fee, _ := decimal.NewFromString(order.FillFee)
+	ut, _ := strconv.ParseInt(order.UpdateTime, 10, 64)
+	fillTime, _ := strconv.ParseInt(order.LatestFillTime, 10, 64)
+	return types.OrderUpdateEntry{
+		OrderId:          order.OrderId,
+		ClientOrderId:    order.ClientOrderId,
+		Status:           OrderStatus(order.State).Convert(),
+		Symbol:           symbol,
+		Side:             strings.ToUpper(order.Side),
+		Type:             OrderType(order.OrderType).Convert(),
+		Price:            price,
+		Quantity:         qty,
+		FilledQuantity:   filled,
+		FilledQuote:      avgPrice.Mul(filled),
+		LastFillPrice:    lastPrice,
+		LastFillQuantity: lastQty,
+		// okx reports deducted fee as negative
+		Fee:             fee.Neg(),
+		FeeAsset:        order.FillFeeCcy,
+		IsMaker:         ExecType(order.ExecType) == ExecTypeMaker,
+		TradeId:         order.TradeID,
+		RejectReason:    order.CancelSourceReason,
+		EventTime:       ut,
+		TransactionTime: fillTime,
+	}
+}
+
+// OrderStream subscribe orders channel, Login must be called first.
+// https://www.okx.com/docs-v5/en/#order-book-trading-trade-ws-order-channel
 func (stream *UserDataStream) OrderStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
-	//TODO implement me
-	panic("implement me")
+	return stream.subscribe(ctx, map[string]any{
+		"channel":  "orders",
+		"instType": "SPOT",
+	}, func(msg []byte) {
+		var event StreamEvent[OrderInfo]
+		if err := stream.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, order := range event.Data {
+			if !platforms.Deliver(ctx, channel, order.updateEntry()) {
+				return
+			}
+		}
+	})
+}
+
+type BalanceAndPosition struct {
+	PushTime  string `json:"pTime"`
+	EventType string `json:"eventType"`
+	BalData   []struct {
+		Ccy     string `json:"ccy"`
+		CashBal string `json:"cashBal"`
+		UTime   string `json:"uTime"`
+	} `json:"balData"`
 }
 
+func (BalanceAndPosition) String() string {
+	return ""
+}
+
+// BalanceStream subscribe balance_and_position channel, trade fills are left to AccountStream.
+// The channel only pushes cash balance, which is reported as Free.
+// https://www.okx.com/docs-v5/en/#trading-account-websocket-balance-and-position-channel
 func (stream *UserDataStream) BalanceStream(ctx context.Context, channel chan<- types.BalanceUpdateEntry) error {
-	//TODO implement me
-	panic("implement me")
+	var last = make(map[string]decimal.Decimal)
+	return stream.subscribe(ctx, map[string]any{
+		"channel": "balance_and_position",
+	}, func(msg []byte) {
+		var event StreamEvent[BalanceAndPosition]
+		if err := stream.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, e := range event.Data {
+			for _, bal := range e.BalData {
+				cash, _ := decimal.NewFromString(bal.CashBal)
+				ut, _ := strconv.ParseInt(bal.UTime, 10, 64)
+				var delta decimal.Decimal
+				if previous, ok := last[bal.Ccy]; ok {
+					delta = cash.Sub(previous)
+				}
+				last[bal.Ccy] = cash
+				if e.EventType == "filled" {
+					continue
+				}
+				if !platforms.Deliver(ctx, channel, types.BalanceUpdateEntry{
+					Asset:     bal.Ccy,
+					Free:      cash,
+					Delta:     delta,
+					Reason:    e.EventType,
+					Timestamp: ut,
+				}) {
+					return
+				}
+			}
+		}
+	})
 }
 
+type AccountEvent struct {
+	UTime   string `json:"uTime"`
+	Details []struct {
+		Ccy       string `json:"ccy"`
+		AvailBal  string `json:"availBal"`
+		FrozenBal string `json:"frozenBal"`
+		CashBal   string `json:"cashBal"`
+		UTime     string `json:"uTime"`
+	} `json:"details"`
+}
+
+func (AccountEvent) String() string {
+	return ""
+}
+
+// AccountStream subscribe account channel, okx pushes no reason so it is left empty.
+// https://www.okx.com/docs-v5/en/#trading-account-websocket-account-channel
 func (stream *UserDataStream) AccountStream(ctx context.Context, channel chan<- types.AccountUpdateEntry) error {
-	//TODO implement me
-	panic("implement me")
+	var last = make(map[string]decimal.Decimal)
+	return stream.subscribe(ctx, map[string]any{
+		"channel": "account",
+	}, func(msg []byte) {
+		var event StreamEvent[AccountEvent]
+		if err := stream.Decode(msg, &event); err != nil {
+			return
+		}
+		for _, e := range event.Data {
+			for _, detail := range e.Details {
+				free, _ := decimal.NewFromString(detail.AvailBal)
+				locked, _ := decimal.NewFromString(detail.FrozenBal)
+				cash, _ := decimal.NewFromString(detail.CashBal)
+				ut, _ := strconv.ParseInt(detail.UTime, 10, 64)
+				var delta decimal.Decimal
+				if previous, ok := last[detail.Ccy]; ok {
+					delta = cash.Sub(previous)
+				}
+				last[detail.Ccy] = cash
+				if !platforms.Deliver(ctx, channel, types.AccountUpdateEntry{
+					Asset:     detail.Ccy,
+					Free:      free,
+					Locked:    locked,
+					Delta:     delta,
+					Timestamp: ut,
+				}) {
+					return
+				}
+			}
+		}
+	})
 }
 
 func NewUserStream(cred *platforms.Credentials) platforms.UserDataStreamer {
-	return &UserDataStream{
+	stream := &UserDataStream{
 		StreamBase:  platforms.NewStream(),
 		Credentials: cred,
 	}
+	stream.dispatcher = platforms.NewDispatcher(stream.StreamBase, route)
+	stream.dispatcher.ReplyId = replyId
+	stream.dispatcher.Recover = stream.Reconnect
+	return stream
 }
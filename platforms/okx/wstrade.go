@@ -0,0 +1,97 @@
+package okx
+
+import (
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"github.com/xavierzho/go-cexs/utils"
+	"net/http"
+	"strings"
+)
+
+// WsOrderProtocol order entry over the private channel.
+// https://www.okx.com/docs-v5/en/#order-book-trading-trade-ws-place-order
+type WsOrderProtocol struct {
+	*Connector
+}
+
+func NewWsTrader(cred *platforms.Credentials, client *http.Client) *platforms.WsTrader {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	connector := &Connector{Credentials: cred, Client: client}
+	return platforms.NewWsTrader(connector, &WsOrderProtocol{connector})
+}
+
+func (p *WsOrderProtocol) URL() string {
+	return StreamAPI + PrivateChannel
+}
+
+func (p *WsOrderProtocol) Login(stream *platforms.StreamBase) error {
+	return wsLogin(stream, p.Credentials)
+}
+
+// Ping okx drops connections idle for 30 seconds
+func (p *WsOrderProtocol) Ping() string {
+	return "ping"
+}
+
+func (p *WsOrderProtocol) PlaceOrder(id string, order types.OrderEntry) map[string]any {
+	clientId := order.TradeNo
+	if clientId == "" {
+		clientId = platforms.NewClientId()
+	}
+	return map[string]any{
+		"id": id,
+		"op": "order",
+		"args": []map[string]any{{
+			"instId":  p.SymbolPattern(order.Symbol),
+			"tdMode":  CashMode,
+			"clOrdId": clientId,
+			"side":    strings.ToLower(order.Side),
+			"ordType": p.MatchOrderType(order.Type),
+			"px":      order.Price.StringFixed(12),
+			"sz":      order.Quantity.StringFixed(2),
+		}},
+	}
+}
+
+func (p *WsOrderProtocol) Cancel(id, symbol, orderId string) map[string]any {
+	return map[string]any{
+		"id": id,
+		"op": "cancel-order",
+		"args": []map[string]any{{
+			"instId": p.SymbolPattern(symbol),
+			"ordId":  orderId,
+		}},
+	}
+}
+
+type WsReply struct {
+	Id   string        `json:"id"`
+	Op   string        `json:"op"`
+	Code string        `json:"code"`
+	Msg  string        `json:"msg"`
+	Data []OrderReturn `json:"data"`
+}
+
+func (p *WsOrderProtocol) ReplyId(msg []byte) (string, bool) {
+	var reply WsReply
+	if err := utils.Json.Unmarshal(msg, &reply); err != nil || reply.Id == "" {
+		return "", false
+	}
+	return reply.Id, true
+}
+
+func (p *WsOrderProtocol) OrderId(msg []byte) (string, error) {
+	var reply WsReply
+	if err := utils.Json.Unmarshal(msg, &reply); err != nil {
+		return "", err
+	}
+	if len(reply.Data) == 0 {
+		return "", orderError(reply.Code, reply.Msg)
+	}
+	if reply.Data[0].SCode != "0" {
+		return "", orderError(reply.Data[0].SCode, reply.Data[0].SMsg)
+	}
+	return reply.Data[0].OrderId, nil
+}
@@ -0,0 +1,519 @@
+// Package oms keeps the authoritative state of the account's orders on one exchange,
+// combining REST replies, the order stream and periodic reconciliation.
+package oms
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// State lifecycle state of an order.
+type State string
+
+const (
+	// PendingNew sent, not acknowledged by the exchange yet
+	PendingNew      State = "PENDING_NEW"
+	Open            State = "OPEN"
+	PartiallyFilled State = "PARTIALLY_FILLED"
+	Filled          State = "FILLED"
+	Canceled        State = "CANCELED"
+	Rejected        State = "REJECTED"
+	// Unknown a status the OMS does not map, it never moves an order
+	Unknown State = "UNKNOWN"
+)
+
+// rank orders the states, an order never moves back to a lower rank
+func (s State) rank() int {
+	switch s {
+	case Unknown:
+		return -1
+	case PendingNew:
+		return 0
+	case Open:
+		return 1
+	case PartiallyFilled:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Terminal the order can not change anymore.
+func (s State) Terminal() bool {
+	return s.rank() == 3
+}
+
+func stateOf(status constants.OrderStatus) State {
+	switch status {
+	case constants.Open:
+		return Open
+	case constants.PartiallyFilled:
+		return PartiallyFilled
+	case constants.Filled:
+		return Filled
+	case constants.Canceled, constants.PartiallyCanceled:
+		return Canceled
+	case constants.Error:
+		return Rejected
+	}
+	return Unknown
+}
+
+// Order tracked order.
+type Order struct {
+	// ClientId client order id sent on PlaceOrder, empty for orders found on the exchange
+	ClientId string
+	OrderId  string
+	Symbol   string
+	Side     string
+	Type     constants.OrderType
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+	State    State
+	// Filled cumulative base quantity, FilledQuote cumulative quote amount when reported
+	Filled      decimal.Decimal
+	FilledQuote decimal.Decimal
+	// Reason of a rejection
+	Reason string
+	// CreateTime, UpdateTime in milliseconds
+	CreateTime int64
+	UpdateTime int64
+}
+
+// Source where a change was learned from.
+type Source string
+
+const (
+	SourceRest      Source = "rest"
+	SourceStream    Source = "stream"
+	SourceReconcile Source = "reconcile"
+)
+
+// Event a change of an order, Previous is empty for a new order.
+type Event struct {
+	Order    Order
+	Previous State
+	Source   Source
+}
+
+// OMS order management on top of a SpotConnector and its UserDataStreamer.
+type OMS struct {
+	connector platforms.SpotConnector
+	streamer  platforms.UserDataStreamer
+	// ReconcileInterval between reconciliations against PendingOrders and QueryOrder, default 30s
+	// and also used when not positive
+	ReconcileInterval time.Duration
+	// Symbols reconciled even without tracked open orders, so orders placed elsewhere are adopted
+	Symbols []string
+
+	mux     sync.Mutex
+	orders  map[string]*Order
+	pending map[string]*Order // by ClientId until acknowledged or seen on the stream
+	// lost by ClientId, placements whose reply was lost in transit until found on the stream or by Reconcile
+	lost        map[string]*Order
+	subscribers []chan Event
+	// dispatch keeps events in the order the changes were made
+	dispatch sync.Mutex
+}
+
+const defaultReconcileInterval = 30 * time.Second
+
+func New(connector platforms.SpotConnector, streamer platforms.UserDataStreamer) *OMS {
+	return &OMS{
+		connector:         connector,
+		streamer:          streamer,
+		ReconcileInterval: defaultReconcileInterval,
+		orders:            make(map[string]*Order),
+		pending:           make(map[string]*Order),
+		lost:              make(map[string]*Order),
+	}
+}
+
+// subscriberBuffer events a subscriber may lag behind before the OMS blocks
+const subscriberBuffer = 256
+
+// Subscribe order lifecycle events, the channel must be drained.
+func (o *OMS) Subscribe() <-chan Event {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	subscriber := make(chan Event, subscriberBuffer)
+	o.subscribers = append(o.subscribers, subscriber)
+	return subscriber
+}
+
+// Run follow the order stream and reconcile every ReconcileInterval until ctx is done.
+func (o *OMS) Run(ctx context.Context) error {
+	updates := make(chan types.OrderUpdateEntry, 64)
+	if err := o.streamer.OrderStream(ctx, updates); err != nil {
+		return err
+	}
+	interval := o.ReconcileInterval
+	if interval <= 0 {
+		interval = defaultReconcileInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case update := <-updates:
+			o.OnUpdate(update)
+		case <-ticker.C:
+			o.Reconcile()
+		}
+	}
+}
+
+// PlaceOrder send order and track it. A refused order is tracked as Rejected and returned with the error.
+// The entry's TradeNo is the client order id, one is generated when empty so stream updates
+// arriving before the reply are matched to the order. An order whose reply was lost in transit,
+// a platforms.ErrNetwork, stays PendingNew until the stream or Reconcile finds it by client id.
+func (o *OMS) PlaceOrder(entry types.OrderEntry) (Order, error) {
+	if entry.TradeNo == "" {
+		// 24 hex characters fit the client order id of every venue
+		entry.TradeNo = platforms.NewClientId()
+	}
+	now := time.Now().UnixMilli()
+	order := &Order{
+		ClientId:   entry.TradeNo,
+		Symbol:     entry.Symbol,
+		Side:       entry.Side,
+		Type:       entry.Type,
+		Price:      entry.Price,
+		Quantity:   entry.Quantity,
+		State:      PendingNew,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	o.mux.Lock()
+	o.pending[order.ClientId] = order
+	o.commit(Event{Order: *order, Source: SourceRest})
+
+	orderId, err := o.connector.PlaceOrder(entry)
+
+	o.mux.Lock()
+	delete(o.pending, order.ClientId)
+	if err != nil && order.OrderId != "" {
+		// the stream saw the order, it exists whatever the reply said
+		snapshot := *order
+		o.commit()
+		return snapshot, err
+	}
+	if errors.Is(err, platforms.ErrNetwork) {
+		// the order may have reached the exchange
+		o.lost[order.ClientId] = order
+		snapshot := *order
+		o.commit()
+		return snapshot, err
+	}
+	if err != nil {
+		previous := order.State
+		order.State, order.Reason, order.UpdateTime = Rejected, err.Error(), time.Now().UnixMilli()
+		o.commit(Event{Order: *order, Previous: previous, Source: SourceRest})
+		return *order, err
+	}
+	order.OrderId = orderId
+	if known, ok := o.orders[orderId]; ok {
+		// the stream was faster than the reply, keep its progress
+		known.ClientId, known.CreateTime = order.ClientId, order.CreateTime
+		snapshot := *known
+		o.commit()
+		return snapshot, nil
+	}
+	order.State, order.UpdateTime = Open, time.Now().UnixMilli()
+	o.orders[orderId] = order
+	o.commit(Event{Order: *order, Previous: PendingNew, Source: SourceRest})
+	return *order, nil
+}
+
+// Cancel cancel a tracked order. An accepted cancel does not mean the order is canceled, it may
+// have filled meanwhile: the order is resolved from QueryOrder, and from the stream when it is
+// still closing on the exchange. An order already gone is resolved from QueryOrder as well.
+func (o *OMS) Cancel(symbol, orderId string) error {
+	_, err := o.connector.Cancel(symbol, orderId)
+	if err == nil || errors.Is(err, platforms.ErrOrderNotFound) || errors.Is(err, platforms.ErrOrderFilled) ||
+		errors.Is(err, platforms.ErrOrderClosed) {
+		o.query(symbol, orderId)
+	}
+	return err
+}
+
+// Order snapshot of a tracked order.
+func (o *OMS) Order(orderId string) (Order, bool) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	order, ok := o.orders[orderId]
+	if !ok {
+		return Order{}, false
+	}
+	return *order, true
+}
+
+// Orders snapshot of the tracked orders, terminal ones included when all is set.
+func (o *OMS) Orders(all bool) []Order {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	var orders []Order
+	for _, order := range o.pending {
+		orders = append(orders, *order)
+	}
+	for _, order := range o.lost {
+		orders = append(orders, *order)
+	}
+	for _, order := range o.orders {
+		if all || !order.State.Terminal() {
+			orders = append(orders, *order)
+		}
+	}
+	return orders
+}
+
+// OnUpdate apply a stream update. Duplicates and updates older than the known state are ignored,
+// updates of orders still waiting for their PlaceOrder reply, or whose reply was lost,
+// are matched by client order id, other orders unknown to the OMS are adopted.
+func (o *OMS) OnUpdate(update types.OrderUpdateEntry) {
+	if update.OrderId == "" {
+		return
+	}
+	o.mux.Lock()
+	order, ok := o.orders[update.OrderId]
+	if !ok {
+		order, ok = o.sent(update.ClientOrderId, update.OrderId)
+	}
+	if !ok {
+		order = &Order{
+			OrderId:    update.OrderId,
+			Symbol:     update.Symbol,
+			Side:       update.Side,
+			Type:       update.Type,
+			Price:      update.Price,
+			Quantity:   update.Quantity,
+			State:      PendingNew,
+			CreateTime: update.TransactionTime,
+		}
+		o.orders[update.OrderId] = order
+	}
+	state := stateOf(update.Status)
+	if state == Rejected && update.RejectReason != "" {
+		order.Reason = update.RejectReason
+	}
+	event, changed := o.advance(order, state, update.FilledQuantity, update.FilledQuote, SourceStream)
+	if !ok {
+		event.Previous = ""
+	}
+	if changed || !ok {
+		o.commit(event)
+		return
+	}
+	o.commit()
+}
+
+// sent the order placed with clientId, now known as orderId, nil when the OMS did not send it.
+// The caller holds mux.
+func (o *OMS) sent(clientId, orderId string) (*Order, bool) {
+	if clientId == "" {
+		return nil, false
+	}
+	order, ok := o.pending[clientId]
+	if ok {
+		delete(o.pending, clientId)
+	} else if order, ok = o.lost[clientId]; ok {
+		delete(o.lost, clientId)
+	} else {
+		return nil, false
+	}
+	order.OrderId = orderId
+	o.orders[orderId] = order
+	return order, true
+}
+
+// Reconcile compare the tracked orders with PendingOrders of their symbols and Symbols.
+// Tracked orders no longer pending are resolved with QueryOrder, unknown pending orders are adopted.
+// Orders whose PlaceOrder reply was lost are looked up by client id in PendingOrders and OrderHistory,
+// those still missing after ReconcileInterval are Rejected.
+func (o *OMS) Reconcile() {
+	o.mux.Lock()
+	symbols := make(map[string]struct{})
+	for _, symbol := range o.Symbols {
+		symbols[symbol] = struct{}{}
+	}
+	for _, order := range o.orders {
+		if !order.State.Terminal() {
+			symbols[order.Symbol] = struct{}{}
+		}
+	}
+	for _, order := range o.lost {
+		symbols[order.Symbol] = struct{}{}
+	}
+	o.mux.Unlock()
+	for symbol := range symbols {
+		pending, err := o.connector.PendingOrders(symbol)
+		if err != nil {
+			platforms.LoggerOf(o.connector).Warn("reconcile pending orders failed", slog.String("platform", string(o.connector.Name())), slog.String("symbol", symbol), slog.String("error", err.Error()))
+			continue
+		}
+		open := make(map[string]struct{}, len(pending))
+		var events []Event
+		o.mux.Lock()
+		for _, entry := range pending {
+			open[entry.OrderId] = struct{}{}
+			order, ok := o.orders[entry.OrderId]
+			if !ok {
+				order, ok = o.sent(entry.TradeNo, entry.OrderId)
+			}
+			if !ok {
+				order = &Order{
+					OrderId:    entry.OrderId,
+					Symbol:     entry.Symbol,
+					Side:       entry.Side,
+					Type:       entry.Type,
+					Price:      entry.Price,
+					Quantity:   entry.Quantity,
+					State:      PendingNew,
+					CreateTime: time.Now().UnixMilli(),
+				}
+				o.orders[entry.OrderId] = order
+			}
+			event, changed := o.advance(order, stateOf(entry.Status), decimal.Zero, decimal.Zero, SourceReconcile)
+			if !ok {
+				event.Previous = ""
+			}
+			if changed || !ok {
+				events = append(events, event)
+			}
+		}
+		var gone []string
+		for id, order := range o.orders {
+			if _, ok := open[id]; !ok && order.Symbol == symbol && !order.State.Terminal() {
+				gone = append(gone, id)
+			}
+		}
+		o.commit(events...)
+		for _, id := range gone {
+			o.query(symbol, id)
+		}
+		o.resolve(symbol)
+	}
+}
+
+// resolve the lost placements of symbol from OrderHistory.
+func (o *OMS) resolve(symbol string) {
+	o.mux.Lock()
+	var since int64
+	var lost []string
+	for clientId, order := range o.lost {
+		if order.Symbol == symbol {
+			lost = append(lost, clientId)
+			if since == 0 || order.CreateTime < since {
+				since = order.CreateTime
+			}
+		}
+	}
+	o.mux.Unlock()
+	if len(lost) == 0 {
+		return
+	}
+	history, err := o.connector.OrderHistory(context.Background(), symbol, since, time.Now().UnixMilli(), nil)
+	if err != nil {
+		platforms.LoggerOf(o.connector).Warn("reconcile order history failed", slog.String("platform", string(o.connector.Name())), slog.String("symbol", symbol), slog.String("error", err.Error()))
+		return
+	}
+	found := make(map[string]types.QueryOrder, len(history))
+	for _, result := range history {
+		if result.TradeNo != "" {
+			found[result.TradeNo] = result
+		}
+	}
+	interval := o.ReconcileInterval
+	if interval <= 0 {
+		interval = defaultReconcileInterval
+	}
+	var events []Event
+	o.mux.Lock()
+	for _, clientId := range lost {
+		order, ok := o.lost[clientId]
+		if !ok {
+			continue // found on the stream meanwhile
+		}
+		if result, ok := found[clientId]; ok {
+			o.sent(clientId, result.OrderId)
+			if event, changed := o.advance(order, stateOf(result.Status), result.Filled, decimal.Zero, SourceReconcile); changed {
+				events = append(events, event)
+			}
+			continue
+		}
+		if time.Since(time.UnixMilli(order.CreateTime)) < interval {
+			continue // the request may still be on its way
+		}
+		delete(o.lost, clientId)
+		order.State, order.Reason, order.UpdateTime = Rejected, "not found on the exchange", time.Now().UnixMilli()
+		events = append(events, Event{Order: *order, Previous: PendingNew, Source: SourceReconcile})
+	}
+	o.commit(events...)
+}
+
+// query resolve an order from QueryOrder.
+func (o *OMS) query(symbol, orderId string) {
+	result, err := o.connector.QueryOrder(symbol, orderId)
+	if err != nil {
+		platforms.LoggerOf(o.connector).Warn("reconcile order failed", slog.String("platform", string(o.connector.Name())), slog.String("symbol", symbol), slog.String("order", orderId), slog.String("error", err.Error()))
+		return
+	}
+	o.mux.Lock()
+	order, ok := o.orders[orderId]
+	if !ok {
+		o.mux.Unlock()
+		return
+	}
+	event, changed := o.advance(order, stateOf(result.Status), result.Filled, decimal.Zero, SourceReconcile)
+	if changed {
+		o.commit(event)
+		return
+	}
+	o.commit()
+}
+
+// advance move order forward, the caller holds mux. Fill quantities only grow and
+// the state never goes back, so stale or repeated reports leave the order untouched.
+func (o *OMS) advance(order *Order, state State, filled, filledQuote decimal.Decimal, source Source) (Event, bool) {
+	previous := order.State
+	changed := false
+	if filled.GreaterThan(order.Filled) {
+		order.Filled, changed = filled, true
+		if !state.Terminal() && state.rank() < PartiallyFilled.rank() {
+			state = PartiallyFilled
+		}
+	}
+	if filledQuote.GreaterThan(order.FilledQuote) {
+		order.FilledQuote, changed = filledQuote, true
+	}
+	if !order.State.Terminal() && state.rank() > order.State.rank() {
+		order.State, changed = state, true
+	}
+	if changed {
+		order.UpdateTime = time.Now().UnixMilli()
+	}
+	return Event{Order: *order, Previous: previous, Source: source}, changed
+}
+
+// commit release mux and deliver events in order, the caller holds mux.
+func (o *OMS) commit(events ...Event) {
+	subscribers := o.subscribers
+	o.dispatch.Lock()
+	o.mux.Unlock()
+	defer o.dispatch.Unlock()
+	for _, event := range events {
+		for _, subscriber := range subscribers {
+			subscriber <- event
+		}
+	}
+}
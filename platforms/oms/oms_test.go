@@ -0,0 +1,217 @@
+package oms
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+type exchange struct {
+	platforms.SpotConnector
+	pending []types.OpenOrderEntry
+	orders  map[string]types.QueryOrder
+	// onPlace runs before the reply, e.g. to deliver the stream update first
+	onPlace func(orderId string)
+	placed  []types.OrderEntry
+	// placeErr returned by PlaceOrder, history by OrderHistory
+	placeErr error
+	history  []types.QueryOrder
+}
+
+func (e *exchange) Name() constants.Platform { return "test" }
+
+func (e *exchange) PlaceOrder(entry types.OrderEntry) (string, error) {
+	e.placed = append(e.placed, entry)
+	if e.onPlace != nil {
+		e.onPlace("1")
+	}
+	if e.placeErr != nil {
+		return "", e.placeErr
+	}
+	return "1", nil
+}
+
+func (e *exchange) Cancel(string, string) (bool, error) {
+	return true, nil
+}
+
+func (e *exchange) OrderHistory(context.Context, string, int64, int64, []constants.OrderStatus) ([]types.QueryOrder, error) {
+	return e.history, nil
+}
+
+func (e *exchange) PendingOrders(string) ([]types.OpenOrderEntry, error) {
+	return e.pending, nil
+}
+
+func (e *exchange) QueryOrder(_ string, orderId string) (types.QueryOrder, error) {
+	return e.orders[orderId], nil
+}
+
+func TestOMS(t *testing.T) {
+	connector := &exchange{orders: make(map[string]types.QueryOrder)}
+	oms := New(connector, nil)
+	events := oms.Subscribe()
+	quantity := decimal.NewFromInt(2)
+	// the fill arrives before the PlaceOrder reply
+	connector.onPlace = func(orderId string) {
+		oms.OnUpdate(types.OrderUpdateEntry{OrderId: orderId, Symbol: "BTCUSDT", Status: constants.PartiallyFilled,
+			Quantity: quantity, FilledQuantity: decimal.NewFromInt(1)})
+	}
+	order, err := oms.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", Side: "BUY", Quantity: quantity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.State != PartiallyFilled || order.ClientId == "" {
+		t.Errorf("unexpected order %+v", order)
+	}
+	// stale and repeated updates change nothing
+	oms.OnUpdate(types.OrderUpdateEntry{OrderId: "1", Status: constants.Open})
+	oms.OnUpdate(types.OrderUpdateEntry{OrderId: "1", Status: constants.PartiallyFilled, FilledQuantity: decimal.NewFromInt(1)})
+
+	// the order left the book while the stream was down
+	connector.orders["1"] = types.QueryOrder{OrderId: "1", Status: constants.Filled, Filled: quantity}
+	connector.pending = []types.OpenOrderEntry{{OrderId: "2", Symbol: "BTCUSDT", Status: constants.Open}}
+	oms.Reconcile()
+
+	if order, _ = oms.Order("1"); order.State != Filled || !order.Filled.Equal(quantity) {
+		t.Errorf("order not reconciled %+v", order)
+	}
+	if order, ok := oms.Order("2"); !ok || order.State != Open {
+		t.Errorf("pending order not adopted %+v", order)
+	}
+	var states []State
+	for len(events) > 0 {
+		event := <-events
+		states = append(states, event.Order.State)
+	}
+	want := []State{PendingNew, PartiallyFilled, Open, Filled}
+	if len(states) != len(want) {
+		t.Fatalf("want events %v, got %v", want, states)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("want events %v, got %v", want, states)
+			break
+		}
+	}
+}
+
+func TestClientOrderId(t *testing.T) {
+	connector := &exchange{orders: make(map[string]types.QueryOrder)}
+	oms := New(connector, nil)
+	events := oms.Subscribe()
+	// the stream reports the order by client id before the reply
+	connector.onPlace = func(orderId string) {
+		oms.OnUpdate(types.OrderUpdateEntry{OrderId: orderId, ClientOrderId: connector.placed[0].TradeNo, Symbol: "BTCUSDT", Status: constants.Open})
+	}
+	order, err := oms.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := connector.placed[0].TradeNo
+	if len(sent) != 24 || order.ClientId != sent || order.OrderId != "1" || order.State != Open {
+		t.Errorf("unexpected order %+v, client id sent %q", order, sent)
+	}
+	if orders := oms.Orders(true); len(orders) != 1 {
+		t.Errorf("order tracked twice %+v", orders)
+	}
+	// a TradeNo given by the caller is kept
+	connector.onPlace = nil
+	if order, _ = oms.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", TradeNo: "mine"}); order.ClientId != "mine" || connector.placed[1].TradeNo != "mine" {
+		t.Errorf("caller's client id not kept %+v", order)
+	}
+
+	// no order id or an unmapped status change nothing
+	oms.OnUpdate(types.OrderUpdateEntry{Symbol: "BTCUSDT", Status: constants.Open})
+	oms.OnUpdate(types.OrderUpdateEntry{OrderId: "1", Status: 42})
+	if _, ok := oms.Order(""); ok {
+		t.Error("update without order id tracked")
+	}
+	if order, _ = oms.Order("1"); order.State != Open {
+		t.Errorf("unmapped status moved the order %+v", order)
+	}
+	var previous []State
+	for len(events) > 0 {
+		event := <-events
+		if event.Order.ClientId == sent {
+			previous = append(previous, event.Previous)
+		}
+	}
+	if len(previous) != 2 || previous[0] != "" || previous[1] != PendingNew {
+		t.Errorf("unexpected transitions from %v", previous)
+	}
+}
+
+func TestLostPlacement(t *testing.T) {
+	connector := &exchange{orders: make(map[string]types.QueryOrder), placeErr: platforms.NetworkError(errors.New("timeout"))}
+	oms := New(connector, nil)
+	order, err := oms.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromInt(1)})
+	if !errors.Is(err, platforms.ErrNetwork) || order.State != PendingNew {
+		t.Fatalf("lost placement not kept pending %+v: %v", order, err)
+	}
+	// not on the exchange yet, the request may still be on its way
+	oms.Reconcile()
+	if orders := oms.Orders(false); len(orders) != 1 || orders[0].State != PendingNew {
+		t.Fatalf("lost placement resolved too early %+v", orders)
+	}
+	connector.history = []types.QueryOrder{{OrderId: "7", TradeNo: order.ClientId, Symbol: "BTCUSDT",
+		Status: constants.Filled, Filled: decimal.NewFromInt(1)}}
+	oms.Reconcile()
+	if order, ok := oms.Order("7"); !ok || order.State != Filled || order.ClientId != connector.placed[0].TradeNo {
+		t.Errorf("lost placement not found by client id %+v", order)
+	}
+	if orders := oms.Orders(true); len(orders) != 1 {
+		t.Errorf("order tracked twice %+v", orders)
+	}
+
+	// never reached the exchange
+	oms.ReconcileInterval = time.Nanosecond
+	order, _ = oms.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromInt(1)})
+	events := oms.Subscribe()
+	time.Sleep(time.Millisecond)
+	oms.Reconcile()
+	if len(events) != 1 {
+		t.Fatalf("expected the missing placement resolved, got %d events", len(events))
+	}
+	if event := <-events; event.Order.ClientId != order.ClientId || event.Order.State != Rejected {
+		t.Errorf("missing placement not rejected %+v", event)
+	}
+}
+
+func TestCancelRacingFill(t *testing.T) {
+	connector := &exchange{orders: make(map[string]types.QueryOrder)}
+	oms := New(connector, nil)
+	if _, err := oms.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromInt(1)}); err != nil {
+		t.Fatal(err)
+	}
+	// the order filled while the cancel was on its way, the exchange still accepted it
+	connector.orders["1"] = types.QueryOrder{OrderId: "1", Status: constants.Filled, Filled: decimal.NewFromInt(1)}
+	if err := oms.Cancel("BTCUSDT", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if order, _ := oms.Order("1"); order.State != Filled {
+		t.Errorf("accepted cancel hid the fill %+v", order)
+	}
+}
+
+type streamer struct {
+	platforms.UserDataStreamer
+}
+
+func (streamer) OrderStream(context.Context, chan<- types.OrderUpdateEntry) error { return nil }
+
+func TestRunWithoutInterval(t *testing.T) {
+	oms := New(&exchange{}, streamer{})
+	oms.ReconcileInterval = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := oms.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("unexpected error %v", err)
+	}
+}
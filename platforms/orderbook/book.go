@@ -0,0 +1,231 @@
+// Package orderbook locally maintained order books, seeded from a REST snapshot and kept
+// current with DepthStream updates.
+package orderbook
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Level one price level of a book.
+type Level struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+}
+
+// Book order book of one symbol, safe for concurrent use.
+type Book struct {
+	Symbol string
+	mux    sync.RWMutex
+	bids   map[string]Level
+	asks   map[string]Level
+	// updateTime in milliseconds
+	updateTime int64
+	listeners  []chan struct{}
+}
+
+func NewBook(symbol string) *Book {
+	return &Book{
+		Symbol: symbol,
+		bids:   make(map[string]Level),
+		asks:   make(map[string]Level),
+	}
+}
+
+// Reset replace the book with a snapshot.
+func (b *Book) Reset(snapshot types.OrderBookEntry) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.bids = make(map[string]Level, len(snapshot.Bids))
+	b.asks = make(map[string]Level, len(snapshot.Asks))
+	apply(b.bids, snapshot.Bids)
+	apply(b.asks, snapshot.Asks)
+	b.updateTime = snapshot.Timestamp
+	if b.updateTime == 0 {
+		b.updateTime = time.Now().UnixMilli()
+	}
+	b.notify()
+}
+
+// Apply merge an update, a level with zero quantity is removed.
+// Levels of the opposite side the update crosses are stale and dropped.
+// A snapshot replaces the book instead.
+func (b *Book) Apply(update types.DepthEntry) {
+	if update.Snapshot {
+		b.Reset(types.OrderBookEntry{Symbol: b.Symbol, Asks: update.Asks, Bids: update.Bids})
+		return
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	apply(b.bids, update.Bids)
+	apply(b.asks, update.Asks)
+	if bid, ok := best(b.bids, true); ok && len(update.Bids) > 0 {
+		for key, level := range b.asks {
+			if level.Price.LessThanOrEqual(bid.Price) {
+				delete(b.asks, key)
+			}
+		}
+	}
+	if ask, ok := best(b.asks, false); ok && len(update.Asks) > 0 {
+		for key, level := range b.bids {
+			if level.Price.GreaterThanOrEqual(ask.Price) {
+				delete(b.bids, key)
+			}
+		}
+	}
+	b.updateTime = time.Now().UnixMilli()
+	b.notify()
+}
+
+// Subscribe signal every change of the book. Signals coalesce while the receiver is busy,
+// so a receiver reads the book itself rather than expecting one signal per update.
+func (b *Book) Subscribe() <-chan struct{} {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	listener := make(chan struct{}, 1)
+	b.listeners = append(b.listeners, listener)
+	return listener
+}
+
+// notify the caller holds mux.
+func (b *Book) notify() {
+	for _, listener := range b.listeners {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Bids best first, depth zero returns every level.
+func (b *Book) Bids(depth int) []Level {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return sorted(b.bids, true, depth)
+}
+
+// Asks best first, depth zero returns every level.
+func (b *Book) Asks(depth int) []Level {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return sorted(b.asks, false, depth)
+}
+
+// Best top of book, ok is false while either side is empty.
+func (b *Book) Best() (bid, ask Level, ok bool) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	bid, bidOk := best(b.bids, true)
+	ask, askOk := best(b.asks, false)
+	return bid, ask, bidOk && askOk
+}
+
+// UpdateTime time of the last snapshot or update in milliseconds.
+func (b *Book) UpdateTime() int64 {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.updateTime
+}
+
+// Maintain keep the book of symbol current until ctx is done. The book is seeded with
+// GetOrderBook(depth) and then follows DepthStream. Updates carry no sequence numbers,
+// so a missed message can not be detected; the book is re-seeded every resync instead,
+// zero disables it. symbol is in the unified notation.
+func (b *Book) Maintain(ctx context.Context, connector platforms.SpotConnector, streamer platforms.MarketStreamer,
+	depth int64, resync time.Duration) error {
+	symbol := connector.SymbolPattern(b.Symbol)
+	seed := func() error {
+		snapshot, err := connector.GetOrderBook(symbol, &depth)
+		if err != nil {
+			return err
+		}
+		b.Reset(snapshot)
+		return nil
+	}
+	updates := make(chan types.DepthEntry, 64)
+	if err := streamer.DepthStream(ctx, symbol, updates); err != nil {
+		return err
+	}
+	if err := seed(); err != nil {
+		return err
+	}
+	go func() {
+		var tick <-chan time.Time
+		if resync > 0 {
+			ticker := time.NewTicker(resync)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-updates:
+				b.Apply(update)
+			case <-tick:
+				if err := seed(); err != nil {
+					platforms.LoggerOf(connector).Warn("order book resync failed", slog.String("platform", string(connector.Name())), slog.String("symbol", b.Symbol), slog.String("error", err.Error()))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// apply upsert [price, quantity] levels into side.
+func apply(side map[string]Level, levels [][]string) {
+	for _, entry := range levels {
+		if len(entry) < 2 {
+			continue
+		}
+		price, err := decimal.NewFromString(entry[0])
+		if err != nil {
+			continue
+		}
+		quantity, err := decimal.NewFromString(entry[1])
+		if err != nil {
+			continue
+		}
+		key := price.String()
+		if quantity.IsZero() {
+			delete(side, key)
+			continue
+		}
+		side[key] = Level{Price: price, Quantity: quantity}
+	}
+}
+
+func best(side map[string]Level, bids bool) (Level, bool) {
+	var top Level
+	var ok bool
+	for _, level := range side {
+		if !ok || (bids && level.Price.GreaterThan(top.Price)) || (!bids && level.Price.LessThan(top.Price)) {
+			top, ok = level, true
+		}
+	}
+	return top, ok
+}
+
+func sorted(side map[string]Level, bids bool, depth int) []Level {
+	var levels = make([]Level, 0, len(side))
+	for _, level := range side {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if bids {
+			return levels[i].Price.GreaterThan(levels[j].Price)
+		}
+		return levels[i].Price.LessThan(levels[j].Price)
+	})
+	if depth > 0 && len(levels) > depth {
+		levels = levels[:depth]
+	}
+	return levels
+}
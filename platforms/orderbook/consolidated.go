@@ -0,0 +1,196 @@
+package orderbook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+)
+
+// Source book of one venue merged into a Consolidated.
+type Source struct {
+	Venue constants.Platform
+	Book  *Book
+	// TakerRate fee rate the effective prices are adjusted with, e.g. 0.001 for 0.1%
+	TakerRate decimal.Decimal
+}
+
+// NewSource maintain the book of symbol on the venue of connector, see Book.Maintain,
+// and read its taker rate.
+func NewSource(ctx context.Context, connector platforms.SpotConnector, streamer platforms.MarketStreamer,
+	symbol string, depth int64, resync time.Duration) (Source, error) {
+	rates, err := connector.GetFeeRates([]string{symbol})
+	if err != nil {
+		return Source{}, err
+	}
+	book := NewBook(symbol)
+	if err = book.Maintain(ctx, connector, streamer, depth, resync); err != nil {
+		return Source{}, err
+	}
+	return Source{Venue: connector.Name(), Book: book, TakerRate: rates[symbol].Taker}, nil
+}
+
+func (s Source) level(level Level, bid bool) VenueLevel {
+	one := decimal.NewFromInt(1)
+	effective := level.Price.Mul(one.Add(s.TakerRate))
+	if bid {
+		effective = level.Price.Mul(one.Sub(s.TakerRate))
+	}
+	return VenueLevel{Venue: s.Venue, Price: level.Price, Quantity: level.Quantity, EffectivePrice: effective}
+}
+
+// VenueLevel a price level of one venue in the consolidated ladder.
+type VenueLevel struct {
+	Venue    constants.Platform
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+	// EffectivePrice after taker fee, what a buyer pays or a seller receives per unit
+	EffectivePrice decimal.Decimal
+}
+
+// Ladder consolidated book, bids and asks sorted best first by EffectivePrice.
+type Ladder struct {
+	Symbol string
+	Bids   []VenueLevel
+	Asks   []VenueLevel
+	// Timestamp in milliseconds
+	Timestamp int64
+}
+
+// Cross the best bid of one venue at or above the best ask of another.
+type Cross struct {
+	Bid VenueLevel
+	Ask VenueLevel
+	// Profitable the cross survives the taker fees of both venues
+	Profitable bool
+}
+
+// Consolidated merges the books of several venues for one symbol.
+type Consolidated struct {
+	Symbol  string
+	sources []Source
+	updates chan Ladder
+}
+
+func NewConsolidated(symbol string, sources ...Source) *Consolidated {
+	return &Consolidated{Symbol: symbol, sources: sources, updates: make(chan Ladder, 1)}
+}
+
+// Ladder merge the current books, depth levels per venue and side, zero takes every level.
+func (c *Consolidated) Ladder(depth int) Ladder {
+	ladder := Ladder{Symbol: c.Symbol, Timestamp: time.Now().UnixMilli()}
+	for _, source := range c.sources {
+		for _, level := range source.Book.Bids(depth) {
+			ladder.Bids = append(ladder.Bids, source.level(level, true))
+		}
+		for _, level := range source.Book.Asks(depth) {
+			ladder.Asks = append(ladder.Asks, source.level(level, false))
+		}
+	}
+	sort.SliceStable(ladder.Bids, func(i, j int) bool {
+		return ladder.Bids[i].EffectivePrice.GreaterThan(ladder.Bids[j].EffectivePrice)
+	})
+	sort.SliceStable(ladder.Asks, func(i, j int) bool {
+		return ladder.Asks[i].EffectivePrice.LessThan(ladder.Asks[j].EffectivePrice)
+	})
+	return ladder
+}
+
+// BestBid highest bid across venues by raw price.
+func (c *Consolidated) BestBid() (VenueLevel, bool) {
+	return c.best(true)
+}
+
+// BestAsk lowest ask across venues by raw price.
+func (c *Consolidated) BestAsk() (VenueLevel, bool) {
+	return c.best(false)
+}
+
+// Crossed report whether the best bid of one venue is at or above the best ask of another.
+// A venue crossed against itself is a stale book and not reported.
+func (c *Consolidated) Crossed() (Cross, bool) {
+	ladder := c.Ladder(1)
+	var cross Cross
+	var ok bool
+	for _, bid := range ladder.Bids {
+		for _, ask := range ladder.Asks {
+			if bid.Venue == ask.Venue || bid.Price.LessThan(ask.Price) {
+				continue
+			}
+			if !ok || bid.Price.Sub(ask.Price).GreaterThan(cross.Bid.Price.Sub(cross.Ask.Price)) {
+				cross = Cross{Bid: bid, Ask: ask, Profitable: bid.EffectivePrice.GreaterThan(ask.EffectivePrice)}
+				ok = true
+			}
+		}
+	}
+	return cross, ok
+}
+
+// Updates ladders published by Run, only the latest is kept while the receiver lags.
+func (c *Consolidated) Updates() <-chan Ladder {
+	return c.updates
+}
+
+// Run publish a ladder of depth levels on Updates whenever a venue's book changes, until ctx is done.
+func (c *Consolidated) Run(ctx context.Context, depth int) {
+	changed := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	for _, source := range c.sources {
+		wg.Add(1)
+		go func(signal <-chan struct{}) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-signal:
+					select {
+					case changed <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}(source.Book.Subscribe())
+	}
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			ladder := c.Ladder(depth)
+			// replace a ladder the receiver has not taken yet
+			select {
+			case <-c.updates:
+			default:
+			}
+			c.updates <- ladder
+		}
+	}
+}
+
+func (c *Consolidated) best(bids bool) (VenueLevel, bool) {
+	var top VenueLevel
+	var ok bool
+	for _, source := range c.sources {
+		var levels []Level
+		if bids {
+			levels = source.Book.Bids(1)
+		} else {
+			levels = source.Book.Asks(1)
+		}
+		if len(levels) == 0 {
+			continue
+		}
+		level := levels[0]
+		if !ok || (bids && level.Price.GreaterThan(top.Price)) || (!bids && level.Price.LessThan(top.Price)) {
+			top = source.level(level, bids)
+			ok = true
+		}
+	}
+	return top, ok
+}
@@ -0,0 +1,67 @@
+package orderbook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+func TestBook(t *testing.T) {
+	book := NewBook("BTCUSDT")
+	book.Reset(types.OrderBookEntry{
+		Bids: [][]string{{"99", "1"}, {"98", "2"}},
+		Asks: [][]string{{"101", "1"}, {"102", "2"}},
+	})
+	book.Apply(types.DepthEntry{Bids: [][]string{{"99", "0"}, {"97.50", "3"}}})
+	bids := book.Bids(0)
+	if len(bids) != 2 || !bids[0].Price.Equal(decimal.NewFromInt(98)) || !bids[1].Price.Equal(decimal.NewFromFloat(97.5)) {
+		t.Errorf("unexpected bids %+v", bids)
+	}
+	// a bid through the asks removes the stale asks
+	book.Apply(types.DepthEntry{Bids: [][]string{{"101.5", "1"}}})
+	if bid, ask, ok := book.Best(); !ok || !bid.Price.Equal(decimal.NewFromFloat(101.5)) || !ask.Price.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("unexpected top of book %+v %+v", bid, ask)
+	}
+	// a snapshot drops the levels it does not list
+	book.Apply(types.DepthEntry{Bids: [][]string{{"100", "1"}}, Asks: [][]string{{"100.5", "1"}}, Snapshot: true})
+	if bids, asks := book.Bids(0), book.Asks(0); len(bids) != 1 || len(asks) != 1 || !asks[0].Price.Equal(decimal.NewFromFloat(100.5)) {
+		t.Errorf("snapshot merged as a diff %+v %+v", bids, asks)
+	}
+}
+
+func TestConsolidated(t *testing.T) {
+	a, b := NewBook("BTCUSDT"), NewBook("BTCUSDT")
+	a.Reset(types.OrderBookEntry{Bids: [][]string{{"100", "1"}}, Asks: [][]string{{"100.5", "1"}}})
+	b.Reset(types.OrderBookEntry{Bids: [][]string{{"99", "1"}}, Asks: [][]string{{"99.9", "2"}}})
+	rate := decimal.NewFromFloat(0.001)
+	consolidated := NewConsolidated("BTCUSDT", Source{Venue: "A", Book: a, TakerRate: rate}, Source{Venue: "B", Book: b, TakerRate: rate})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go consolidated.Run(ctx, 5)
+
+	if ask, ok := consolidated.BestAsk(); !ok || ask.Venue != "B" {
+		t.Errorf("unexpected best ask %+v", ask)
+	}
+	cross, ok := consolidated.Crossed()
+	if !ok || cross.Bid.Venue != "A" || cross.Ask.Venue != "B" || cross.Profitable {
+		t.Errorf("unexpected cross %+v, %v", cross, ok)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	b.Apply(types.DepthEntry{Asks: [][]string{{"99.5", "1"}}})
+	select {
+	case ladder := <-consolidated.Updates():
+		if len(ladder.Asks) != 3 || ladder.Asks[0].Venue != "B" || !ladder.Asks[0].Price.Equal(decimal.NewFromFloat(99.5)) {
+			t.Errorf("unexpected ladder asks %+v", ladder.Asks)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no ladder published")
+	}
+	if cross, _ = consolidated.Crossed(); !cross.Profitable {
+		t.Errorf("expected a profitable cross, got %+v", cross)
+	}
+}
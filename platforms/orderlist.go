@@ -0,0 +1,335 @@
+package platforms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// ValidateOrderList check the list carries exactly the legs its type needs.
+func ValidateOrderList(list types.OrderListEntry) error {
+	switch list.Type {
+	case types.OCO:
+		if list.Entry != nil || list.TakeProfit == nil || list.StopLoss == nil {
+			return errors.New("OCO needs take profit and stop loss without entry")
+		}
+	case types.OTO:
+		if list.Entry == nil || (list.TakeProfit == nil) == (list.StopLoss == nil) {
+			return errors.New("OTO needs an entry and either take profit or stop loss")
+		}
+	case types.OTOCO:
+		if list.Entry == nil || list.TakeProfit == nil || list.StopLoss == nil {
+			return errors.New("OTOCO needs entry, take profit and stop loss")
+		}
+	default:
+		return fmt.Errorf("unknown order list type %q", list.Type)
+	}
+	if list.StopLoss != nil && list.StopLoss.StopPrice.IsZero() {
+		return errors.New("stop loss needs a stop price")
+	}
+	return nil
+}
+
+// emulatedList state of a client-side order list, guarded by OrderListEmulator.mux.
+type emulatedList struct {
+	list   types.OrderListEntry
+	result types.OrderListResult
+	// armed the exits are live: from placement for OCO, once the entry fills otherwise
+	armed bool
+	// takeProfitFilled base quantity the take profit leg has filled so far
+	takeProfitFilled decimal.Decimal
+	// entryFilled quantity of an entry canceled after a partial fill, the exits cover no more than it
+	entryFilled decimal.Decimal
+	done        bool
+}
+
+// exit order of an exit leg, cut to the filled part of a partially filled entry.
+func (list *emulatedList) exit(order *types.OrderEntry) types.OrderEntry {
+	entry := leg(list.list.Symbol, order)
+	if list.entryFilled.IsPositive() {
+		entry.Quantity = decimal.Min(entry.Quantity, list.entryFilled)
+	}
+	return entry
+}
+
+const (
+	// earlyUpdates updates of unknown orders an OrderListEmulator keeps at most, the oldest is dropped first
+	earlyUpdates = 256
+	// earlyUpdateTTL how long an update of an unknown order waits for its PlaceOrder reply
+	earlyUpdateTTL = time.Minute
+)
+
+// earlyUpdate an update of an order not registered yet
+type earlyUpdate struct {
+	update   types.OrderUpdateEntry
+	received time.Time
+}
+
+// OrderListEmulator PlaceOrderList for exchanges without native order lists, lists with native
+// support are passed through to the connector.
+// Exits are placed once the entry fills, or for the filled part of an entry canceled after a
+// partial fill. The take profit rests on the book while the stop loss
+// is held client-side and sent when OnPrice crosses its stop price, after the take profit was canceled.
+// A take profit that fills or is canceled retires its stop loss.
+// Order updates must be fed to Run, typically from UserDataStreamer.OrderStream;
+// lists are only watched while the process runs.
+type OrderListEmulator struct {
+	SpotConnector
+	mux    sync.Mutex
+	lists  map[string]*emulatedList // list id -> list
+	orders map[string]*emulatedList // order id of a working leg -> list
+	// early updates of orders not registered yet, the stream may be faster than the PlaceOrder reply.
+	// Updates of other orders land here too, so entries expire after earlyUpdateTTL.
+	early    map[string]earlyUpdate
+	sequence atomic.Uint64
+}
+
+func NewOrderListEmulator(connector SpotConnector) *OrderListEmulator {
+	return &OrderListEmulator{
+		SpotConnector: connector,
+		lists:         make(map[string]*emulatedList),
+		orders:        make(map[string]*emulatedList),
+		early:         make(map[string]earlyUpdate),
+	}
+}
+
+func (e *OrderListEmulator) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	if err := ValidateOrderList(list); err != nil {
+		return types.OrderListResult{}, err
+	}
+	result, err := e.SpotConnector.PlaceOrderList(list)
+	if !errors.Is(err, ErrNotSupported) {
+		return result, err
+	}
+	emulated := &emulatedList{
+		list:   list,
+		result: types.OrderListResult{ListId: "emulated-" + strconv.FormatUint(e.sequence.Add(1), 10)},
+	}
+	if list.Type == types.OCO {
+		emulated.armed = true
+		orderId, err := e.SpotConnector.PlaceOrder(leg(list.Symbol, list.TakeProfit))
+		if err != nil {
+			return types.OrderListResult{}, err
+		}
+		emulated.result.TakeProfitOrderId = orderId
+	} else {
+		orderId, err := e.SpotConnector.PlaceOrder(leg(list.Symbol, list.Entry))
+		if err != nil {
+			return types.OrderListResult{}, err
+		}
+		emulated.result.EntryOrderId = orderId
+	}
+	e.mux.Lock()
+	e.lists[emulated.result.ListId] = emulated
+	result = emulated.result
+	var orderId = result.EntryOrderId
+	if orderId == "" {
+		orderId = result.TakeProfitOrderId
+	}
+	e.orders[orderId] = emulated
+	arrived, ok := e.takeEarly(orderId)
+	e.mux.Unlock()
+	if ok {
+		e.onUpdate(arrived)
+	}
+	return result, nil
+}
+
+// OrderList current ids of an emulated list, false once the list is finished or unknown.
+func (e *OrderListEmulator) OrderList(listId string) (types.OrderListResult, bool) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	list, ok := e.lists[listId]
+	if !ok {
+		return types.OrderListResult{}, false
+	}
+	return list.result, true
+}
+
+// Run drive the emulated lists from order updates until ctx is done or updates is closed.
+func (e *OrderListEmulator) Run(ctx context.Context, updates <-chan types.OrderUpdateEntry) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			e.onUpdate(update)
+		}
+	}
+}
+
+func (e *OrderListEmulator) onUpdate(update types.OrderUpdateEntry) {
+	e.mux.Lock()
+	list, ok := e.orders[update.OrderId]
+	if !ok {
+		e.keepEarly(update)
+		e.mux.Unlock()
+		return
+	}
+	var placeTakeProfit bool
+	switch update.OrderId {
+	case list.result.EntryOrderId:
+		switch update.Status {
+		case constants.Filled:
+			delete(e.orders, update.OrderId)
+			list.armed = true
+			placeTakeProfit = list.list.TakeProfit != nil
+		case constants.Canceled, constants.PartiallyCanceled:
+			if !update.FilledQuantity.IsPositive() {
+				e.finish(list)
+				break
+			}
+			// the part filled before the cancel needs its exits all the same
+			delete(e.orders, update.OrderId)
+			list.armed = true
+			list.entryFilled = update.FilledQuantity
+			placeTakeProfit = list.list.TakeProfit != nil
+		}
+	case list.result.TakeProfitOrderId:
+		list.takeProfitFilled = update.FilledQuantity
+		if update.Status == constants.Filled || update.Status == constants.Canceled {
+			e.finish(list)
+		}
+	}
+	e.mux.Unlock()
+	if !placeTakeProfit {
+		return
+	}
+	e.mux.Lock()
+	takeProfit := list.exit(list.list.TakeProfit)
+	e.mux.Unlock()
+	orderId, err := e.SpotConnector.PlaceOrder(takeProfit)
+	e.mux.Lock()
+	if err != nil {
+		LoggerOf(e.SpotConnector).Warn("take profit failed", slog.String("list", list.result.ListId), slog.String("error", err.Error()))
+		if list.list.StopLoss == nil {
+			e.finish(list)
+		}
+		e.mux.Unlock()
+		return
+	}
+	list.result.TakeProfitOrderId = orderId
+	stopped := list.done
+	var arrived types.OrderUpdateEntry
+	if !stopped {
+		e.orders[orderId] = list
+		arrived, ok = e.takeEarly(orderId)
+	}
+	e.mux.Unlock()
+	// the stop loss triggered while the take profit was being placed
+	if stopped {
+		if _, err = e.SpotConnector.Cancel(list.list.Symbol, orderId); err != nil {
+			LoggerOf(e.SpotConnector).Warn("take profit cancel failed", slog.String("list", list.result.ListId), slog.String("error", err.Error()))
+		}
+		return
+	}
+	if ok {
+		e.onUpdate(arrived)
+	}
+}
+
+// keepEarly hold the update of an unknown order until its PlaceOrder reply, within earlyUpdates
+// and earlyUpdateTTL. The caller holds mux.
+func (e *OrderListEmulator) keepEarly(update types.OrderUpdateEntry) {
+	now := time.Now()
+	var oldest string
+	for orderId, early := range e.early {
+		if now.Sub(early.received) > earlyUpdateTTL {
+			delete(e.early, orderId)
+		} else if oldest == "" || early.received.Before(e.early[oldest].received) {
+			oldest = orderId
+		}
+	}
+	if _, ok := e.early[update.OrderId]; !ok && len(e.early) >= earlyUpdates {
+		delete(e.early, oldest)
+	}
+	e.early[update.OrderId] = earlyUpdate{update: update, received: now}
+}
+
+// takeEarly the update held for orderId, the caller holds mux.
+func (e *OrderListEmulator) takeEarly(orderId string) (types.OrderUpdateEntry, bool) {
+	early, ok := e.early[orderId]
+	delete(e.early, orderId)
+	return early.update, ok
+}
+
+// OnPrice trigger the held stop losses of symbol crossed by price: a sell stops at or below
+// its stop price, a buy at or above.
+func (e *OrderListEmulator) OnPrice(symbol string, price decimal.Decimal) {
+	type trigger struct {
+		list             types.OrderListEntry
+		result           types.OrderListResult
+		stopLoss         types.OrderEntry
+		takeProfitFilled decimal.Decimal
+	}
+	var triggered []trigger
+	e.mux.Lock()
+	for _, list := range e.lists {
+		stop := list.list.StopLoss
+		if list.list.Symbol != symbol || !list.armed || list.done || stop == nil {
+			continue
+		}
+		sell := strings.ToUpper(stop.Side) == "SELL"
+		if (sell && price.LessThanOrEqual(stop.StopPrice)) || (!sell && price.GreaterThanOrEqual(stop.StopPrice)) {
+			e.finish(list)
+			triggered = append(triggered, trigger{list.list, list.result, list.exit(stop), list.takeProfitFilled})
+		}
+	}
+	e.mux.Unlock()
+	for _, t := range triggered {
+		filled := t.takeProfitFilled
+		if t.result.TakeProfitOrderId != "" {
+			if _, err := e.SpotConnector.Cancel(t.list.Symbol, t.result.TakeProfitOrderId); err != nil {
+				// the take profit may have filled meanwhile, only stop what is left
+				order, err := e.SpotConnector.QueryOrder(t.list.Symbol, t.result.TakeProfitOrderId)
+				if err != nil {
+					LoggerOf(e.SpotConnector).Error("take profit state unknown, stop loss skipped", slog.String("list", t.result.ListId), slog.String("error", err.Error()))
+					continue
+				}
+				filled = decimal.Max(filled, order.Filled)
+			}
+		}
+		order := t.stopLoss
+		order.Quantity = order.Quantity.Sub(filled)
+		if !order.Quantity.IsPositive() {
+			continue
+		}
+		order.StopPrice = decimal.Zero
+		if order.Price.IsZero() {
+			order.Type = constants.Market
+		} else {
+			order.Type = constants.Limit
+		}
+		if _, err := e.SpotConnector.PlaceOrder(order); err != nil {
+			LoggerOf(e.SpotConnector).Error("stop loss failed", slog.String("list", t.result.ListId), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// finish retire the list, the caller holds mux.
+func (e *OrderListEmulator) finish(list *emulatedList) {
+	list.done = true
+	delete(e.lists, list.result.ListId)
+	delete(e.orders, list.result.EntryOrderId)
+	delete(e.orders, list.result.TakeProfitOrderId)
+}
+
+// leg order of a list leg on the list symbol
+func leg(symbol string, order *types.OrderEntry) types.OrderEntry {
+	entry := *order
+	entry.Symbol = symbol
+	return entry
+}
@@ -0,0 +1,170 @@
+package platforms
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// plainTrader records orders and cancels, it has no native order lists
+type plainTrader struct {
+	SpotConnector
+	mux      sync.Mutex
+	placed   []types.OrderEntry
+	canceled []string
+}
+
+func (*plainTrader) PlaceOrderList(types.OrderListEntry) (types.OrderListResult, error) {
+	return types.OrderListResult{}, ErrNotSupported
+}
+
+func (c *plainTrader) PlaceOrder(order types.OrderEntry) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.placed = append(c.placed, order)
+	return strconv.Itoa(len(c.placed)), nil
+}
+
+func (c *plainTrader) Cancel(_, orderId string) (bool, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.canceled = append(c.canceled, orderId)
+	return true, nil
+}
+
+func TestValidateOrderList(t *testing.T) {
+	order := &types.OrderEntry{StopPrice: decimal.NewFromInt(1)}
+	valid := []types.OrderListEntry{
+		{Type: types.OCO, TakeProfit: order, StopLoss: order},
+		{Type: types.OTO, Entry: order, StopLoss: order},
+		{Type: types.OTOCO, Entry: order, TakeProfit: order, StopLoss: order},
+	}
+	for _, list := range valid {
+		if err := ValidateOrderList(list); err != nil {
+			t.Errorf("%s: %v", list.Type, err)
+		}
+	}
+	invalid := []types.OrderListEntry{
+		{Type: types.OCO, Entry: order, TakeProfit: order, StopLoss: order},
+		{Type: types.OTO, Entry: order, TakeProfit: order, StopLoss: order},
+		{Type: types.OTOCO, Entry: order, TakeProfit: order},
+		{Type: types.OCO, TakeProfit: order, StopLoss: &types.OrderEntry{}},
+		{Type: "OTX"},
+	}
+	for _, list := range invalid {
+		if err := ValidateOrderList(list); err == nil {
+			t.Errorf("expected %+v to be rejected", list)
+		}
+	}
+}
+
+func TestOrderListEmulator(t *testing.T) {
+	connector := &plainTrader{}
+	emulator := NewOrderListEmulator(connector)
+	result, err := emulator.PlaceOrderList(types.OrderListEntry{
+		Type:       types.OTOCO,
+		Symbol:     "BTCUSDT",
+		Entry:      &types.OrderEntry{Side: "BUY", Price: decimal.NewFromInt(100), Quantity: decimal.NewFromInt(2)},
+		TakeProfit: &types.OrderEntry{Side: "SELL", Price: decimal.NewFromInt(110), Quantity: decimal.NewFromInt(2)},
+		StopLoss:   &types.OrderEntry{Side: "SELL", StopPrice: decimal.NewFromInt(95), Quantity: decimal.NewFromInt(2)},
+	})
+	if err != nil || result.EntryOrderId != "1" {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+	// the stop loss is not armed before the entry fills
+	emulator.OnPrice("BTCUSDT", decimal.NewFromInt(90))
+	if len(connector.placed) != 1 {
+		t.Fatalf("stop loss triggered before entry fill: %+v", connector.placed)
+	}
+
+	updates := make(chan types.OrderUpdateEntry)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go emulator.Run(ctx, updates)
+	updates <- types.OrderUpdateEntry{OrderId: "1", Status: constants.Filled}
+	updates <- types.OrderUpdateEntry{OrderId: "2", Status: constants.PartiallyFilled, FilledQuantity: decimal.NewFromFloat(0.5)}
+	updates <- types.OrderUpdateEntry{OrderId: "unrelated", Status: constants.Filled}
+	list, ok := emulator.OrderList(result.ListId)
+	if !ok || list.TakeProfitOrderId != "2" {
+		t.Fatalf("take profit not placed after entry fill: %+v", list)
+	}
+
+	emulator.OnPrice("BTCUSDT", decimal.NewFromInt(96))
+	emulator.OnPrice("BTCUSDT", decimal.NewFromInt(95))
+	if len(connector.canceled) != 1 || connector.canceled[0] != "2" {
+		t.Errorf("take profit not canceled: %v", connector.canceled)
+	}
+	if len(connector.placed) != 3 {
+		t.Fatalf("stop loss not placed: %+v", connector.placed)
+	}
+	stop := connector.placed[2]
+	if stop.Type != constants.Market || !stop.Quantity.Equal(decimal.NewFromFloat(1.5)) || stop.Symbol != "BTCUSDT" {
+		t.Errorf("unexpected stop loss order %+v", stop)
+	}
+	if _, ok = emulator.OrderList(result.ListId); ok {
+		t.Error("list still active after stop loss")
+	}
+}
+
+func TestOrderListPartialEntry(t *testing.T) {
+	connector := &plainTrader{}
+	emulator := NewOrderListEmulator(connector)
+	result, err := emulator.PlaceOrderList(types.OrderListEntry{
+		Type:       types.OTOCO,
+		Symbol:     "BTCUSDT",
+		Entry:      &types.OrderEntry{Side: "BUY", Price: decimal.NewFromInt(100), Quantity: decimal.NewFromInt(2)},
+		TakeProfit: &types.OrderEntry{Side: "SELL", Price: decimal.NewFromInt(110), Quantity: decimal.NewFromInt(2)},
+		StopLoss:   &types.OrderEntry{Side: "SELL", StopPrice: decimal.NewFromInt(95), Quantity: decimal.NewFromInt(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// canceled after filling 0.8 of 2
+	emulator.onUpdate(types.OrderUpdateEntry{OrderId: result.EntryOrderId, Status: constants.Canceled, FilledQuantity: decimal.NewFromFloat(0.8)})
+	if len(connector.placed) != 2 || !connector.placed[1].Quantity.Equal(decimal.NewFromFloat(0.8)) {
+		t.Fatalf("take profit not placed for the filled part: %+v", connector.placed)
+	}
+	emulator.OnPrice("BTCUSDT", decimal.NewFromInt(94))
+	if len(connector.placed) != 3 || !connector.placed[2].Quantity.Equal(decimal.NewFromFloat(0.8)) {
+		t.Errorf("stop loss not cut to the filled part: %+v", connector.placed)
+	}
+
+	// canceled before any fill retires the list
+	result, _ = emulator.PlaceOrderList(types.OrderListEntry{
+		Type:     types.OTO,
+		Symbol:   "BTCUSDT",
+		Entry:    &types.OrderEntry{Side: "BUY", Price: decimal.NewFromInt(100), Quantity: decimal.NewFromInt(2)},
+		StopLoss: &types.OrderEntry{Side: "SELL", StopPrice: decimal.NewFromInt(95), Quantity: decimal.NewFromInt(2)},
+	})
+	emulator.onUpdate(types.OrderUpdateEntry{OrderId: result.EntryOrderId, Status: constants.Canceled})
+	if _, ok := emulator.OrderList(result.ListId); ok {
+		t.Error("list without fill still active after the entry was canceled")
+	}
+}
+
+func TestOrderListEarlyFill(t *testing.T) {
+	connector := &plainTrader{}
+	emulator := NewOrderListEmulator(connector)
+	// the stream reports the entry filled before PlaceOrder returns its id
+	emulator.onUpdate(types.OrderUpdateEntry{OrderId: "1", Status: constants.Filled})
+	result, err := emulator.PlaceOrderList(types.OrderListEntry{
+		Type:       types.OTO,
+		Symbol:     "BTCUSDT",
+		Entry:      &types.OrderEntry{Side: "BUY", Price: decimal.NewFromInt(100), Quantity: decimal.NewFromInt(2)},
+		TakeProfit: &types.OrderEntry{Side: "SELL", Price: decimal.NewFromInt(110), Quantity: decimal.NewFromInt(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, ok := emulator.OrderList(result.ListId); !ok || list.TakeProfitOrderId != "2" || len(connector.placed) != 2 {
+		t.Errorf("early entry fill dropped %+v", list)
+	}
+	if len(emulator.early) != 0 {
+		t.Errorf("early update kept after use %+v", emulator.early)
+	}
+}
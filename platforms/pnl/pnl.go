@@ -0,0 +1,355 @@
+// Package pnl positions, realized and unrealized PnL and fees from the account's fills,
+// per venue and consolidated across venues.
+package pnl
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Method cost accounting of closing fills.
+type Method string
+
+const (
+	// FIFO closes the oldest open lot first
+	FIFO Method = "FIFO"
+	// LIFO closes the newest open lot first
+	LIFO Method = "LIFO"
+	// AverageCost keeps one lot at the average entry price
+	AverageCost Method = "AVERAGE_COST"
+)
+
+// lot open quantity at its entry price, negative for a short
+type lot struct {
+	quantity decimal.Decimal
+	price    decimal.Decimal
+}
+
+type position struct {
+	lots     []lot
+	realized decimal.Decimal
+	mark     decimal.Decimal
+}
+
+// Position of one symbol. Realized excludes fees, see Tracker.Fees.
+type Position struct {
+	// Venue empty for a consolidated position
+	Venue  constants.Platform
+	Symbol string
+	// Quantity open base quantity, negative when more was sold than bought
+	Quantity decimal.Decimal
+	// AverageEntry price of the open quantity under the tracker's method
+	AverageEntry decimal.Decimal
+	Realized     decimal.Decimal
+	// MarkPrice last mark, Unrealized is zero until the symbol was marked
+	MarkPrice  decimal.Decimal
+	Unrealized decimal.Decimal
+}
+
+// Tracker keeps positions from fills, safe for concurrent use.
+type Tracker struct {
+	method    Method
+	mux       sync.Mutex
+	positions map[constants.Platform]map[string]*position
+	// assets net change of every asset, fees included
+	assets map[constants.Platform]map[string]decimal.Decimal
+	fees   map[string]decimal.Decimal
+	// seen trade ids, fills from history and from the stream overlap
+	seen map[string]struct{}
+}
+
+func NewTracker(method Method) *Tracker {
+	return &Tracker{
+		method:    method,
+		positions: make(map[constants.Platform]map[string]*position),
+		assets:    make(map[constants.Platform]map[string]decimal.Decimal),
+		fees:      make(map[string]decimal.Decimal),
+		seen:      make(map[string]struct{}),
+	}
+}
+
+// OnFill apply a fill of venue, a trade id seen before is ignored.
+func (t *Tracker) OnFill(venue constants.Platform, fill types.FillEntry) {
+	symbol, err := constants.StandardizeSymbol(fill.Symbol)
+	if err != nil || !fill.Quantity.IsPositive() {
+		return
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if fill.TradeId != "" {
+		key := string(venue) + ":" + fill.TradeId
+		if _, ok := t.seen[key]; ok {
+			return
+		}
+		t.seen[key] = struct{}{}
+	}
+	if t.positions[venue] == nil {
+		t.positions[venue] = make(map[string]*position)
+		t.assets[venue] = make(map[string]decimal.Decimal)
+	}
+	p, ok := t.positions[venue][symbol]
+	if !ok {
+		p = &position{}
+		t.positions[venue][symbol] = p
+	}
+	quantity := fill.Quantity
+	if strings.ToUpper(fill.Side) == "SELL" {
+		quantity = quantity.Neg()
+	}
+	t.apply(p, quantity, fill.Price)
+
+	matches := constants.UnifiedPattern.FindStringSubmatch(symbol)
+	assets := t.assets[venue]
+	assets[matches[1]] = assets[matches[1]].Add(quantity)
+	assets[matches[2]] = assets[matches[2]].Sub(quantity.Mul(fill.Price))
+	if fill.Fee.IsPositive() && fill.FeeCurrency != "" {
+		currency := strings.ToUpper(fill.FeeCurrency)
+		assets[currency] = assets[currency].Sub(fill.Fee)
+		t.fees[currency] = t.fees[currency].Add(fill.Fee)
+	}
+}
+
+// OnUpdate apply the fill carried by an order stream update, updates without a fill are ignored.
+func (t *Tracker) OnUpdate(venue constants.Platform, update types.OrderUpdateEntry) {
+	if !update.LastFillQuantity.IsPositive() {
+		return
+	}
+	t.OnFill(venue, types.FillEntry{
+		Symbol:      update.Symbol,
+		TradeId:     update.TradeId,
+		OrderId:     update.OrderId,
+		Side:        update.Side,
+		Price:       update.LastFillPrice,
+		Quantity:    update.LastFillQuantity,
+		Fee:         update.Fee,
+		FeeCurrency: update.FeeAsset,
+		IsMaker:     update.IsMaker,
+		Timestamp:   update.TransactionTime,
+	})
+}
+
+// Load apply the trade history of symbol between since and until, in milliseconds.
+func (t *Tracker) Load(ctx context.Context, connector platforms.SpotConnector, symbol string, since, until int64) error {
+	fills, err := connector.GetMyTrades(ctx, connector.SymbolPattern(symbol), since, until)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(fills, func(i, j int) bool {
+		return fills[i].Timestamp < fills[j].Timestamp
+	})
+	for _, fill := range fills {
+		t.OnFill(connector.Name(), fill)
+	}
+	return nil
+}
+
+// Follow apply the fills of venue's order stream until ctx is done.
+func (t *Tracker) Follow(ctx context.Context, venue constants.Platform, streamer platforms.UserDataStreamer) error {
+	updates := make(chan types.OrderUpdateEntry, 64)
+	if err := streamer.OrderStream(ctx, updates); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-updates:
+				t.OnUpdate(venue, update)
+			}
+		}
+	}()
+	return nil
+}
+
+// Mark set the mark price of symbol on venue.
+func (t *Tracker) Mark(venue constants.Platform, symbol string, price decimal.Decimal) {
+	symbol, err := constants.StandardizeSymbol(symbol)
+	if err != nil {
+		return
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if p, ok := t.positions[venue][symbol]; ok {
+		p.mark = price
+	}
+}
+
+// MarkAll mark every symbol held on the venue of connector with GetTicker.
+func (t *Tracker) MarkAll(connector platforms.SpotConnector) error {
+	venue := connector.Name()
+	t.mux.Lock()
+	var symbols []string
+	for symbol := range t.positions[venue] {
+		symbols = append(symbols, symbol)
+	}
+	t.mux.Unlock()
+	for _, symbol := range symbols {
+		ticker, err := connector.GetTicker(connector.SymbolPattern(symbol))
+		if err != nil {
+			return err
+		}
+		t.Mark(venue, symbol, ticker.Price)
+	}
+	return nil
+}
+
+// WatchMarks mark symbol on venue with the close of its 1m candles until ctx is done,
+// symbol in the notation of streamer.
+func (t *Tracker) WatchMarks(ctx context.Context, venue constants.Platform, streamer platforms.MarketStreamer, symbol string) error {
+	candles := make(chan types.CandleEntry)
+	if err := streamer.CandleStream(ctx, symbol, "1m", candles); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case candle := <-candles:
+				// [start_timestamp, open, high, low, close, volume, (volume_usd)]
+				if len(candle) > 4 && candle[4] > 0 {
+					t.Mark(venue, symbol, decimal.NewFromFloat(candle[4]))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Positions per venue and symbol, sorted by venue then symbol.
+func (t *Tracker) Positions() []Position {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	var positions []Position
+	for venue, bySymbol := range t.positions {
+		for symbol, p := range bySymbol {
+			positions = append(positions, summarize(venue, symbol, p.realized, p.mark, p.lots))
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Venue != positions[j].Venue {
+			return positions[i].Venue < positions[j].Venue
+		}
+		return positions[i].Symbol < positions[j].Symbol
+	})
+	return positions
+}
+
+// Consolidated positions per symbol across venues. Unrealized PnL is taken per venue with
+// its own mark, MarkPrice is the mark of the last venue holding the symbol.
+func (t *Tracker) Consolidated() []Position {
+	bySymbol := make(map[string]*Position)
+	for _, p := range t.Positions() {
+		total, ok := bySymbol[p.Symbol]
+		if !ok {
+			total = &Position{Symbol: p.Symbol}
+			bySymbol[p.Symbol] = total
+		}
+		cost := total.AverageEntry.Mul(total.Quantity).Add(p.AverageEntry.Mul(p.Quantity))
+		total.Quantity = total.Quantity.Add(p.Quantity)
+		total.AverageEntry = decimal.Zero
+		if !total.Quantity.IsZero() {
+			total.AverageEntry = cost.Div(total.Quantity)
+		}
+		total.Realized = total.Realized.Add(p.Realized)
+		total.Unrealized = total.Unrealized.Add(p.Unrealized)
+		if p.MarkPrice.IsPositive() {
+			total.MarkPrice = p.MarkPrice
+		}
+	}
+	var positions = make([]Position, 0, len(bySymbol))
+	for _, p := range bySymbol {
+		positions = append(positions, *p)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Symbol < positions[j].Symbol
+	})
+	return positions
+}
+
+// Assets net change of every asset per venue caused by the fills, fees included.
+func (t *Tracker) Assets() map[constants.Platform]map[string]decimal.Decimal {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	var assets = make(map[constants.Platform]map[string]decimal.Decimal, len(t.assets))
+	for venue, byAsset := range t.assets {
+		assets[venue] = make(map[string]decimal.Decimal, len(byAsset))
+		for asset, amount := range byAsset {
+			assets[venue][asset] = amount
+		}
+	}
+	return assets
+}
+
+// Fees paid per currency across venues.
+func (t *Tracker) Fees() map[string]decimal.Decimal {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	var fees = make(map[string]decimal.Decimal, len(t.fees))
+	for currency, fee := range t.fees {
+		fees[currency] = fee
+	}
+	return fees
+}
+
+// apply a signed fill quantity, closing opposite lots first. The caller holds mux.
+func (t *Tracker) apply(p *position, quantity, price decimal.Decimal) {
+	for !quantity.IsZero() && len(p.lots) > 0 && p.lots[0].quantity.Sign() != quantity.Sign() {
+		i := 0
+		if t.method == LIFO {
+			i = len(p.lots) - 1
+		}
+		open := p.lots[i]
+		closed := decimal.Min(open.quantity.Abs(), quantity.Abs())
+		if open.quantity.IsPositive() {
+			p.realized = p.realized.Add(price.Sub(open.price).Mul(closed))
+			open.quantity = open.quantity.Sub(closed)
+			quantity = quantity.Add(closed)
+		} else {
+			p.realized = p.realized.Add(open.price.Sub(price).Mul(closed))
+			open.quantity = open.quantity.Add(closed)
+			quantity = quantity.Sub(closed)
+		}
+		if open.quantity.IsZero() {
+			p.lots = append(p.lots[:i], p.lots[i+1:]...)
+		} else {
+			p.lots[i] = open
+		}
+	}
+	if quantity.IsZero() {
+		return
+	}
+	if t.method == AverageCost && len(p.lots) == 1 {
+		total := p.lots[0].quantity.Add(quantity)
+		p.lots[0] = lot{
+			quantity: total,
+			price:    p.lots[0].price.Mul(p.lots[0].quantity).Add(price.Mul(quantity)).Div(total),
+		}
+		return
+	}
+	p.lots = append(p.lots, lot{quantity: quantity, price: price})
+}
+
+func summarize(venue constants.Platform, symbol string, realized, mark decimal.Decimal, lots []lot) Position {
+	p := Position{Venue: venue, Symbol: symbol, Realized: realized, MarkPrice: mark}
+	var cost decimal.Decimal
+	for _, l := range lots {
+		p.Quantity = p.Quantity.Add(l.quantity)
+		cost = cost.Add(l.quantity.Mul(l.price))
+		if mark.IsPositive() {
+			p.Unrealized = p.Unrealized.Add(mark.Sub(l.price).Mul(l.quantity))
+		}
+	}
+	if !p.Quantity.IsZero() {
+		p.AverageEntry = cost.Div(p.Quantity)
+	}
+	return p
+}
@@ -0,0 +1,55 @@
+package pnl
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+func fill(id, side string, quantity, price int64) types.FillEntry {
+	return types.FillEntry{
+		Symbol:      "BTC_USDT",
+		TradeId:     id,
+		Side:        side,
+		Quantity:    decimal.NewFromInt(quantity),
+		Price:       decimal.NewFromInt(price),
+		Fee:         decimal.NewFromFloat(0.1),
+		FeeCurrency: "usdt",
+	}
+}
+
+func TestTracker(t *testing.T) {
+	fills := []types.FillEntry{fill("1", "BUY", 1, 100), fill("2", "BUY", 1, 200), fill("3", "SELL", 1, 300)}
+	for method, realized := range map[Method]int64{FIFO: 200, LIFO: 100, AverageCost: 150} {
+		tracker := NewTracker(method)
+		for _, f := range fills {
+			tracker.OnFill("A", f)
+		}
+		tracker.OnFill("A", fills[2]) // duplicate from the stream
+		tracker.Mark("A", "BTCUSDT", decimal.NewFromInt(250))
+		positions := tracker.Positions()
+		if len(positions) != 1 || !positions[0].Quantity.Equal(decimal.NewFromInt(1)) ||
+			!positions[0].Realized.Equal(decimal.NewFromInt(realized)) ||
+			!positions[0].Unrealized.Equal(decimal.NewFromInt(250).Sub(positions[0].AverageEntry)) {
+			t.Errorf("%s: unexpected positions %+v", method, positions)
+		}
+	}
+
+	tracker := NewTracker(FIFO)
+	tracker.OnFill("A", fill("1", "BUY", 2, 100))
+	tracker.OnFill("B", fill("1", "SELL", 1, 110))
+	tracker.Mark("A", "BTCUSDT", decimal.NewFromInt(120))
+	tracker.Mark("B", "BTCUSDT", decimal.NewFromInt(120))
+	consolidated := tracker.Consolidated()
+	if len(consolidated) != 1 || !consolidated[0].Quantity.Equal(decimal.NewFromInt(1)) ||
+		!consolidated[0].Unrealized.Equal(decimal.NewFromInt(30)) {
+		t.Errorf("unexpected consolidated positions %+v", consolidated)
+	}
+	if fees := tracker.Fees(); !fees["USDT"].Equal(decimal.NewFromFloat(0.2)) {
+		t.Errorf("unexpected fees %+v", fees)
+	}
+	if assets := tracker.Assets(); !assets["A"]["USDT"].Equal(decimal.NewFromFloat(-200.1)) || !assets["B"]["BTC"].Equal(decimal.NewFromInt(-1)) {
+		t.Errorf("unexpected assets %+v", assets)
+	}
+}
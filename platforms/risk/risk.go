@@ -0,0 +1,371 @@
+// Package risk pre-trade checks and a kill switch in front of any SpotConnector.
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Rule a pre-trade check.
+type Rule string
+
+const (
+	MaxNotional   Rule = "MAX_NOTIONAL"
+	MaxPosition   Rule = "MAX_POSITION"
+	MaxOpenOrders Rule = "MAX_OPEN_ORDERS"
+	PriceBand     Rule = "PRICE_BAND"
+	OrderRate     Rule = "ORDER_RATE"
+	CancelRate    Rule = "CANCEL_RATE"
+	KillSwitch    Rule = "KILL_SWITCH"
+)
+
+// ErrRejected matches every RejectError with errors.Is.
+var ErrRejected = errors.New("rejected by risk check")
+
+// RejectError a request stopped before reaching the exchange.
+type RejectError struct {
+	Rule    Rule
+	Symbol  string
+	Message string
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("[Risk] %s %s: %s", e.Rule, e.Symbol, e.Message)
+}
+
+func (e *RejectError) Unwrap() error {
+	return ErrRejected
+}
+
+// Limits zero values disable the corresponding check.
+type Limits struct {
+	// MaxNotional quote value of a single order, priced at the order price or the last price for market orders
+	MaxNotional decimal.Decimal
+	// MaxPosition holdings of an asset (free, locked and pending buys) a buy may lead to, by asset e.g. "BTC"
+	MaxPosition map[string]decimal.Decimal
+	// MaxOpenOrders pending orders per symbol
+	MaxOpenOrders int
+	// PriceBand largest distance of a limit price from the last price as a ratio, e.g. 0.05 for 5%
+	PriceBand decimal.Decimal
+	// OrderRate, CancelRate requests allowed per RateWindow, attempts rejected by the rate are not counted
+	OrderRate  int
+	CancelRate int
+	// RateWindow sliding window of the rate limits, default one second
+	RateWindow time.Duration
+}
+
+// Connector SpotConnector whose orders pass the checks of Limits first.
+type Connector struct {
+	platforms.SpotConnector
+	limits Limits
+	// entry one order entry at a time from check to send, so the checks see the orders sent before
+	entry   sync.Mutex
+	mux     sync.Mutex
+	killed  bool
+	orders  []time.Time
+	cancels []time.Time
+	// symbols traded through the connector, canceled by Kill where CancelAllSymbols is not supported
+	symbols map[string]struct{}
+}
+
+func New(connector platforms.SpotConnector, limits Limits) *Connector {
+	if limits.RateWindow <= 0 {
+		limits.RateWindow = time.Second
+	}
+	return &Connector{SpotConnector: connector, limits: limits, symbols: make(map[string]struct{})}
+}
+
+// Kill block new orders and cancel every pending order. Cancels keep working while killed.
+func (c *Connector) Kill() error {
+	c.mux.Lock()
+	c.killed = true
+	var symbols []string
+	for symbol := range c.symbols {
+		symbols = append(symbols, symbol)
+	}
+	c.mux.Unlock()
+	err := c.SpotConnector.CancelAllSymbols()
+	if !errors.Is(err, platforms.ErrNotSupported) {
+		return err
+	}
+	var failures []error
+	for _, symbol := range symbols {
+		if err = c.SpotConnector.CancelAll(symbol); err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", symbol, err))
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// Resume accept new orders again after Kill.
+func (c *Connector) Resume() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.killed = false
+}
+
+// Killed whether the kill switch is engaged.
+func (c *Connector) Killed() bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.killed
+}
+
+func (c *Connector) PlaceOrder(order types.OrderEntry) (string, error) {
+	if err := c.admit(1, order.Symbol); err != nil {
+		return "", err
+	}
+	c.entry.Lock()
+	defer c.entry.Unlock()
+	if err := c.check(order, newStaged()); err != nil {
+		return "", err
+	}
+	return c.SpotConnector.PlaceOrder(order)
+}
+
+// BatchOrder send the orders passing the checks, the rejected ones come back with the rule as Code.
+// The orders passed count against the open orders and positions of the ones after them.
+func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	var symbols = make([]string, len(orders))
+	for i, order := range orders {
+		symbols[i] = order.Symbol
+	}
+	if err := c.admit(len(orders), symbols...); err != nil {
+		return nil, err
+	}
+	c.entry.Lock()
+	defer c.entry.Unlock()
+	var results = make([]types.BatchOrderResult, len(orders))
+	var passed []types.OrderEntry
+	var at []int
+	batch := newStaged()
+	for i, order := range orders {
+		if err := c.check(order, batch); err != nil {
+			results[i] = types.BatchOrderResult{TradeNo: order.TradeNo, Code: "RISK", Message: err.Error()}
+			var reject *RejectError
+			if errors.As(err, &reject) {
+				results[i].Code, results[i].Message = string(reject.Rule), reject.Message
+			}
+			continue
+		}
+		passed = append(passed, order)
+		at = append(at, i)
+	}
+	if len(passed) == 0 {
+		return results, nil
+	}
+	sent, err := c.SpotConnector.BatchOrder(passed)
+	for i, result := range sent {
+		results[at[i]] = result
+	}
+	return results, err
+}
+
+func (c *Connector) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	if err := c.admit(1, list.Symbol); err != nil {
+		return types.OrderListResult{}, err
+	}
+	c.entry.Lock()
+	defer c.entry.Unlock()
+	legs := newStaged()
+	for _, leg := range []*types.OrderEntry{list.Entry, list.TakeProfit, list.StopLoss} {
+		if leg == nil {
+			continue
+		}
+		if err := c.check(*leg, legs); err != nil {
+			return types.OrderListResult{}, err
+		}
+	}
+	return c.SpotConnector.PlaceOrderList(list)
+}
+
+func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
+	if err := c.admitCancel(1, symbol); err != nil {
+		return false, err
+	}
+	return c.SpotConnector.Cancel(symbol, orderId)
+}
+
+func (c *Connector) CancelAll(symbol string) error {
+	if err := c.admitCancel(1, symbol); err != nil {
+		return err
+	}
+	return c.SpotConnector.CancelAll(symbol)
+}
+
+func (c *Connector) CancelAllSymbols() error {
+	if err := c.admitCancel(1, ""); err != nil {
+		return err
+	}
+	return c.SpotConnector.CancelAllSymbols()
+}
+
+func (c *Connector) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	if err := c.admitCancel(len(orderIds), symbol); err != nil {
+		return nil, err
+	}
+	return c.SpotConnector.CancelByIds(symbol, orderIds)
+}
+
+// CancelAllAfter refresh the exchange countdown, not held by the kill switch nor the cancel rate.
+func (c *Connector) CancelAllAfter(timeout time.Duration) error {
+	return platforms.CancelAllAfter(c.SpotConnector, timeout)
+}
+
+// admit apply the kill switch and the order rate to n new orders on symbols.
+func (c *Connector) admit(n int, symbols ...string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.killed {
+		return &RejectError{Rule: KillSwitch, Symbol: symbols[0], Message: "kill switch engaged"}
+	}
+	var ok bool
+	c.orders, ok = take(c.orders, n, c.limits.OrderRate, c.limits.RateWindow)
+	if !ok {
+		return &RejectError{Rule: OrderRate, Symbol: symbols[0],
+			Message: fmt.Sprintf("more than %d orders per %s", c.limits.OrderRate, c.limits.RateWindow)}
+	}
+	for _, symbol := range symbols {
+		c.symbols[symbol] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Connector) admitCancel(n int, symbol string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	var ok bool
+	c.cancels, ok = take(c.cancels, n, c.limits.CancelRate, c.limits.RateWindow)
+	if !ok {
+		return &RejectError{Rule: CancelRate, Symbol: symbol,
+			Message: fmt.Sprintf("more than %d cancels per %s", c.limits.CancelRate, c.limits.RateWindow)}
+	}
+	return nil
+}
+
+// staged orders of one request that passed the checks, the exchange does not know them yet.
+type staged struct {
+	orders map[string]int             // symbol -> orders
+	bought map[string]decimal.Decimal // base asset -> buy quantity
+}
+
+func newStaged() *staged {
+	return &staged{orders: make(map[string]int), bought: make(map[string]decimal.Decimal)}
+}
+
+// check the limits of one order that need the exchange state, on top of the orders staged
+// before it in the same request. A passing order is staged.
+func (c *Connector) check(order types.OrderEntry, staged *staged) error {
+	reject := func(rule Rule, format string, args ...any) error {
+		return &RejectError{Rule: rule, Symbol: order.Symbol, Message: fmt.Sprintf(format, args...)}
+	}
+	price := order.Price
+	if c.limits.PriceBand.IsPositive() || (c.limits.MaxNotional.IsPositive() && !price.IsPositive()) {
+		ticker, err := c.GetTicker(order.Symbol)
+		if err != nil {
+			return fmt.Errorf("risk check needs the last price: %w", err)
+		}
+		if c.limits.PriceBand.IsPositive() && price.IsPositive() && ticker.Price.IsPositive() {
+			distance := price.Sub(ticker.Price).Abs().Div(ticker.Price)
+			if distance.GreaterThan(c.limits.PriceBand) {
+				return reject(PriceBand, "price %s is %s%% away from last %s", price,
+					distance.Mul(decimal.NewFromInt(100)).StringFixed(2), ticker.Price)
+			}
+		}
+		if !price.IsPositive() {
+			price = ticker.Price
+		}
+	}
+	if c.limits.MaxNotional.IsPositive() {
+		if notional := order.Quantity.Mul(price); notional.GreaterThan(c.limits.MaxNotional) {
+			return reject(MaxNotional, "notional %s above %s", notional, c.limits.MaxNotional)
+		}
+	}
+	buy := len(c.limits.MaxPosition) > 0 && strings.ToUpper(order.Side) == "BUY"
+	var pending []types.OpenOrderEntry
+	if c.limits.MaxOpenOrders > 0 || buy {
+		var err error
+		if pending, err = c.PendingOrders(order.Symbol); err != nil {
+			return fmt.Errorf("risk check needs the pending orders: %w", err)
+		}
+	}
+	if c.limits.MaxOpenOrders > 0 {
+		if count := len(pending) + staged.orders[order.Symbol]; count >= c.limits.MaxOpenOrders {
+			return reject(MaxOpenOrders, "%d orders already pending", count)
+		}
+	}
+	if buy {
+		base, err := c.checkPosition(order, pending, staged, reject)
+		if err != nil {
+			return err
+		}
+		staged.bought[base] = staged.bought[base].Add(order.Quantity)
+	}
+	staged.orders[order.Symbol]++
+	return nil
+}
+
+// checkPosition the buy against the limit of its base asset, which is returned.
+// Buys resting on the symbol count in full, the filled part of a partial fill is counted twice.
+func (c *Connector) checkPosition(order types.OrderEntry, pending []types.OpenOrderEntry, staged *staged, reject func(Rule, string, ...any) error) (string, error) {
+	symbol, err := constants.StandardizeSymbol(order.Symbol)
+	if err != nil {
+		return "", err
+	}
+	base := constants.UnifiedPattern.FindStringSubmatch(symbol)[1]
+	limit, ok := c.limits.MaxPosition[base]
+	if !ok {
+		return base, nil
+	}
+	balances, err := c.Balance([]string{base})
+	if err != nil {
+		return "", fmt.Errorf("risk check needs the balance: %w", err)
+	}
+	held := order.Quantity.Add(staged.bought[base])
+	for _, open := range pending {
+		if strings.ToUpper(open.Side) == "BUY" {
+			held = held.Add(open.Quantity)
+		}
+	}
+	if balance, ok := balances[base]; ok {
+		free, _ := decimal.NewFromString(balance.Free)
+		locked, _ := decimal.NewFromString(balance.Locked)
+		held = held.Add(free).Add(locked)
+	}
+	if held.GreaterThan(limit) {
+		return "", reject(MaxPosition, "%s position would reach %s, above %s", base, held, limit)
+	}
+	return base, nil
+}
+
+// take record n events in the sliding window, report false without recording when limit would be exceeded.
+func take(events []time.Time, n, limit int, window time.Duration) ([]time.Time, bool) {
+	if limit <= 0 {
+		return events, true
+	}
+	now := time.Now()
+	kept := events[:0]
+	for _, at := range events {
+		if now.Sub(at) < window {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept)+n > limit {
+		return kept, false
+	}
+	for i := 0; i < n; i++ {
+		kept = append(kept, now)
+	}
+	return kept, true
+}
@@ -0,0 +1,159 @@
+package risk
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+type exchange struct {
+	platforms.SpotConnector
+	placed   int
+	canceled bool
+	pending  []types.OpenOrderEntry
+}
+
+func (e *exchange) GetTicker(symbol string) (types.TickerEntry, error) {
+	return types.TickerEntry{Symbol: symbol, Price: decimal.NewFromInt(100)}, nil
+}
+
+func (e *exchange) Balance([]string) (map[string]types.BalanceEntry, error) {
+	return map[string]types.BalanceEntry{"BTC": {Currency: "BTC", Free: "1", Locked: "0.5"}}, nil
+}
+
+func (e *exchange) PendingOrders(string) ([]types.OpenOrderEntry, error) {
+	return slices.Clone(e.pending), nil
+}
+
+// PlaceOrder the order rests on the book, after a pause letting concurrent checks run.
+func (e *exchange) PlaceOrder(order types.OrderEntry) (string, error) {
+	time.Sleep(time.Millisecond)
+	e.placed++
+	e.pending = append(e.pending, types.OpenOrderEntry{Symbol: order.Symbol, Side: order.Side, Quantity: order.Quantity})
+	return "1", nil
+}
+
+func (e *exchange) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	e.placed += len(orders)
+	results := make([]types.BatchOrderResult, len(orders))
+	for i := range orders {
+		results[i].OrderId = "1"
+	}
+	return results, nil
+}
+
+func (e *exchange) CancelAllSymbols() error {
+	e.canceled = true
+	return nil
+}
+
+func TestConnector(t *testing.T) {
+	connector := &exchange{}
+	guarded := New(connector, Limits{
+		MaxNotional: decimal.NewFromInt(1000),
+		MaxPosition: map[string]decimal.Decimal{"BTC": decimal.NewFromInt(2)},
+		PriceBand:   decimal.NewFromFloat(0.05),
+		OrderRate:   4,
+		RateWindow:  time.Minute,
+	})
+	order := func(side string, quantity, price float64) types.OrderEntry {
+		return types.OrderEntry{Symbol: "BTCUSDT", Side: side, Quantity: decimal.NewFromFloat(quantity), Price: decimal.NewFromFloat(price)}
+	}
+	for _, c := range []struct {
+		order types.OrderEntry
+		rule  Rule
+	}{
+		{order("BUY", 0.5, 101), ""},
+		{order("BUY", 0.5, 110), PriceBand},
+		{order("SELL", 20, 100), MaxNotional},
+		{order("BUY", 1, 100), MaxPosition},
+		{order("SELL", 1, 100), OrderRate},
+	} {
+		_, err := guarded.PlaceOrder(c.order)
+		var reject *RejectError
+		if c.rule == "" && err != nil || c.rule != "" && (!errors.As(err, &reject) || reject.Rule != c.rule) {
+			t.Errorf("%+v: want %q, got %v", c.order, c.rule, err)
+		}
+	}
+	if connector.placed != 1 {
+		t.Errorf("expected one order through, got %d", connector.placed)
+	}
+
+	if err := guarded.Kill(); err != nil || !connector.canceled {
+		t.Fatalf("kill switch did not cancel: %v", err)
+	}
+	if _, err := guarded.PlaceOrder(order("SELL", 0.1, 100)); !errors.Is(err, ErrRejected) {
+		t.Errorf("expected kill switch rejection, got %v", err)
+	}
+}
+
+func TestBatchOrder(t *testing.T) {
+	connector := &exchange{}
+	guarded := New(connector, Limits{
+		MaxPosition:   map[string]decimal.Decimal{"BTC": decimal.NewFromInt(2)},
+		MaxOpenOrders: 2,
+	})
+	order := func(symbol, side string, quantity float64) types.OrderEntry {
+		return types.OrderEntry{Symbol: symbol, Side: side, Quantity: decimal.NewFromFloat(quantity), Price: decimal.NewFromInt(100)}
+	}
+	results, err := guarded.BatchOrder([]types.OrderEntry{
+		order("BTCUSDT", "BUY", 0.3),
+		// 1.5 held and 0.3 bought above
+		order("BTCUSDT", "BUY", 0.3),
+		order("ETHUSDT", "SELL", 1),
+		order("BTCUSDT", "SELL", 1),
+		// two BTCUSDT orders passed already
+		order("BTCUSDT", "SELL", 1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	for _, result := range results {
+		codes = append(codes, result.Code)
+	}
+	want := []string{"", string(MaxPosition), "", "", string(MaxOpenOrders)}
+	if !slices.Equal(codes, want) || connector.placed != 3 {
+		t.Errorf("want %v, got %v with %d placed", want, codes, connector.placed)
+	}
+	if _, ok := guarded.symbols["ETHUSDT"]; !ok {
+		t.Error("symbol of a later batch order not kept for the kill switch")
+	}
+}
+
+func TestConcurrentOrders(t *testing.T) {
+	connector := &exchange{}
+	guarded := New(connector, Limits{MaxOpenOrders: 1})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = guarded.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", Side: "SELL", Quantity: decimal.NewFromInt(1)})
+		}()
+	}
+	wg.Wait()
+	if connector.placed != 1 {
+		t.Errorf("checks raced the orders sent, %d placed", connector.placed)
+	}
+}
+
+func TestPendingBuys(t *testing.T) {
+	connector := &exchange{pending: []types.OpenOrderEntry{
+		{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromFloat(0.4)},
+		{Symbol: "BTCUSDT", Side: "SELL", Quantity: decimal.NewFromInt(1)},
+	}}
+	guarded := New(connector, Limits{MaxPosition: map[string]decimal.Decimal{"BTC": decimal.NewFromInt(2)}})
+	// 1.5 held and 0.4 resting
+	_, err := guarded.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT", Side: "BUY", Quantity: decimal.NewFromFloat(0.2), Price: decimal.NewFromInt(100)})
+	var reject *RejectError
+	if !errors.As(err, &reject) || reject.Rule != MaxPosition {
+		t.Errorf("resting buy not counted in the position, got %v", err)
+	}
+}
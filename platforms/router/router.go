@@ -0,0 +1,367 @@
+// Package router splits an order across venues by the fee adjusted prices of their books,
+// within the balance available on each venue.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/platforms/orderbook"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Venue an exchange the router may send children to.
+type Venue struct {
+	Connector platforms.SpotConnector
+	// Book live book of the symbol on the venue, e.g. kept by Book.Maintain.
+	// nil reads GetOrderBook on every route.
+	Book *orderbook.Book
+	// StepSize quantity increment of the symbol on the venue, zero leaves quantities unrounded
+	StepSize decimal.Decimal
+}
+
+// Child order sent to one venue and its outcome.
+type Child struct {
+	Venue constants.Platform
+	Order types.OrderEntry
+	// TakerRate fee rate the allocation was priced with
+	TakerRate decimal.Decimal
+	OrderId   string
+	Filled    decimal.Decimal
+	// AveragePrice of the fills, zero before the fills are read
+	AveragePrice decimal.Decimal
+	Fee          decimal.Decimal
+	Err          error
+	// venue index in Router.venues, the same exchange may appear with several accounts
+	venue int
+}
+
+// Report aggregated outcome of a routed order.
+type Report struct {
+	Symbol   string
+	Side     string
+	Quantity decimal.Decimal
+	Filled   decimal.Decimal
+	// AveragePrice volume weighted over every child, zero without fills
+	AveragePrice decimal.Decimal
+	// Fee sum of the child fees; fees charged in different assets are added as reported
+	Fee      decimal.Decimal
+	Children []Child
+}
+
+// Router splits orders across its venues.
+type Router struct {
+	venues []Venue
+	// Depth levels read per venue when it has no live book, default 50
+	Depth int64
+	// Settle time children get to fill before their rest is canceled, default 2s
+	Settle time.Duration
+	mux    sync.Mutex
+	// taker rates by venue and symbol, fee tiers rarely change during a session
+	rates map[string]decimal.Decimal
+}
+
+func NewRouter(venues ...Venue) *Router {
+	return &Router{
+		venues: venues,
+		Depth:  50,
+		Settle: 2 * time.Second,
+		rates:  make(map[string]decimal.Decimal),
+	}
+}
+
+// quote state of one venue read for a plan
+type quote struct {
+	levels []orderbook.Level
+	rate   decimal.Decimal
+	// budget free quote balance for a buy, free base balance for a sell
+	budget decimal.Decimal
+	err    error
+}
+
+// Plan allocate quantity of symbol over the venues without sending anything.
+// Levels are ranked by price after taker fee; a buy never takes asks above limitPrice and a sell
+// never takes bids below it, a zero limitPrice takes any level. Each child is a limit order at the
+// worst level allocated on its venue. The plan may cover less than quantity when the books or
+// balances are short; a venue whose book or balance can not be read is skipped.
+func (r *Router) Plan(symbol, side string, quantity, limitPrice decimal.Decimal) ([]Child, error) {
+	symbol, err := constants.StandardizeSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if !quantity.IsPositive() {
+		return nil, errors.New("quantity must be positive")
+	}
+	buy := strings.ToUpper(side) == "BUY"
+	matches := constants.UnifiedPattern.FindStringSubmatch(symbol)
+	base, quoteAsset := matches[1], matches[2]
+
+	quotes := make([]quote, len(r.venues))
+	var wg sync.WaitGroup
+	for i := range r.venues {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			quotes[i] = r.quote(r.venues[i], symbol, base, quoteAsset, buy)
+		}(i)
+	}
+	wg.Wait()
+
+	type candidate struct {
+		venue     int
+		level     orderbook.Level
+		effective decimal.Decimal
+	}
+	var candidates []candidate
+	var failed []string
+	for i, q := range quotes {
+		if q.err != nil {
+			platforms.LoggerOf(r.venues[i].Connector).Warn("venue skipped", slog.String("platform", string(r.venues[i].Connector.Name())), slog.String("error", q.err.Error()))
+			failed = append(failed, fmt.Sprintf("%s: %v", r.venues[i].Connector.Name(), q.err))
+			continue
+		}
+		for _, level := range q.levels {
+			if limitPrice.IsPositive() &&
+				((buy && level.Price.GreaterThan(limitPrice)) || (!buy && level.Price.LessThan(limitPrice))) {
+				break
+			}
+			effective := level.Price.Mul(decimal.NewFromInt(1).Add(q.rate))
+			if !buy {
+				effective = level.Price.Mul(decimal.NewFromInt(1).Sub(q.rate))
+			}
+			candidates = append(candidates, candidate{venue: i, level: level, effective: effective})
+		}
+	}
+	if len(failed) == len(r.venues) {
+		return nil, fmt.Errorf("no venue available: %s", strings.Join(failed, "; "))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if buy {
+			return candidates[i].effective.LessThan(candidates[j].effective)
+		}
+		return candidates[i].effective.GreaterThan(candidates[j].effective)
+	})
+
+	allocated := make([]decimal.Decimal, len(r.venues))
+	worst := make([]decimal.Decimal, len(r.venues))
+	remaining := quantity
+	for _, c := range candidates {
+		if !remaining.IsPositive() {
+			break
+		}
+		q := &quotes[c.venue]
+		take := decimal.Min(c.level.Quantity, remaining)
+		if buy {
+			// the budget is spent at the effective price, fees included
+			take = decimal.Min(take, q.budget.Div(c.effective))
+		} else {
+			take = decimal.Min(take, q.budget)
+		}
+		if !take.IsPositive() {
+			continue
+		}
+		if buy {
+			q.budget = q.budget.Sub(take.Mul(c.effective))
+		} else {
+			q.budget = q.budget.Sub(take)
+		}
+		allocated[c.venue] = allocated[c.venue].Add(take)
+		worst[c.venue] = c.level.Price
+		remaining = remaining.Sub(take)
+	}
+
+	var children []Child
+	for i, venue := range r.venues {
+		amount := allocated[i]
+		if venue.StepSize.IsPositive() {
+			amount = amount.Div(venue.StepSize).Floor().Mul(venue.StepSize)
+		}
+		if !amount.IsPositive() {
+			continue
+		}
+		children = append(children, Child{
+			Venue: venue.Connector.Name(),
+			Order: types.OrderEntry{
+				Symbol:   venue.Connector.SymbolPattern(symbol),
+				Type:     constants.Limit,
+				Side:     strings.ToUpper(side),
+				Price:    worst[i],
+				Quantity: amount,
+			},
+			TakerRate: quotes[i].rate,
+			venue:     i,
+		})
+	}
+	if len(children) == 0 {
+		return nil, errors.New("no liquidity within limit price and balances")
+	}
+	return children, nil
+}
+
+// Route plan and send the children, give them Settle to fill, cancel their rest and report the fills.
+// A child that failed carries its error in Child.Err; the error is only set when nothing could be sent.
+func (r *Router) Route(ctx context.Context, symbol, side string, quantity, limitPrice decimal.Decimal) (Report, error) {
+	symbol, err := constants.StandardizeSymbol(symbol)
+	if err != nil {
+		return Report{}, err
+	}
+	children, err := r.Plan(symbol, side, quantity, limitPrice)
+	if err != nil {
+		return Report{}, err
+	}
+	since := time.Now().UnixMilli()
+	r.each(children, func(venue Venue, child *Child) {
+		child.OrderId, child.Err = venue.Connector.PlaceOrder(child.Order)
+	})
+	var sent bool
+	for _, child := range children {
+		sent = sent || child.Err == nil
+	}
+	if !sent {
+		return report(symbol, side, quantity, children), children[0].Err
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(r.Settle):
+	}
+	r.each(children, func(venue Venue, child *Child) {
+		if child.Err != nil {
+			return
+		}
+		r.settle(ctx, venue, child, since)
+	})
+	return report(symbol, side, quantity, children), nil
+}
+
+// settle cancel the rest of child and read its fills.
+func (r *Router) settle(ctx context.Context, venue Venue, child *Child, since int64) {
+	symbol := child.Order.Symbol
+	_, err := venue.Connector.Cancel(symbol, child.OrderId)
+	if err != nil && !errors.Is(err, platforms.ErrOrderNotFound) &&
+		!errors.Is(err, platforms.ErrOrderFilled) && !errors.Is(err, platforms.ErrOrderClosed) {
+		platforms.LoggerOf(venue.Connector).Warn("cancel child failed", slog.String("platform", string(child.Venue)), slog.String("symbol", symbol), slog.String("order", child.OrderId), slog.String("error", err.Error()))
+	}
+	fills, err := venue.Connector.GetMyTrades(ctx, symbol, since, time.Now().UnixMilli())
+	if err == nil {
+		var quoteFilled decimal.Decimal
+		for _, fill := range fills {
+			if fill.OrderId != child.OrderId {
+				continue
+			}
+			child.Filled = child.Filled.Add(fill.Quantity)
+			child.Fee = child.Fee.Add(fill.Fee)
+			quoteFilled = quoteFilled.Add(fill.Quantity.Mul(fill.Price))
+		}
+		if child.Filled.IsPositive() {
+			child.AveragePrice = quoteFilled.Div(child.Filled)
+		}
+		return
+	}
+	// without the fills the order's own state bounds the price by its limit
+	order, err := venue.Connector.QueryOrder(symbol, child.OrderId)
+	if err != nil {
+		child.Err = err
+		return
+	}
+	child.Filled = order.Filled
+	if order.Filled.IsPositive() {
+		child.AveragePrice = child.Order.Price
+		child.Fee = order.Filled.Mul(child.Order.Price).Mul(child.TakerRate)
+	}
+}
+
+// quote read book, taker rate and balance of venue.
+func (r *Router) quote(venue Venue, symbol, base, quoteAsset string, buy bool) quote {
+	var q quote
+	if venue.Book != nil {
+		if buy {
+			q.levels = venue.Book.Asks(0)
+		} else {
+			q.levels = venue.Book.Bids(0)
+		}
+	} else {
+		book := orderbook.NewBook(symbol)
+		depth := r.Depth
+		snapshot, err := venue.Connector.GetOrderBook(venue.Connector.SymbolPattern(symbol), &depth)
+		if err != nil {
+			return quote{err: err}
+		}
+		book.Reset(snapshot)
+		if buy {
+			q.levels = book.Asks(0)
+		} else {
+			q.levels = book.Bids(0)
+		}
+	}
+	q.rate, q.err = r.rate(venue.Connector, symbol)
+	if q.err != nil {
+		return q
+	}
+	asset := base
+	if buy {
+		asset = quoteAsset
+	}
+	balances, err := venue.Connector.Balance([]string{asset})
+	if err != nil {
+		return quote{err: err}
+	}
+	if balance, ok := balances[asset]; ok {
+		q.budget, _ = decimal.NewFromString(balance.Free)
+	}
+	return q
+}
+
+func (r *Router) rate(connector platforms.SpotConnector, symbol string) (decimal.Decimal, error) {
+	key := string(connector.Name()) + ":" + symbol
+	r.mux.Lock()
+	rate, ok := r.rates[key]
+	r.mux.Unlock()
+	if ok {
+		return rate, nil
+	}
+	rates, err := connector.GetFeeRates([]string{symbol})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	rate = rates[symbol].Taker
+	r.mux.Lock()
+	r.rates[key] = rate
+	r.mux.Unlock()
+	return rate, nil
+}
+
+// each run fn for every child concurrently on its venue.
+func (r *Router) each(children []Child, fn func(venue Venue, child *Child)) {
+	var wg sync.WaitGroup
+	for i := range children {
+		wg.Add(1)
+		go func(child *Child) {
+			defer wg.Done()
+			fn(r.venues[child.venue], child)
+		}(&children[i])
+	}
+	wg.Wait()
+}
+
+func report(symbol, side string, quantity decimal.Decimal, children []Child) Report {
+	rep := Report{Symbol: symbol, Side: strings.ToUpper(side), Quantity: quantity, Children: children}
+	var quoteFilled decimal.Decimal
+	for _, child := range children {
+		rep.Filled = rep.Filled.Add(child.Filled)
+		rep.Fee = rep.Fee.Add(child.Fee)
+		quoteFilled = quoteFilled.Add(child.Filled.Mul(child.AveragePrice))
+	}
+	if rep.Filled.IsPositive() {
+		rep.AveragePrice = quoteFilled.Div(rep.Filled)
+	}
+	return rep
+}
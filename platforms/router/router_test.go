@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// venue fills every order in full at its limit price
+type venue struct {
+	platforms.SpotConnector
+	name    constants.Platform
+	asks    [][]string
+	taker   decimal.Decimal
+	balance string
+	placed  []types.OrderEntry
+}
+
+func (v *venue) Name() constants.Platform { return v.name }
+
+func (v *venue) SymbolPattern(symbol string) string { return symbol }
+
+func (v *venue) GetOrderBook(symbol string, _ *int64) (types.OrderBookEntry, error) {
+	return types.OrderBookEntry{Symbol: symbol, Asks: v.asks}, nil
+}
+
+func (v *venue) GetFeeRates(symbols []string) (map[string]types.FeeRateEntry, error) {
+	return map[string]types.FeeRateEntry{symbols[0]: {Symbol: symbols[0], Taker: v.taker}}, nil
+}
+
+func (v *venue) Balance(assets []string) (map[string]types.BalanceEntry, error) {
+	return map[string]types.BalanceEntry{assets[0]: {Currency: assets[0], Free: v.balance}}, nil
+}
+
+func (v *venue) PlaceOrder(order types.OrderEntry) (string, error) {
+	v.placed = append(v.placed, order)
+	return string(v.name) + "-1", nil
+}
+
+func (v *venue) Cancel(string, string) (bool, error) {
+	return false, platforms.ErrOrderFilled
+}
+
+func (v *venue) GetMyTrades(_ context.Context, symbol string, _, _ int64) ([]types.FillEntry, error) {
+	var fills []types.FillEntry
+	for _, order := range v.placed {
+		fills = append(fills, types.FillEntry{
+			Symbol:   symbol,
+			OrderId:  string(v.name) + "-1",
+			Price:    order.Price,
+			Quantity: order.Quantity,
+			Fee:      order.Quantity.Mul(order.Price).Mul(v.taker),
+		})
+	}
+	return fills, nil
+}
+
+func TestRoute(t *testing.T) {
+	// cheaper book but higher fee and a balance for one BTC only
+	a := &venue{name: "A", asks: [][]string{{"100", "1"}, {"101", "5"}}, taker: decimal.NewFromFloat(0.002), balance: "100.2"}
+	b := &venue{name: "B", asks: [][]string{{"100.1", "1"}, {"100.5", "5"}}, taker: decimal.NewFromFloat(0.001), balance: "100000"}
+	router := NewRouter(Venue{Connector: a}, Venue{Connector: b})
+	router.Settle = time.Millisecond
+
+	report, err := router.Route(context.Background(), "BTC-USDT", "buy", decimal.NewFromInt(3), decimal.NewFromInt(101))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.placed) != 1 || !a.placed[0].Quantity.Equal(decimal.NewFromInt(1)) || !a.placed[0].Price.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("unexpected child on A %+v", a.placed)
+	}
+	if len(b.placed) != 1 || !b.placed[0].Quantity.Equal(decimal.NewFromInt(2)) || !b.placed[0].Price.Equal(decimal.NewFromFloat(100.5)) {
+		t.Errorf("unexpected child on B %+v", b.placed)
+	}
+	if !report.Filled.Equal(decimal.NewFromInt(3)) || !report.AveragePrice.Round(4).Equal(decimal.NewFromFloat(100.3333)) || !report.Fee.Equal(decimal.NewFromFloat(0.401)) {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	if _, err = router.Plan("BTCUSDT", "BUY", decimal.NewFromInt(1), decimal.NewFromInt(99)); err == nil {
+		t.Error("expected no liquidity below the limit price")
+	}
+}
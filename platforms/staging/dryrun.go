@@ -0,0 +1,218 @@
+package staging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// Request a request a DryRun kept from the exchange, signed as it would have been sent.
+// Headers carrying the api key or passphrase are redacted.
+type Request struct {
+	Venue  constants.Platform
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+}
+
+// errCaptured stops a captured request inside the connector's http client
+var errCaptured = errors.New("dry run: request not sent")
+
+// capture transport that keeps every non-GET request and sends GET requests on.
+type capture struct {
+	base     http.RoundTripper
+	dry      *DryRun
+	captured atomic.Int64
+}
+
+func (c *capture) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet {
+		return c.base.RoundTrip(req)
+	}
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+	}
+	header := req.Header.Clone()
+	for name := range header {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "key") || strings.Contains(lower, "passphrase") {
+			header.Set(name, "<redacted>")
+		}
+	}
+	c.captured.Add(1)
+	c.dry.OnRequest(Request{
+		Venue:  c.dry.Name(),
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: header,
+		Body:   string(bytes.TrimSpace(body)),
+	})
+	return nil, errCaptured
+}
+
+// DryRun SpotConnector that builds and signs order entry and cancel requests but does not send them.
+// Reads, Balance, GetOrderBook and PendingOrders included, reach the exchange.
+type DryRun struct {
+	platforms.SpotConnector
+	// OnRequest receives every request kept from the exchange, logs it by default
+	OnRequest func(Request)
+	// trader the same connector on the capturing client
+	trader    platforms.SpotConnector
+	transport *capture
+	// mux one mutating call at a time, so the captured requests belong to it
+	mux      sync.Mutex
+	sequence atomic.Uint64
+}
+
+// NewDryRun build the connector twice with newConnector, on client for reads and on a capturing
+// copy of client for order entry and cancels. client nil uses http.DefaultClient.
+func NewDryRun(newConnector func(client *http.Client) platforms.SpotConnector, client *http.Client) *DryRun {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	dry := &DryRun{SpotConnector: newConnector(client)}
+	dry.OnRequest = func(request Request) {
+		platforms.LoggerOf(dry.SpotConnector).Info("dry run request", slog.String("platform", string(request.Venue)),
+			slog.String("method", request.Method), slog.String("url", platforms.RedactURL(request.URL)), slog.String("body", request.Body))
+	}
+	dry.transport = &capture{base: base, dry: dry}
+	dry.trader = newConnector(&http.Client{Transport: dry.transport, Timeout: client.Timeout})
+	return dry
+}
+
+// Call capture requests other than GET, GET requests are sent.
+func (d *DryRun) Call(method string, route string, body platforms.Serializer,
+	authType constants.AuthType, returnType interface{}) error {
+	if method == http.MethodGet {
+		return d.SpotConnector.Call(method, route, body, authType, returnType)
+	}
+	return d.dry(func() error {
+		return d.trader.Call(method, route, body, authType, returnType)
+	})
+}
+
+func (d *DryRun) PlaceOrder(order types.OrderEntry) (string, error) {
+	err := d.dry(func() error {
+		_, err := d.trader.PlaceOrder(order)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return d.id(), nil
+}
+
+func (d *DryRun) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	err := d.dry(func() error {
+		_, err := d.trader.BatchOrder(orders)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var results = make([]types.BatchOrderResult, len(orders))
+	for i, order := range orders {
+		results[i] = types.BatchOrderResult{OrderId: d.id(), TradeNo: order.TradeNo}
+	}
+	return results, nil
+}
+
+func (d *DryRun) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	err := d.dry(func() error {
+		_, err := d.trader.PlaceOrderList(list)
+		return err
+	})
+	if err != nil {
+		return types.OrderListResult{}, err
+	}
+	result := types.OrderListResult{ListId: d.id()}
+	if list.Entry != nil {
+		result.EntryOrderId = d.id()
+	}
+	if list.TakeProfit != nil {
+		result.TakeProfitOrderId = d.id()
+	}
+	if list.StopLoss != nil {
+		result.StopLossOrderId = d.id()
+	}
+	return result, nil
+}
+
+func (d *DryRun) Cancel(symbol, orderId string) (bool, error) {
+	err := d.dry(func() error {
+		_, err := d.trader.Cancel(symbol, orderId)
+		return err
+	})
+	return err == nil, err
+}
+
+func (d *DryRun) CancelAll(symbol string) error {
+	return d.dry(func() error {
+		return d.trader.CancelAll(symbol)
+	})
+}
+
+func (d *DryRun) CancelAllSymbols() error {
+	return d.dry(func() error {
+		return d.trader.CancelAllSymbols()
+	})
+}
+
+func (d *DryRun) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	err := d.dry(func() error {
+		_, err := d.trader.CancelByIds(symbol, orderIds)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var results = make([]types.CancelResult, len(orderIds))
+	for i, orderId := range orderIds {
+		results[i] = types.CancelResult{OrderId: orderId}
+	}
+	return results, nil
+}
+
+func (d *DryRun) CancelAllAfter(timeout time.Duration) error {
+	return d.dry(func() error {
+		return platforms.CancelAllAfter(d.trader, timeout)
+	})
+}
+
+// dry run call on the capturing connector. A call that reached the transport succeeded;
+// one refused before any request, e.g. ErrNotSupported or a validation error, keeps its error.
+func (d *DryRun) dry(call func() error) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	before := d.transport.captured.Load()
+	err := call()
+	if d.transport.captured.Load() > before {
+		return nil
+	}
+	if err == nil {
+		return fmt.Errorf("dry run: %s sent no request", d.Name())
+	}
+	return err
+}
+
+func (d *DryRun) id() string {
+	return fmt.Sprintf("dry-run-%d", d.sequence.Add(1))
+}
@@ -0,0 +1,65 @@
+// Package staging connectors that run strategies against real market data and balances without trading.
+package staging
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// ErrReadOnly returned by every mutating method of a ReadOnly connector.
+var ErrReadOnly = errors.New("connector is read-only")
+
+// ReadOnly SpotConnector that rejects order entry and cancels, reads pass through.
+type ReadOnly struct {
+	platforms.SpotConnector
+}
+
+func NewReadOnly(connector platforms.SpotConnector) *ReadOnly {
+	return &ReadOnly{SpotConnector: connector}
+}
+
+// Call pass GET requests through, any other method may mutate and is rejected.
+func (r *ReadOnly) Call(method string, route string, body platforms.Serializer,
+	authType constants.AuthType, returnType interface{}) error {
+	if method != http.MethodGet {
+		return ErrReadOnly
+	}
+	return r.SpotConnector.Call(method, route, body, authType, returnType)
+}
+
+func (r *ReadOnly) PlaceOrder(types.OrderEntry) (string, error) {
+	return "", ErrReadOnly
+}
+
+func (r *ReadOnly) BatchOrder([]types.OrderEntry) ([]types.BatchOrderResult, error) {
+	return nil, ErrReadOnly
+}
+
+func (r *ReadOnly) PlaceOrderList(types.OrderListEntry) (types.OrderListResult, error) {
+	return types.OrderListResult{}, ErrReadOnly
+}
+
+func (r *ReadOnly) Cancel(string, string) (bool, error) {
+	return false, ErrReadOnly
+}
+
+func (r *ReadOnly) CancelAll(string) error {
+	return ErrReadOnly
+}
+
+func (r *ReadOnly) CancelAllSymbols() error {
+	return ErrReadOnly
+}
+
+func (r *ReadOnly) CancelByIds(string, []string) ([]types.CancelResult, error) {
+	return nil, ErrReadOnly
+}
+
+func (r *ReadOnly) CancelAllAfter(time.Duration) error {
+	return ErrReadOnly
+}
@@ -0,0 +1,51 @@
+package staging
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/platforms/okx"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+func TestDryRun(t *testing.T) {
+	passphrase := "passphrase"
+	cred := platforms.NewCredentials("api-key", "api-secret", &passphrase)
+	dry := NewDryRun(func(client *http.Client) platforms.SpotConnector {
+		return okx.NewConnector(cred, client)
+	}, nil)
+	var requests []Request
+	dry.OnRequest = func(request Request) {
+		requests = append(requests, request)
+	}
+	orderId, err := dry.PlaceOrder(types.OrderEntry{
+		Symbol:   "BTCUSDT",
+		Side:     "BUY",
+		Price:    decimal.NewFromInt(100),
+		Quantity: decimal.NewFromInt(1),
+	})
+	if err != nil || orderId == "" {
+		t.Fatalf("unexpected result %q, %v", orderId, err)
+	}
+	if len(requests) != 1 || requests[0].Method != http.MethodPost || !strings.Contains(requests[0].Body, `"instId":"BTC-USDT"`) {
+		t.Fatalf("unexpected requests %+v", requests)
+	}
+	for name, values := range requests[0].Header {
+		for _, value := range values {
+			if value == "api-key" || value == passphrase {
+				t.Errorf("secret leaked in header %s", name)
+			}
+		}
+	}
+	if requests[0].Header.Get("OK-ACCESS-SIGN") == "" {
+		t.Error("request not signed")
+	}
+
+	if _, err = NewReadOnly(dry).PlaceOrder(types.OrderEntry{}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected read-only rejection, got %v", err)
+	}
+}
@@ -1,18 +1,32 @@
 package platforms
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/xavierzho/go-cexs/types"
-	"log"
+	"github.com/xavierzho/go-cexs/utils"
+	"log/slog"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// StreamObserver sees the traffic of a stream, e.g. to export metrics. Calls must not block.
+type StreamObserver interface {
+	// OnMessage every message read, with the time it was received
+	OnMessage(msg []byte, received time.Time)
+	// OnDecodeError a message the stream could not decode
+	OnDecodeError(err error)
+	// OnReconnect a connection dialed again
+	OnReconnect()
+}
+
 type StreamBase struct {
+	Logging
+	observers     atomic.Pointer[[]StreamObserver]
 	dialer        *websocket.Dialer
 	conn          *websocket.Conn
 	url           string
@@ -22,6 +36,7 @@ type StreamBase struct {
 	mux           sync.RWMutex
 	reconnectChan chan struct{}
 	closeOne      sync.Once
+	stopPing      context.CancelFunc // pinger of the current connection
 }
 
 const (
@@ -39,6 +54,44 @@ func NewStream() *StreamBase {
 		reconnectChan: make(chan struct{}, 1),
 	}
 }
+
+// Observe report the traffic of the stream to observer as well, e.g. to metrics and tracing.
+func (stream *StreamBase) Observe(observer StreamObserver) {
+	for {
+		current := stream.observers.Load()
+		var observers []StreamObserver
+		if current != nil {
+			observers = append(observers, *current...)
+		}
+		observers = append(observers, observer)
+		if stream.observers.CompareAndSwap(current, &observers) {
+			return
+		}
+	}
+}
+
+// notify every observer of the stream.
+func (stream *StreamBase) notify(event func(observer StreamObserver)) {
+	if observers := stream.observers.Load(); observers != nil {
+		for _, observer := range *observers {
+			event(observer)
+		}
+	}
+}
+
+// Decode unmarshal a message read from the stream into v, reporting the failures.
+// Heartbeat text frames such as "pong" are not json and not reported.
+func (stream *StreamBase) Decode(msg []byte, v any) error {
+	err := utils.Json.Unmarshal(msg, v)
+	trimmed := bytes.TrimSpace(msg)
+	if err == nil || len(trimmed) == 0 || trimmed[0] != '{' && trimmed[0] != '[' {
+		return err
+	}
+	stream.Logger().Debug("stream message not decoded", slog.String("url", stream.url), slog.String("error", err.Error()))
+	stream.notify(func(observer StreamObserver) { observer.OnDecodeError(err) })
+	return err
+}
+
 func (stream *StreamBase) getConn() *websocket.Conn {
 	stream.mux.RLock()
 	defer stream.mux.RUnlock()
@@ -51,6 +104,9 @@ func (stream *StreamBase) Connect(url string) error {
 	if stream.conn != nil {
 		_ = stream.conn.Close()
 	}
+	if stream.url != "" {
+		stream.notify(StreamObserver.OnReconnect)
+	}
 	conn, _, err := stream.dialer.DialContext(stream.ctx, url, nil)
 	if err != nil {
 		return err
@@ -67,24 +123,66 @@ func (stream *StreamBase) Connect(url string) error {
 		return stream.conn.SetReadDeadline(time.Now().Add(defaultPingPeriod)) // 更新读取超时
 	})
 
-	go stream.KeepAlive(defaultPingPeriod)
+	// one pinger per connection, the one of the previous connection stops
+	if stream.stopPing != nil {
+		stream.stopPing()
+	}
+	var ctx context.Context
+	ctx, stream.stopPing = context.WithCancel(stream.ctx)
+	go stream.keepAlive(ctx, defaultPingPeriod)
 	return nil
 }
 func (stream *StreamBase) SendMessage(payload map[string]any) error {
 	stream.payload.Store(payload)
+	_, err := stream.Request(payload)
+	return err
+}
+
+// Request send json payload and return the next message, which is expected to be the reply.
+func (stream *StreamBase) Request(payload map[string]any) ([]byte, error) {
 	conn := stream.getConn()
 	if conn == nil {
-		return fmt.Errorf("not connected")
+		return nil, fmt.Errorf("not connected")
 	}
 	stream.mux.Lock()
 	defer stream.mux.Unlock()
 	err := stream.conn.WriteJSON(payload)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, _, err = stream.conn.ReadMessage()
-	return err
+	_, msg, err := stream.conn.ReadMessage()
+	return msg, err
 }
+
+// WriteJSON send json payload without waiting for a reply, for callers reading replies themselves.
+func (stream *StreamBase) WriteJSON(payload map[string]any) error {
+	conn := stream.getConn()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	stream.mux.Lock()
+	defer stream.mux.Unlock()
+	_ = stream.conn.SetWriteDeadline(time.Now().Add(defaultWriteWait))
+	return stream.conn.WriteJSON(payload)
+}
+
+// WriteText send a raw text frame, for venues that expect application level heartbeat like "ping".
+func (stream *StreamBase) WriteText(message string) error {
+	conn := stream.getConn()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	stream.mux.Lock()
+	defer stream.mux.Unlock()
+	_ = stream.conn.SetWriteDeadline(time.Now().Add(defaultWriteWait))
+	return stream.conn.WriteMessage(websocket.TextMessage, []byte(message))
+}
+
+// Done closed after Close is called.
+func (stream *StreamBase) Done() <-chan struct{} {
+	return stream.ctx.Done()
+}
+
 func (stream *StreamBase) Close() error {
 
 	stream.closeOne.Do(func() {
@@ -98,22 +196,24 @@ func (stream *StreamBase) Close() error {
 	})
 	return nil
 }
+
+// Reconnect dial the last url again and resend the last subscription.
+// Connect takes the lock itself, holding it here would deadlock.
 func (stream *StreamBase) Reconnect() error {
-	stream.mux.Lock()
-	defer stream.mux.Unlock()
 	// 指数退避重试
 	backoff := time.Second
 	maxBackoff := 30 * time.Second
 
-	for retry := 0; ; retry++ {
-		if err := stream.Connect(stream.url); err != nil {
+	for {
+		err := stream.Connect(stream.url)
+		if err == nil {
 			if payload := stream.payload.Load(); payload != nil {
 				return stream.SendMessage(payload.(map[string]any))
 			}
 			return nil
 		}
 		if backoff > maxBackoff {
-			return fmt.Errorf("max reconnect attempts reached")
+			return fmt.Errorf("max reconnect attempts reached: %w", err)
 		}
 		select {
 		case <-stream.ctx.Done():
@@ -121,26 +221,34 @@ func (stream *StreamBase) Reconnect() error {
 		case <-time.After(backoff):
 			backoff *= 2
 		}
-		time.Sleep(backoff)
 	}
 }
 func (stream *StreamBase) ReadMessage() ([]byte, error) {
 	conn := stream.getConn()
+	if conn == nil {
+		return nil, fmt.Errorf("not connected")
+	}
 	_, msg, err := conn.ReadMessage()
 	if err != nil {
 		return nil, err
 	}
+	received := time.Now()
+	stream.notify(func(observer StreamObserver) { observer.OnMessage(msg, received) })
 	return msg, nil
 }
 
 // KeepAlive keep alive must be with go keyword.
 func (stream *StreamBase) KeepAlive(interval time.Duration) {
+	stream.keepAlive(stream.ctx, interval)
+}
+
+func (stream *StreamBase) keepAlive(ctx context.Context, interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	//stream.conn
 	for {
 		select {
-		case <-stream.ctx.Done():
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			conn := stream.getConn()
@@ -149,7 +257,7 @@ func (stream *StreamBase) KeepAlive(interval time.Duration) {
 			}
 			err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(5*time.Second))
 			if err != nil {
-				log.Printf("Ping failed: %v, attempting reconnect", err)
+				stream.Logger().Warn("ping failed, reconnecting", slog.String("url", stream.url), slog.String("error", err.Error()))
 				stream.scheduleReconnect()
 			}
 		case <-stream.reconnectChan:
@@ -160,7 +268,7 @@ func (stream *StreamBase) KeepAlive(interval time.Duration) {
 func (stream *StreamBase) scheduleReconnect() {
 	select {
 	case stream.reconnectChan <- struct{}{}:
-		fmt.Println("Reconnect scheduled")
+		stream.Logger().Info("reconnect scheduled", slog.String("url", stream.url))
 	default:
 
 	}
@@ -0,0 +1,324 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Connector SpotConnector whose order entry and cancels are spans, children of the caller's context.
+type Connector struct {
+	platforms.SpotConnector
+	tracer *Tracer
+}
+
+// Connector trace the order entry and cancels of connector, see Instrument for its REST calls.
+func (t *Tracer) Connector(connector platforms.SpotConnector) *Connector {
+	return &Connector{SpotConnector: connector, tracer: t}
+}
+
+// PlaceOrderContext place order in a span child of ctx, e.g. of the strategy decision.
+// The acknowledgement is an event on the span, the stream events of the order its children.
+// An order without TradeNo is given one, the stream may report it before the acknowledgement.
+func (c *Connector) PlaceOrderContext(ctx context.Context, order types.OrderEntry) (string, error) {
+	if order.TradeNo == "" {
+		order.TradeNo = platforms.NewClientId()
+	}
+	span := c.orderSpan(ctx, order)
+	defer span.End()
+	orderId, err := c.SpotConnector.PlaceOrder(order)
+	if err != nil {
+		record(span, err)
+		return orderId, err
+	}
+	c.acknowledge(span, orderId)
+	return orderId, nil
+}
+
+// orderSpan start the span of placing order, found by its client order id until acknowledged.
+func (c *Connector) orderSpan(ctx context.Context, order types.OrderEntry) trace.Span {
+	platform := c.Name()
+	_, span := c.tracer.tracer.Start(ctx, "PlaceOrder", trace.WithAttributes(
+		PlatformKey.String(string(platform)),
+		SymbolKey.String(order.Symbol),
+		ClientOrderIdKey.String(order.TradeNo),
+		attribute.String("cex.side", order.Side),
+		attribute.String("cex.price", order.Price.String()),
+		attribute.String("cex.quantity", order.Quantity.String()),
+	))
+	c.tracer.remember(span.SpanContext(), clientKey(platform, order.TradeNo))
+	return span
+}
+
+// acknowledge the order of span was accepted as orderId.
+func (c *Connector) acknowledge(span trace.Span, orderId string) {
+	span.SetAttributes(OrderIdKey.String(orderId))
+	span.AddEvent("acknowledged", trace.WithAttributes(OrderIdKey.String(orderId)))
+	c.tracer.remember(span.SpanContext(), orderKey(c.Name(), orderId))
+}
+
+func (c *Connector) PlaceOrder(order types.OrderEntry) (string, error) {
+	return c.PlaceOrderContext(context.Background(), order)
+}
+
+// BatchOrderContext place orders in a span child of ctx, every order a PlaceOrder span child of it.
+// Orders without TradeNo are given one, as for PlaceOrderContext.
+func (c *Connector) BatchOrderContext(ctx context.Context, orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	orders = slices.Clone(orders)
+	ctx, batch := c.tracer.tracer.Start(ctx, "BatchOrder", trace.WithAttributes(
+		PlatformKey.String(string(c.Name())),
+		attribute.Int("cex.orders", len(orders)),
+	))
+	defer batch.End()
+	spans := make([]trace.Span, len(orders))
+	for i := range orders {
+		if orders[i].TradeNo == "" {
+			orders[i].TradeNo = platforms.NewClientId()
+		}
+		spans[i] = c.orderSpan(ctx, orders[i])
+	}
+	results, err := c.SpotConnector.BatchOrder(orders)
+	for i, span := range spans {
+		switch {
+		case i < len(results) && results[i].Success():
+			c.acknowledge(span, results[i].OrderId)
+		case i < len(results) && (results[i].Code != "" || err == nil):
+			record(span, fmt.Errorf("%s %s", results[i].Code, results[i].Message))
+		default:
+			// the batch failed as a whole
+			record(span, err)
+		}
+		span.End()
+	}
+	record(batch, err)
+	return results, err
+}
+
+func (c *Connector) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	return c.BatchOrderContext(context.Background(), orders)
+}
+
+// PlaceOrderListContext place list in a span child of ctx, the span of every leg as for PlaceOrderContext.
+// Legs without TradeNo are given one.
+func (c *Connector) PlaceOrderListContext(ctx context.Context, list types.OrderListEntry) (types.OrderListResult, error) {
+	platform := c.Name()
+	_, span := c.tracer.tracer.Start(ctx, "PlaceOrderList", trace.WithAttributes(
+		PlatformKey.String(string(platform)),
+		SymbolKey.String(list.Symbol),
+		attribute.String("cex.list_type", string(list.Type)),
+	))
+	defer span.End()
+	for _, leg := range []**types.OrderEntry{&list.Entry, &list.TakeProfit, &list.StopLoss} {
+		if *leg == nil {
+			continue
+		}
+		order := **leg
+		if order.TradeNo == "" {
+			order.TradeNo = platforms.NewClientId()
+		}
+		*leg = &order
+		c.tracer.remember(span.SpanContext(), clientKey(platform, order.TradeNo))
+	}
+	result, err := c.SpotConnector.PlaceOrderList(list)
+	if err != nil {
+		record(span, err)
+		return result, err
+	}
+	span.SetAttributes(attribute.String("cex.list_id", result.ListId))
+	for _, orderId := range []string{result.EntryOrderId, result.TakeProfitOrderId, result.StopLossOrderId} {
+		if orderId != "" {
+			// legs created on trigger are found by client order id
+			span.AddEvent("acknowledged", trace.WithAttributes(OrderIdKey.String(orderId)))
+			c.tracer.remember(span.SpanContext(), orderKey(platform, orderId))
+		}
+	}
+	return result, nil
+}
+
+func (c *Connector) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	return c.PlaceOrderListContext(context.Background(), list)
+}
+
+// CancelContext cancel in a span child of ctx and linked to the span of the order.
+func (c *Connector) CancelContext(ctx context.Context, symbol, orderId string) (bool, error) {
+	platform := c.Name()
+	options := []trace.SpanStartOption{trace.WithAttributes(
+		PlatformKey.String(string(platform)),
+		SymbolKey.String(symbol),
+		OrderIdKey.String(orderId),
+	)}
+	if order, ok := c.tracer.lookup(orderKey(platform, orderId)); ok {
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: order}))
+	}
+	_, span := c.tracer.tracer.Start(ctx, "Cancel", options...)
+	defer span.End()
+	ok, err := c.SpotConnector.Cancel(symbol, orderId)
+	record(span, err)
+	return ok, err
+}
+
+func (c *Connector) Cancel(symbol, orderId string) (bool, error) {
+	return c.CancelContext(context.Background(), symbol, orderId)
+}
+
+// CancelByIdsContext cancel orderIds in a span child of ctx and linked to the spans of the orders.
+// An order not canceled is an event on the span.
+func (c *Connector) CancelByIdsContext(ctx context.Context, symbol string, orderIds []string) ([]types.CancelResult, error) {
+	platform := c.Name()
+	options := []trace.SpanStartOption{trace.WithAttributes(
+		PlatformKey.String(string(platform)),
+		SymbolKey.String(symbol),
+		attribute.StringSlice("cex.order_ids", orderIds),
+	)}
+	for _, orderId := range orderIds {
+		if order, ok := c.tracer.lookup(orderKey(platform, orderId)); ok {
+			options = append(options, trace.WithLinks(trace.Link{SpanContext: order}))
+		}
+	}
+	_, span := c.tracer.tracer.Start(ctx, "CancelByIds", options...)
+	defer span.End()
+	results, err := c.SpotConnector.CancelByIds(symbol, orderIds)
+	for _, result := range results {
+		if result.Err != nil {
+			span.AddEvent("failed", trace.WithAttributes(
+				OrderIdKey.String(result.OrderId),
+				attribute.String("exception.message", result.Err.Error()),
+			))
+		}
+	}
+	record(span, err)
+	return results, err
+}
+
+func (c *Connector) CancelByIds(symbol string, orderIds []string) ([]types.CancelResult, error) {
+	return c.CancelByIdsContext(context.Background(), symbol, orderIds)
+}
+
+func (c *Connector) CancelAllAfter(timeout time.Duration) error {
+	return platforms.CancelAllAfter(c.SpotConnector, timeout)
+}
+
+// OnUpdate a span for an order stream event, for handlers reading streams themselves.
+// It starts at the event time, so its duration is the delay until receipt.
+func (t *Tracer) OnUpdate(platform constants.Platform, update types.OrderUpdateEntry) {
+	ctx := context.Background()
+	options := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			PlatformKey.String(string(platform)),
+			SymbolKey.String(update.Symbol),
+			ClientOrderIdKey.String(update.ClientOrderId),
+			OrderIdKey.String(update.OrderId),
+			attribute.Int("cex.status", int(update.Status)),
+			attribute.String("cex.filled_quantity", update.FilledQuantity.String()),
+		),
+	}
+	var keys []string
+	if update.OrderId != "" {
+		keys = append(keys, orderKey(platform, update.OrderId))
+	}
+	if update.ClientOrderId != "" {
+		keys = append(keys, clientKey(platform, update.ClientOrderId))
+	}
+	if order, ok := t.lookup(keys...); ok {
+		ctx = trace.ContextWithSpanContext(ctx, order)
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: order}))
+		// the event may come before the acknowledgement, let later events find the order by id too
+		t.remember(order, keys...)
+	}
+	eventTime := update.TransactionTime
+	if eventTime == 0 {
+		eventTime = update.EventTime
+	}
+	if eventTime > 0 {
+		options = append(options, trace.WithTimestamp(time.UnixMilli(eventTime)))
+	}
+	_, span := t.tracer.Start(ctx, "OrderUpdate", options...)
+	if update.RejectReason != "" {
+		span.SetAttributes(attribute.String("cex.reject_reason", update.RejectReason))
+	}
+	if update.LastFillQuantity.IsPositive() {
+		span.AddEvent("fill", trace.WithAttributes(
+			attribute.String("cex.trade_id", update.TradeId),
+			attribute.String("cex.price", update.LastFillPrice.String()),
+			attribute.String("cex.quantity", update.LastFillQuantity.String()),
+		))
+	}
+	span.End()
+}
+
+// Streamer UserDataStreamer whose order stream events are traced.
+type Streamer struct {
+	platforms.UserDataStreamer
+	tracer   *Tracer
+	platform constants.Platform
+}
+
+// Streamer trace the order stream of streamer, platform names the venue the orders were placed on.
+func (t *Tracer) Streamer(platform constants.Platform, streamer platforms.UserDataStreamer) *Streamer {
+	return &Streamer{UserDataStreamer: streamer, tracer: t, platform: platform}
+}
+
+func (s *Streamer) OrderStream(ctx context.Context, channel chan<- types.OrderUpdateEntry) error {
+	updates := make(chan types.OrderUpdateEntry, cap(channel))
+	if err := s.UserDataStreamer.OrderStream(ctx, updates); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-updates:
+				s.tracer.OnUpdate(s.platform, update)
+				select {
+				case channel <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// observer platforms.StreamObserver turning the websocket read loop of a stream into spans.
+type observer struct {
+	tracer   *Tracer
+	platform constants.Platform
+}
+
+// Stream observer for a stream of platform, pass it to StreamBase.Observe.
+// Every message read is a span, reconnects and decode failures too.
+func (t *Tracer) Stream(platform constants.Platform) platforms.StreamObserver {
+	return &observer{tracer: t, platform: platform}
+}
+
+func (o *observer) OnMessage(msg []byte, received time.Time) {
+	_, span := o.tracer.tracer.Start(context.Background(), "Receive", trace.WithTimestamp(received),
+		trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(
+			PlatformKey.String(string(o.platform)),
+			attribute.Int("messaging.message.body.size", len(msg)),
+		))
+	span.End()
+}
+
+func (o *observer) OnDecodeError(err error) {
+	_, span := o.tracer.tracer.Start(context.Background(), "Decode",
+		trace.WithAttributes(PlatformKey.String(string(o.platform))))
+	record(span, err)
+	span.End()
+}
+
+func (o *observer) OnReconnect() {
+	_, span := o.tracer.tracer.Start(context.Background(), "Reconnect",
+		trace.WithAttributes(PlatformKey.String(string(o.platform))))
+	span.End()
+}
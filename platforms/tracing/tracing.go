@@ -0,0 +1,180 @@
+// Package tracing OpenTelemetry spans across the order lifecycle: the order entry decision,
+// the REST calls it makes, the acknowledgement and the order stream events that follow.
+//
+//	tracer := tracing.New(provider)
+//	tracer.Instrument(connector)
+//	traced := tracer.Connector(connector)
+//	orderId, err := traced.PlaceOrderContext(ctx, order)
+//	updates := tracer.Streamer(connector.Name(), streamer)
+//
+// Order stream events are children of, and linked to, the span of the order they belong to,
+// found by client order id (TradeNo) or exchange order id. Orders placed through Connector
+// without a TradeNo are given one, so events arriving before the acknowledgement link as well.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/xavierzho/go-cexs/platforms/tracing"
+
+// Span attributes.
+const (
+	PlatformKey      = attribute.Key("cex.platform")
+	SymbolKey        = attribute.Key("cex.symbol")
+	ClientOrderIdKey = attribute.Key("cex.client_order_id")
+	OrderIdKey       = attribute.Key("cex.order_id")
+	ErrorCodeKey     = attribute.Key("cex.error_code")
+	RouteKey         = attribute.Key("cex.route")
+	MethodKey        = attribute.Key("http.request.method")
+	StatusCodeKey    = attribute.Key("http.response.status_code")
+)
+
+// maxOrders order spans remembered for linking, the oldest are forgotten first
+const maxOrders = 4096
+
+// Tracer spans of connector calls, orders and their stream events.
+type Tracer struct {
+	tracer trace.Tracer
+	mux    sync.Mutex
+	orders map[string]trace.SpanContext // platform, kind and id -> order span
+	keys   []string                     // insertion order of orders
+}
+
+// New tracer on provider, nil uses the global provider.
+func New(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{
+		tracer: provider.Tracer(instrumentation),
+		orders: make(map[string]trace.SpanContext),
+	}
+}
+
+func clientKey(platform constants.Platform, clientOrderId string) string {
+	return string(platform) + "/client/" + clientOrderId
+}
+
+func orderKey(platform constants.Platform, orderId string) string {
+	return string(platform) + "/order/" + orderId
+}
+
+// remember the order span under keys.
+func (t *Tracer) remember(span trace.SpanContext, keys ...string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	for _, key := range keys {
+		if _, ok := t.orders[key]; !ok {
+			t.keys = append(t.keys, key)
+		}
+		t.orders[key] = span
+	}
+	for len(t.keys) > maxOrders {
+		delete(t.orders, t.keys[0])
+		t.keys = t.keys[1:]
+	}
+}
+
+// lookup the order span of the first key known.
+func (t *Tracer) lookup(keys ...string) (trace.SpanContext, bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	for _, key := range keys {
+		if span, ok := t.orders[key]; ok {
+			return span, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+// record the error on span.
+func record(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	var exchangeErr *platforms.ExchangeError
+	if errors.As(err, &exchangeErr) {
+		span.SetAttributes(ErrorCodeKey.String(exchangeErr.Code))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Parameter names the connectors send client and exchange order ids under.
+var (
+	clientIdParams = []string{"newClientOrderId", "origClientOrderId", "clientOrderId", "clOrdId", "orderLinkId", "text"}
+	orderIdParams  = []string{"orderId", "ordId", "order_id"}
+)
+
+// Instrument trace the calls of connector, false if it does not run a middleware chain.
+func (t *Tracer) Instrument(connector any) bool {
+	interceptable, ok := connector.(platforms.Interceptable)
+	if ok {
+		interceptable.Use(t.Middleware())
+	}
+	return ok
+}
+
+// Middleware a client span per call, child of the span of the order it concerns when the order is known.
+func (t *Tracer) Middleware() platforms.Middleware {
+	return func(next platforms.Handler) platforms.Handler {
+		return func(invocation *platforms.Invocation) error {
+			ctx := context.Background()
+			clientOrderId := invocation.Param(clientIdParams...)
+			if text := invocation.Param("text"); text != "" && text == clientOrderId {
+				// gate sends the client order id prefixed with "t-", its replies and streams strip it
+				clientOrderId = strings.TrimPrefix(text, "t-")
+			}
+			orderId := invocation.Param(orderIdParams...)
+			if orderId == "" && invocation.Template() != invocation.Route {
+				// the id is in the path
+				orderId = invocation.Route[strings.LastIndexByte(invocation.Route, '/')+1:]
+			}
+			var keys []string
+			if clientOrderId != "" {
+				keys = append(keys, clientKey(invocation.Platform, clientOrderId))
+			}
+			if orderId != "" {
+				keys = append(keys, orderKey(invocation.Platform, orderId))
+			}
+			if parent, ok := t.lookup(keys...); ok {
+				ctx = trace.ContextWithSpanContext(ctx, parent)
+			}
+			attributes := []attribute.KeyValue{
+				PlatformKey.String(string(invocation.Platform)),
+				MethodKey.String(invocation.Method),
+				RouteKey.String(invocation.Template()),
+			}
+			if symbol := invocation.Symbol(); symbol != "" {
+				attributes = append(attributes, SymbolKey.String(symbol))
+			}
+			if clientOrderId != "" {
+				attributes = append(attributes, ClientOrderIdKey.String(clientOrderId))
+			}
+			if orderId != "" {
+				attributes = append(attributes, OrderIdKey.String(orderId))
+			}
+			_, span := t.tracer.Start(ctx, fmt.Sprintf("%s %s", invocation.Method, invocation.Template()),
+				trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+			defer span.End()
+			err := next(invocation)
+			if invocation.StatusCode > 0 {
+				span.SetAttributes(StatusCodeKey.Int(invocation.StatusCode))
+			}
+			record(span, err)
+			return err
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xavierzho/go-cexs/constants"
+	"github.com/xavierzho/go-cexs/platforms"
+	"github.com/xavierzho/go-cexs/types"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type exchange struct {
+	platforms.SpotConnector
+	platforms.Middlewares
+	placed []types.OrderEntry
+	// onPlace runs before the reply
+	onPlace func(order types.OrderEntry)
+}
+
+func (e *exchange) Name() constants.Platform {
+	return constants.Binance
+}
+
+func (e *exchange) PlaceOrder(order types.OrderEntry) (string, error) {
+	e.placed = append(e.placed, order)
+	if e.onPlace != nil {
+		e.onPlace(order)
+	}
+	return "42", e.Invoke(&platforms.Invocation{
+		Platform: constants.Binance, Method: http.MethodPost, Route: "/api/v3/order",
+		Params: &platforms.ObjectBody{"symbol": order.Symbol, "newClientOrderId": order.TradeNo},
+	}, func(invocation *platforms.Invocation) error {
+		invocation.StatusCode = http.StatusOK
+		return nil
+	})
+}
+
+func (e *exchange) BatchOrder(orders []types.OrderEntry) ([]types.BatchOrderResult, error) {
+	e.placed = append(e.placed, orders...)
+	results := []types.BatchOrderResult{{OrderId: "42", TradeNo: orders[0].TradeNo}}
+	if len(orders) > 1 {
+		results = append(results, types.BatchOrderResult{TradeNo: orders[1].TradeNo, Code: "-2010", Message: "insufficient balance"})
+	}
+	return results, nil
+}
+
+func (e *exchange) PlaceOrderList(list types.OrderListEntry) (types.OrderListResult, error) {
+	e.placed = append(e.placed, *list.TakeProfit, *list.StopLoss)
+	return types.OrderListResult{ListId: "7", TakeProfitOrderId: "43", StopLossOrderId: "44"}, nil
+}
+
+func (e *exchange) CancelByIds(_ string, orderIds []string) ([]types.CancelResult, error) {
+	results := []types.CancelResult{{OrderId: orderIds[0]}}
+	for _, orderId := range orderIds[1:] {
+		results = append(results, types.CancelResult{OrderId: orderId, Err: platforms.ErrOrderNotFound})
+	}
+	return results, nil
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	connector := &exchange{}
+	tracer.Instrument(connector)
+
+	ctx, decision := tracer.tracer.Start(context.Background(), "decision")
+	orderId, err := tracer.Connector(connector).PlaceOrderContext(ctx, types.OrderEntry{
+		Symbol: "BTCUSDT", Side: "BUY", Price: decimal.NewFromInt(100), Quantity: decimal.NewFromInt(1), TradeNo: "c-1",
+	})
+	decision.End()
+	if err != nil || orderId != "42" {
+		t.Fatalf("unexpected result %q, %v", orderId, err)
+	}
+	tracer.OnUpdate(constants.Binance, types.OrderUpdateEntry{OrderId: "42", ClientOrderId: "c-1", Status: constants.Filled})
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	place, call, update := spans["PlaceOrder"], spans["POST /api/v3/order"], spans["OrderUpdate"]
+	if place == nil || call == nil || update == nil {
+		t.Fatalf("missing spans %v", spans)
+	}
+	trace := decision.SpanContext().TraceID()
+	for _, span := range []sdktrace.ReadOnlySpan{place, call, update} {
+		if span.SpanContext().TraceID() != trace {
+			t.Errorf("%s not in the decision's trace", span.Name())
+		}
+	}
+	if call.Parent().SpanID() != place.SpanContext().SpanID() || update.Parent().SpanID() != place.SpanContext().SpanID() {
+		t.Error("call and update should be children of the order span")
+	}
+	if links := update.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != place.SpanContext().SpanID() {
+		t.Errorf("update not linked to the order span: %v", links)
+	}
+}
+
+func TestUpdateBeforeAck(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	connector := &exchange{}
+	// the stream reports the order by client id only, before the reply
+	connector.onPlace = func(order types.OrderEntry) {
+		tracer.OnUpdate(constants.Binance, types.OrderUpdateEntry{ClientOrderId: order.TradeNo, Status: constants.Open})
+	}
+	if _, err := tracer.Connector(connector).PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT"}); err != nil {
+		t.Fatal(err)
+	}
+	if connector.placed[0].TradeNo == "" {
+		t.Fatal("order sent without client order id")
+	}
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	place, update := spans["PlaceOrder"], spans["OrderUpdate"]
+	if place == nil || update == nil || update.Parent().SpanID() != place.SpanContext().SpanID() {
+		t.Errorf("update before the acknowledgement not a child of the order span: %v", spans)
+	}
+}
+
+func TestBatchListAndCancels(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	connector := &exchange{}
+	traced := tracer.Connector(connector)
+	if _, err := traced.BatchOrder([]types.OrderEntry{{Symbol: "BTCUSDT"}, {Symbol: "BTCUSDT"}}); err != nil {
+		t.Fatal(err)
+	}
+	if connector.placed[0].TradeNo == "" || connector.placed[1].TradeNo == "" {
+		t.Fatal("batch sent without client order ids")
+	}
+	if _, err := traced.PlaceOrderList(types.OrderListEntry{Type: types.OCO, Symbol: "BTCUSDT",
+		TakeProfit: &types.OrderEntry{Symbol: "BTCUSDT"}, StopLoss: &types.OrderEntry{Symbol: "BTCUSDT"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := traced.CancelByIds("BTCUSDT", []string{"42", "43"}); err != nil {
+		t.Fatal(err)
+	}
+	// a leg found by its order id once the list is acknowledged
+	tracer.OnUpdate(constants.Binance, types.OrderUpdateEntry{OrderId: "44", Status: constants.Open})
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	batch, places, list, cancel := spans["BatchOrder"], spans["PlaceOrder"], spans["PlaceOrderList"], spans["CancelByIds"]
+	if len(batch) != 1 || len(places) != 2 || len(list) != 1 || len(cancel) != 1 {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+	for _, place := range places {
+		if place.Parent().SpanID() != batch[0].SpanContext().SpanID() {
+			t.Error("order spans should be children of the batch span")
+		}
+	}
+	if places[0].Status().Code == codes.Error || places[1].Status().Code != codes.Error {
+		t.Errorf("want the second order failed, got %v and %v", places[0].Status(), places[1].Status())
+	}
+	if update := spans["OrderUpdate"]; len(update) != 1 || update[0].Parent().SpanID() != list[0].SpanContext().SpanID() {
+		t.Error("update of a leg should be a child of the list span")
+	}
+	if links := cancel[0].Links(); len(links) != 2 || links[0].SpanContext.SpanID() != places[0].SpanContext().SpanID() ||
+		links[1].SpanContext.SpanID() != list[0].SpanContext().SpanID() {
+		t.Errorf("cancel not linked to the order spans: %v", links)
+	}
+	if events := cancel[0].Events(); len(events) != 1 || events[0].Name != "failed" {
+		t.Errorf("want the second cancel failed, got %v", events)
+	}
+}
@@ -0,0 +1,228 @@
+package platforms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// WsOrderProtocol exchange specific framing of websocket order entry.
+type WsOrderProtocol interface {
+	// URL websocket api endpoint.
+	URL() string
+	// Login authenticate a fresh connection, replies are read with Request as nothing else reads yet.
+	Login(stream *StreamBase) error
+	// PlaceOrder payload of a new order request tagged with id.
+	PlaceOrder(id string, order types.OrderEntry) map[string]any
+	// Cancel payload of a cancel request tagged with id.
+	Cancel(id, symbol, orderId string) map[string]any
+	// ReplyId request id of a reply, false for anything else (pushes, acks, pongs).
+	ReplyId(msg []byte) (string, bool)
+	// OrderId order id of a successful reply, or the exchange error.
+	OrderId(msg []byte) (string, error)
+}
+
+// WsPinger protocols whose venue expects an application level heartbeat text frame.
+type WsPinger interface {
+	Ping() string
+}
+
+// errSocketDown nothing was sent, the request can safely go through REST instead.
+var errSocketDown = errors.New("websocket down")
+
+const (
+	defaultWsTimeout = 5 * time.Second
+	wsPingInterval   = 20 * time.Second
+	maxWsBackoff     = 30 * time.Second
+)
+
+// WsTrader Trade placing and canceling orders over an authenticated websocket.
+// Every other call goes through the embedded REST connector, and so do orders while the socket is down.
+// A placement without reply within Timeout fails with ErrNetwork instead of falling back,
+// since the order may have reached the exchange; cancels are safe to repeat and fall back.
+type WsTrader struct {
+	SpotConnector
+	// Timeout how long to wait for a reply.
+	Timeout  time.Duration
+	protocol WsOrderProtocol
+	stream   *StreamBase
+	mux      sync.Mutex
+	pending  map[string]chan []byte // request id -> reply
+	up       atomic.Bool
+	sequence atomic.Uint64
+}
+
+func NewWsTrader(rest SpotConnector, protocol WsOrderProtocol) *WsTrader {
+	return &WsTrader{
+		SpotConnector: rest,
+		Timeout:       defaultWsTimeout,
+		protocol:      protocol,
+		stream:        NewStream(),
+		pending:       make(map[string]chan []byte),
+	}
+}
+
+// Connect dial and login, the connection is re-established in background until ctx is done.
+func (t *WsTrader) Connect(ctx context.Context) error {
+	if err := t.dial(); err != nil {
+		return err
+	}
+	go t.read(ctx)
+	go func() {
+		<-ctx.Done()
+		_ = t.stream.Close()
+	}()
+	if pinger, ok := t.protocol.(WsPinger); ok {
+		go t.ping(ctx, pinger.Ping())
+	}
+	return nil
+}
+
+// Connected whether orders currently go through the websocket.
+func (t *WsTrader) Connected() bool {
+	return t.up.Load()
+}
+
+func (t *WsTrader) dial() error {
+	t.stream.SetLogger(LoggerOf(t.SpotConnector))
+	if err := t.stream.Connect(t.protocol.URL()); err != nil {
+		return err
+	}
+	if err := t.protocol.Login(t.stream); err != nil {
+		return err
+	}
+	t.up.Store(true)
+	return nil
+}
+
+func (t *WsTrader) ping(ctx context.Context, message string) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if t.up.Load() {
+				_ = t.stream.WriteText(message)
+			}
+		}
+	}
+}
+
+// read dispatch replies to their requests, on a broken connection the pending requests fail and it redials.
+func (t *WsTrader) read(ctx context.Context) {
+	for {
+		msg, err := t.stream.ReadMessage()
+		if err != nil {
+			t.up.Store(false)
+			t.failPending()
+			if !t.redial(ctx) {
+				return
+			}
+			continue
+		}
+		id, ok := t.protocol.ReplyId(msg)
+		if !ok {
+			continue
+		}
+		t.mux.Lock()
+		reply, ok := t.pending[id]
+		delete(t.pending, id)
+		t.mux.Unlock()
+		if ok {
+			reply <- msg
+		}
+	}
+}
+
+func (t *WsTrader) redial(ctx context.Context) bool {
+	backoff := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		err := t.dial()
+		if err == nil {
+			return true
+		}
+		LoggerOf(t.SpotConnector).Warn("websocket reconnect failed", slog.String("platform", string(t.Name())), slog.String("error", err.Error()))
+		backoff = min(backoff*2, maxWsBackoff)
+	}
+}
+
+func (t *WsTrader) failPending() {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	for id, reply := range t.pending {
+		close(reply)
+		delete(t.pending, id)
+	}
+}
+
+func (t *WsTrader) request(id string, payload map[string]any) ([]byte, error) {
+	if !t.up.Load() {
+		return nil, errSocketDown
+	}
+	reply := make(chan []byte, 1)
+	t.mux.Lock()
+	t.pending[id] = reply
+	t.mux.Unlock()
+	if err := t.stream.WriteJSON(payload); err != nil {
+		t.mux.Lock()
+		delete(t.pending, id)
+		t.mux.Unlock()
+		return nil, errSocketDown
+	}
+	timer := time.NewTimer(t.Timeout)
+	defer timer.Stop()
+	select {
+	case msg, ok := <-reply:
+		if !ok {
+			return nil, NetworkError(fmt.Errorf("connection lost before reply to request %s", id))
+		}
+		return msg, nil
+	case <-timer.C:
+		t.mux.Lock()
+		delete(t.pending, id)
+		t.mux.Unlock()
+		return nil, NetworkError(fmt.Errorf("no reply to request %s within %s", id, t.Timeout))
+	}
+}
+
+func (t *WsTrader) nextId() string {
+	return strconv.FormatUint(t.sequence.Add(1), 10)
+}
+
+func (t *WsTrader) PlaceOrder(order types.OrderEntry) (string, error) {
+	id := t.nextId()
+	msg, err := t.request(id, t.protocol.PlaceOrder(id, order))
+	if errors.Is(err, errSocketDown) {
+		return t.SpotConnector.PlaceOrder(order)
+	}
+	if err != nil {
+		return "", err
+	}
+	return t.protocol.OrderId(msg)
+}
+
+func (t *WsTrader) Cancel(symbol, orderId string) (bool, error) {
+	id := t.nextId()
+	msg, err := t.request(id, t.protocol.Cancel(id, symbol, orderId))
+	if err != nil {
+		return t.SpotConnector.Cancel(symbol, orderId)
+	}
+	if _, err = t.protocol.OrderId(msg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
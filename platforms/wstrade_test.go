@@ -0,0 +1,129 @@
+package platforms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xavierzho/go-cexs/types"
+)
+
+// echoProtocol replies carry the request id and the order id "ws-<id>"
+type echoProtocol struct {
+	url string
+}
+
+func (p *echoProtocol) URL() string { return p.url }
+
+func (p *echoProtocol) Login(*StreamBase) error { return nil }
+
+func (p *echoProtocol) PlaceOrder(id string, order types.OrderEntry) map[string]any {
+	return map[string]any{"id": id, "symbol": order.Symbol}
+}
+
+func (p *echoProtocol) Cancel(id, symbol, orderId string) map[string]any {
+	return map[string]any{"id": id, "symbol": symbol}
+}
+
+func (p *echoProtocol) ReplyId(msg []byte) (string, bool) {
+	var reply map[string]string
+	if json.Unmarshal(msg, &reply) != nil || reply["id"] == "" {
+		return "", false
+	}
+	return reply["id"], true
+}
+
+func (p *echoProtocol) OrderId(msg []byte) (string, error) {
+	var reply map[string]string
+	_ = json.Unmarshal(msg, &reply)
+	if reply["symbol"] == "REJECT" {
+		return "", errors.New("rejected")
+	}
+	return "ws-" + reply["id"], nil
+}
+
+type restTrader struct {
+	SpotConnector
+}
+
+func (restTrader) PlaceOrder(types.OrderEntry) (string, error) { return "rest", nil }
+
+func (restTrader) Cancel(string, string) (bool, error) { return true, nil }
+
+// echoServer answers every request out of order with a push in between, SILENT requests get no reply.
+func echoServer(t *testing.T) *httptest.Server {
+	var upgrader websocket.Upgrader
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		var queued []map[string]any
+		for {
+			var request map[string]any
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			if request["symbol"] == "SILENT" {
+				continue
+			}
+			queued = append(queued, request)
+			if len(queued) < 2 && request["symbol"] != "REJECT" {
+				continue
+			}
+			_ = conn.WriteJSON(map[string]any{"event": "push"})
+			for i := len(queued) - 1; i >= 0; i-- {
+				_ = conn.WriteJSON(queued[i])
+			}
+			queued = nil
+		}
+	}))
+}
+
+func TestWsTrader(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+	trader := NewWsTrader(restTrader{}, &echoProtocol{url: "ws" + strings.TrimPrefix(server.URL, "http")})
+	trader.Timeout = 200 * time.Millisecond
+
+	if orderId, _ := trader.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT"}); orderId != "rest" {
+		t.Fatalf("expected REST fallback before connect, got %s", orderId)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := trader.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var orderIds = make(chan string, 2)
+	for range 2 {
+		go func() {
+			orderId, err := trader.PlaceOrder(types.OrderEntry{Symbol: "BTCUSDT"})
+			if err != nil {
+				t.Error(err)
+			}
+			orderIds <- orderId
+		}()
+	}
+	first, second := <-orderIds, <-orderIds
+	if !strings.HasPrefix(first, "ws-") || !strings.HasPrefix(second, "ws-") || first == second {
+		t.Errorf("replies not correlated: %s, %s", first, second)
+	}
+	if _, err := trader.PlaceOrder(types.OrderEntry{Symbol: "REJECT"}); err == nil {
+		t.Error("expected rejection")
+	}
+	if _, err := trader.PlaceOrder(types.OrderEntry{Symbol: "SILENT"}); !errors.Is(err, ErrNetwork) {
+		t.Errorf("expected timeout as network error, got %v", err)
+	}
+	if ok, err := trader.Cancel("SILENT", "1"); !ok || err != nil {
+		t.Errorf("expected cancel to fall back after timeout, got %v %v", ok, err)
+	}
+}
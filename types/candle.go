@@ -39,4 +39,7 @@ func Safe2Float(data any) float64 {
 type DepthEntry struct {
 	Asks [][]string
 	Bids [][]string
+	// Snapshot the entry is the whole book, e.g. a partial depth channel or the first push after subscribing,
+	// rather than a diff of the levels that changed
+	Snapshot bool
 }
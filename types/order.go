@@ -13,6 +13,61 @@ type OrderEntry struct {
 	Quantity    decimal.Decimal     `json:"quantity"`
 	TradeNo     string              `json:"trade_no"`
 	TimeInForce *string             `json:"-"`
+	// StopPrice trigger price of stop and take profit orders
+	StopPrice decimal.Decimal `json:"stop_price,omitempty"`
+}
+
+// OrderListType contingency between the legs of an order list
+type OrderListType string
+
+const (
+	// OCO one cancels the other: take profit and stop loss exits, a fill of either cancels the other.
+	OCO OrderListType = "OCO"
+	// OTO one triggers the other: the single exit is placed once the entry fills.
+	OTO OrderListType = "OTO"
+	// OTOCO one triggers an OCO: the take profit and stop loss pair is placed once the entry fills.
+	OTOCO OrderListType = "OTOCO"
+)
+
+// OrderListEntry legs of an order list, all on Symbol. Exits are on the opposite side of Entry.
+// TakeProfit is a limit order at Price, StopLoss triggers at StopPrice and then rests at Price,
+// or executes at market when Price is zero.
+type OrderListEntry struct {
+	Type   OrderListType `json:"type"`
+	Symbol string        `json:"symbol"`
+	// Entry working order of OTO and OTOCO
+	Entry      *OrderEntry `json:"entry,omitempty"`
+	TakeProfit *OrderEntry `json:"take_profit,omitempty"`
+	StopLoss   *OrderEntry `json:"stop_loss,omitempty"`
+}
+
+// OrderListResult ids of a placed order list. Leg ids are empty where the exchange
+// only creates the leg once it is triggered.
+type OrderListResult struct {
+	ListId            string `json:"list_id"`
+	EntryOrderId      string `json:"entry_order_id,omitempty"`
+	TakeProfitOrderId string `json:"take_profit_order_id,omitempty"`
+	StopLossOrderId   string `json:"stop_loss_order_id,omitempty"`
+}
+
+// BatchOrderResult outcome of one order of a batch, OrderId is empty when the order was not placed.
+// Code and Message carry the exchange error, Code is empty for transport failures of the whole batch.
+type BatchOrderResult struct {
+	OrderId string `json:"order_id"`
+	TradeNo string `json:"trade_no"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Success whether the order was accepted by the exchange
+func (r BatchOrderResult) Success() bool {
+	return r.OrderId != "" && r.Code == "" && r.Message == ""
+}
+
+// CancelResult outcome of cancelling one order, Err is nil when the order was canceled.
+type CancelResult struct {
+	OrderId string `json:"order_id"`
+	Err     error  `json:"-"`
 }
 
 type BalanceEntry struct {
@@ -21,6 +76,17 @@ type BalanceEntry struct {
 	Currency string `json:"currency"`
 }
 
+// FeeRateEntry maker/taker commission rates of a symbol, e.g. 0.001 means 0.1%
+type FeeRateEntry struct {
+	Symbol string          `json:"symbol"`
+	Maker  decimal.Decimal `json:"maker"`
+	Taker  decimal.Decimal `json:"taker"`
+	// DiscountAsset the asset whose use for paying fees lowers the rates (e.g. BNB, GT), empty if none.
+	DiscountAsset string          `json:"discount_asset,omitempty"`
+	DiscountMaker decimal.Decimal `json:"discount_maker,omitempty"`
+	DiscountTaker decimal.Decimal `json:"discount_taker,omitempty"`
+}
+
 type OrderBookEntry struct {
 	Symbol    string     `json:"symbol"`
 	Asks      [][]string `json:"asks"`
@@ -43,16 +109,74 @@ type TickerEntry struct {
 	Price  decimal.Decimal `json:"price"`
 }
 
+// FillEntry a single execution of one of the account's orders
+type FillEntry struct {
+	Symbol      string          `json:"symbol"`
+	TradeId     string          `json:"trade_id"`
+	OrderId     string          `json:"order_id"`
+	TradeNo     string          `json:"trade_no,omitempty"`
+	Side        string          `json:"side"`
+	Price       decimal.Decimal `json:"price"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	Fee         decimal.Decimal `json:"fee"`
+	FeeCurrency string          `json:"fee_currency"`
+	IsMaker     bool            `json:"is_maker"`
+	Timestamp   int64           `json:"timestamp"`
+}
+
 type OrderUpdateEntry struct {
 	OrderId       string
 	ClientOrderId string
 	Status        constants.OrderStatus
+	Symbol        string
+	Side          string
+	Type          constants.OrderType
+	Price         decimal.Decimal
+	Quantity      decimal.Decimal
+	// FilledQuantity cumulative filled base quantity
+	FilledQuantity decimal.Decimal
+	// FilledQuote cumulative filled quote amount
+	FilledQuote decimal.Decimal
+	// LastFillPrice, LastFillQuantity are zero when the update is not caused by a fill
+	LastFillPrice    decimal.Decimal
+	LastFillQuantity decimal.Decimal
+	// Fee of the last fill, or the cumulative fee where the venue only pushes that
+	Fee          decimal.Decimal
+	FeeAsset     string
+	IsMaker      bool
+	TradeId      string
+	RejectReason string
+	// EventTime, TransactionTime in milliseconds
+	EventTime       int64
+	TransactionTime int64
 }
 
+// BalanceUpdateEntry non-transactional fund change, e.g. deposit, withdrawal, transfer
 type BalanceUpdateEntry struct {
+	Asset  string
+	Free   decimal.Decimal
+	Locked decimal.Decimal
+	// Delta balance change caused by the event, zero when the venue does not report it
+	// and there is no previous balance to compare with
+	Delta decimal.Decimal
+	// Reason venue specific cause of the change
+	Reason string
+	// Timestamp in milliseconds
+	Timestamp int64
 }
 
+// AccountUpdateEntry asset change caused by trading, e.g. order placement, execution, fee deduction
 type AccountUpdateEntry struct {
+	Asset  string
+	Free   decimal.Decimal
+	Locked decimal.Decimal
+	// Delta balance change caused by the event, zero when the venue does not report it
+	// and there is no previous balance to compare with
+	Delta decimal.Decimal
+	// Reason venue specific cause of the change
+	Reason string
+	// Timestamp in milliseconds
+	Timestamp int64
 }
 
 type QueryOrder struct {